@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"github.com/sinhaparth5/whatstyle-mcp/configs"
 	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
 	"github.com/sinhaparth5/whatstyle-mcp/internal/handlers"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
 	"github.com/sinhaparth5/whatstyle-mcp/internal/whatsapp"
 
 	"github.com/gorilla/mux"
@@ -20,11 +22,6 @@ func main() {
 	// Load configuration
 	config := configs.Load()
 
-	// Validate required configuration
-	if config.GrokAPIKey == "" {
-		log.Printf("Warning: GROK_API_KEY not set. Server will use fallback responses.")
-	}
-
 	// Initialize database
 	db, err := database.InitDB(config.DatabasePath)
 	if err != nil {
@@ -41,6 +38,12 @@ func main() {
 			if err := db.CleanupExpiredSessions(); err != nil {
 				log.Printf("Error cleaning up sessions: %v", err)
 			}
+			if err := db.CleanupInboundJobs(7 * 24 * time.Hour); err != nil {
+				log.Printf("Error cleaning up inbound jobs: %v", err)
+			}
+			if err := db.CleanupMedia(config.WhatsAppMediaRetention); err != nil {
+				log.Printf("Error cleaning up media: %v", err)
+			}
 		}
 	}()
 
@@ -52,7 +55,28 @@ func main() {
 
 	// Initialize MCP handlers
 	mcpHandler := handlers.NewMCPHandler(db, config, implementation, nil)
-	whatsappHandler := whatsapp.NewHandler(config)
+	whatsappHandler := whatsapp.NewHandler(config, db, mcpHandler.Conversation())
+	mcpHandler.SetWhatsApp(whatsappHandler)
+
+	// Offer the model the tools defined in LLM_TOOLS_FILE
+	if config.LLMToolsFile != "" {
+		tools, err := llm.LoadHTTPTools(config.LLMToolsFile, config.LLMToolTimeout)
+		if err != nil {
+			log.Fatal("Failed to load LLM tools:", err)
+		}
+		for _, tool := range tools {
+			mcpHandler.Conversation().RegisterTool(tool.Tool, tool.Run)
+			log.Printf("Registered LLM tool %s -> %s", tool.Name, tool.URL)
+		}
+	}
+
+	// Process queued webhook payloads in the background
+	if err := whatsappHandler.StartWorkers(context.Background(), config.WebhookWorkers); err != nil {
+		log.Fatal("Failed to start webhook workers:", err)
+	}
+
+	// Fold the older history of long conversations into summaries
+	mcpHandler.Conversation().StartSummarizer(context.Background())
 
 	// Create MCP server
 	server := mcp.NewServer(implementation, nil)
@@ -92,6 +116,9 @@ func main() {
 			http.Error(w, "Failed to get stats", http.StatusInternalServerError)
 			return
 		}
+		stats["webhook"] = whatsappHandler.Stats()
+		stats["llm"] = mcpHandler.Conversation().Stats()
+		stats["guardrails"] = mcpHandler.Conversation().GuardrailStats()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(stats)
 	}).Methods("GET")
@@ -104,7 +131,7 @@ func main() {
 	log.Printf("Repository: github.com/sinhaparth5/whatstyle-mcp")
 	log.Printf("Port: %s", config.Port)
 	log.Printf("Environment: %s", config.Environment)
-	log.Printf("Grok Model: %s", config.GrokModel)
+	log.Printf("LLM Provider: %s", config.LLMProvider)
 	log.Printf("Database: %s", config.DatabasePath)
 	log.Printf("")
 	log.Printf("Endpoints:")
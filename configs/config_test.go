@@ -3,6 +3,7 @@ package configs
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -30,6 +31,16 @@ func TestLoad(t *testing.T) {
 		if config.GrokModel != "grok-beta" {
 			t.Errorf("Expected default grok model grok-beta, got %s", config.GrokModel)
 		}
+
+		if config.WebhookWorkers != 4 {
+			t.Errorf("Expected default webhook workers 4, got %d", config.WebhookWorkers)
+		}
+
+		// Guardrails are opt-in, as they hold back streamed replies
+		if config.GuardrailPII != "" || config.GuardrailMaxInputChars != 0 || config.GuardrailMaxOutputChars != 0 {
+			t.Errorf("Expected guardrails off by default, got PII %q and limits %d/%d",
+				config.GuardrailPII, config.GuardrailMaxInputChars, config.GuardrailMaxOutputChars)
+		}
 	})
 
 	// Test environment variable override
@@ -89,4 +100,44 @@ func TestGetEnv(t *testing.T) {
 			t.Errorf("Expected default_value for empty env var, got %s", result)
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestGetEnvInt(t *testing.T) {
+	t.Run("ValidInt", func(t *testing.T) {
+		os.Setenv("TEST_INT", "12")
+		defer os.Unsetenv("TEST_INT")
+
+		if result := getEnvInt("TEST_INT", 3); result != 12 {
+			t.Errorf("Expected 12, got %d", result)
+		}
+	})
+
+	t.Run("InvalidInt", func(t *testing.T) {
+		os.Setenv("TEST_INT", "twelve")
+		defer os.Unsetenv("TEST_INT")
+
+		if result := getEnvInt("TEST_INT", 3); result != 3 {
+			t.Errorf("Expected default 3 for invalid value, got %d", result)
+		}
+	})
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	t.Run("ValidDuration", func(t *testing.T) {
+		os.Setenv("TEST_DURATION", "45s")
+		defer os.Unsetenv("TEST_DURATION")
+
+		if result := getEnvDuration("TEST_DURATION", time.Second); result != 45*time.Second {
+			t.Errorf("Expected 45s, got %s", result)
+		}
+	})
+
+	t.Run("InvalidDuration", func(t *testing.T) {
+		os.Setenv("TEST_DURATION", "45")
+		defer os.Unsetenv("TEST_DURATION")
+
+		if result := getEnvDuration("TEST_DURATION", time.Second); result != time.Second {
+			t.Errorf("Expected default 1s for invalid value, got %s", result)
+		}
+	})
+}
@@ -3,6 +3,8 @@ package configs
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,12 +16,97 @@ type Config struct {
 	GrokAPIKey   string
 	GrokModel    string
 	GrokBaseURL  string
-	
+	GrokTimeout  time.Duration
+
+	// Retries of rate limited and failed Grok calls
+	GrokMaxRetries int
+
+	// Language model backend: grok, openai, ollama or none
+	LLMProvider string
+	LLMBaseURL  string
+	LLMAPIKey   string
+	LLMModel    string
+
+	// Token budget: the model's context size and the part kept for the reply
+	LLMContextTokens int
+	LLMReplyTokens   int
+
+	// Rounds of tool calls the model may make before it has to answer
+	LLMMaxToolRounds int
+
+	// JSON file of tools run by calling an HTTP endpoint, and how long
+	// one call may take
+	LLMToolsFile   string
+	LLMToolTimeout time.Duration
+
+	// Prices per million tokens, as model=prompt:completion,...
+	LLMPrices string
+
+	// Rolling summaries: once a conversation has more than LLMSummaryThreshold
+	// messages beyond its summary, all but the newest LLMSummaryKeep are
+	// folded into it; 0 disables summaries
+	LLMSummaryThreshold int
+	LLMSummaryKeep      int
+
+	// Circuit breaker: the provider is skipped for LLMBreakerCooldown once
+	// LLMBreakerFailurePercent of the last LLMBreakerWindow calls failed (and
+	// at least LLMBreakerMinCalls were made); 0 percent disables the breaker
+	LLMBreakerFailurePercent int
+	LLMBreakerWindow         int
+	LLMBreakerMinCalls       int
+	LLMBreakerCooldown       time.Duration
+
+	// Multimodal model that describes and answers inbound images; empty
+	// leaves images as "[Image]" placeholders
+	LLMVisionModel string
+
+	// Model routing: rules picking the model per message (see package
+	// routing) and the "[provider:]model" answering when the chosen one fails
+	LLMRoutes        string
+	LLMFallbackModel string
+
+	// Attempts at repairing a JSON answer that does not follow the requested schema
+	LLMJSONRepairs int
+
+	// Speech-to-text for voice notes: "command" runs SpeechCommand, "openai"
+	// posts to SpeechBaseURL's /audio/transcriptions; empty or "none" disables it
+	SpeechProvider string
+	SpeechCommand  string
+	SpeechBaseURL  string
+	SpeechAPIKey   string
+	SpeechModel    string
+	SpeechTimeout  time.Duration
+
+	// Guardrails on user input and model output: comma-separated blocked
+	// terms, PII kinds to redact ("none" for none), length limits in
+	// characters (0 for none) and an optional moderation model
+	GuardrailBlockedTerms    string
+	GuardrailPII             string
+	GuardrailMaxInputChars   int
+	GuardrailMaxOutputChars  int
+	GuardrailClassifierModel string
+
 	// WhatsApp Business API
 	WhatsAppAccessToken   string
 	WhatsAppVerifyToken   string
 	WhatsAppPhoneNumberID string
 	WhatsAppWebhookURL    string
+	WhatsAppAppSecret     string
+	WhatsAppAPIURL        string
+	WhatsAppMediaDir      string
+
+	// Media received from users: how long it is kept and how long
+	// downloading it may take
+	WhatsAppMediaRetention time.Duration
+	WhatsAppMediaTimeout   time.Duration
+
+	// Inbound webhook processing
+	WebhookWorkers     int
+	WebhookMaxAttempts int
+
+	// Fixed replies to tapped reply buttons and list rows, as a JSON object
+	// mapping selection ID patterns to the reply text
+	SelectionReplies string
 }
 
 func Load() *Config {
@@ -34,12 +121,85 @@ func Load() *Config {
 		GrokAPIKey:   getEnv("GROK_API_KEY", ""),
 		GrokModel:    getEnv("GROK_MODEL", "grok-beta"),
 		GrokBaseURL:  getEnv("GROK_BASE_URL", "https://api.x.ai/v1"),
-		
+		GrokTimeout:  getEnvDuration("GROK_TIMEOUT", 30*time.Second),
+
+		// Retries of rate limited and failed Grok calls
+		GrokMaxRetries: getEnvInt("GROK_MAX_RETRIES", 2),
+
+		// Language model backend
+		LLMProvider: getEnv("LLM_PROVIDER", "grok"),
+		LLMBaseURL:  getEnv("LLM_BASE_URL", ""),
+		LLMAPIKey:   getEnv("LLM_API_KEY", ""),
+		LLMModel:    getEnv("LLM_MODEL", ""),
+
+		// Token budget
+		LLMContextTokens: getEnvInt("LLM_CONTEXT_TOKENS", 8192),
+		LLMReplyTokens:   getEnvInt("LLM_REPLY_TOKENS", 1000),
+
+		// Tool calling
+		LLMMaxToolRounds: getEnvInt("LLM_MAX_TOOL_ROUNDS", 5),
+
+		// HTTP tools
+		LLMToolsFile:   getEnv("LLM_TOOLS_FILE", ""),
+		LLMToolTimeout: getEnvDuration("LLM_TOOL_TIMEOUT", 10*time.Second),
+
+		// Cost reporting
+		LLMPrices: getEnv("LLM_PRICES", ""),
+
+		// Conversation summaries
+		LLMSummaryThreshold: getEnvInt("LLM_SUMMARY_THRESHOLD", 60),
+		LLMSummaryKeep:      getEnvInt("LLM_SUMMARY_KEEP", 20),
+
+		// Circuit breaker
+		LLMBreakerFailurePercent: getEnvInt("LLM_BREAKER_FAILURE_PERCENT", 50),
+		LLMBreakerWindow:         getEnvInt("LLM_BREAKER_WINDOW", 20),
+		LLMBreakerMinCalls:       getEnvInt("LLM_BREAKER_MIN_CALLS", 5),
+		LLMBreakerCooldown:       getEnvDuration("LLM_BREAKER_COOLDOWN", 30*time.Second),
+
+		// Image understanding
+		LLMVisionModel: getEnv("LLM_VISION_MODEL", ""),
+
+		// Model routing
+		LLMRoutes:        getEnv("LLM_ROUTES", ""),
+		LLMFallbackModel: getEnv("LLM_FALLBACK_MODEL", ""),
+
+		// Structured output
+		LLMJSONRepairs: getEnvInt("LLM_JSON_REPAIRS", 2),
+
+		// Speech-to-text
+		SpeechProvider: getEnv("SPEECH_PROVIDER", ""),
+		SpeechCommand:  getEnv("SPEECH_COMMAND", ""),
+		SpeechBaseURL:  getEnv("SPEECH_BASE_URL", ""),
+		SpeechAPIKey:   getEnv("SPEECH_API_KEY", ""),
+		SpeechModel:    getEnv("SPEECH_MODEL", "whisper-1"),
+		SpeechTimeout:  getEnvDuration("SPEECH_TIMEOUT", 60*time.Second),
+
+		// Guardrails
+		GuardrailBlockedTerms:    getEnv("GUARDRAILS_BLOCKED_TERMS", ""),
+		GuardrailPII:             getEnv("GUARDRAILS_PII", ""),
+		GuardrailMaxInputChars:   getEnvInt("GUARDRAILS_MAX_INPUT_CHARS", 0),
+		GuardrailMaxOutputChars:  getEnvInt("GUARDRAILS_MAX_OUTPUT_CHARS", 0),
+		GuardrailClassifierModel: getEnv("GUARDRAILS_CLASSIFIER_MODEL", ""),
+
 		// WhatsApp Business API
 		WhatsAppAccessToken:   getEnv("WHATSAPP_ACCESS_TOKEN", ""),
 		WhatsAppVerifyToken:   getEnv("WHATSAPP_VERIFY_TOKEN", ""),
 		WhatsAppPhoneNumberID: getEnv("WHATSAPP_PHONE_NUMBER_ID", ""),
 		WhatsAppWebhookURL:    getEnv("WHATSAPP_WEBHOOK_URL", ""),
+		WhatsAppAppSecret:     getEnv("WHATSAPP_APP_SECRET", ""),
+		WhatsAppAPIURL:        getEnv("WHATSAPP_API_URL", "https://graph.facebook.com/v18.0"),
+		WhatsAppMediaDir:      getEnv("WHATSAPP_MEDIA_DIR", ""),
+
+		// Media received from users
+		WhatsAppMediaRetention: getEnvDuration("WHATSAPP_MEDIA_RETENTION", 30*24*time.Hour),
+		WhatsAppMediaTimeout:   getEnvDuration("WHATSAPP_MEDIA_TIMEOUT", 30*time.Second),
+
+		// Inbound webhook processing
+		WebhookWorkers:     getEnvInt("WEBHOOK_WORKERS", 4),
+		WebhookMaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+
+		// Interactive replies
+		SelectionReplies: getEnv("SELECTION_REPLIES", ""),
 	}
 
 	return config
@@ -50,4 +210,32 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s (%q), using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Invalid duration for %s (%q), using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,223 @@
+package conversation
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// Token estimation parameters. Chat formats add a few tokens of framing per
+// message and to prime the reply; text runs about four characters per token
+// until calibrated against the counts the provider reports.
+const (
+	tokensPerMessage     = 4
+	tokensPerReply       = 3
+	defaultCharsPerToken = 4.0
+	minCharsPerToken     = 1.0
+	maxCharsPerToken     = 8.0
+	// calibrationWeight is how much one observation moves the estimate.
+	calibrationWeight = 0.2
+)
+
+// summaryPrefix introduces the summary of the earlier conversation.
+const summaryPrefix = "Summary of the earlier conversation with this user:\n"
+
+// truncationNote marks a message cut to fit the prompt budget.
+const truncationNote = "\n[message truncated]"
+
+// contextBuilder assembles the model input for a reply within a token budget:
+// the system prompt and the new message always go in, then stored messages
+// from the newest backwards until the budget, less room for the reply, is spent.
+type contextBuilder struct {
+	contextTokens int
+
+	mu            sync.Mutex
+	charsPerToken float64
+}
+
+// promptEstimate is what the builder assumed about a prompt, kept to
+// calibrate the estimator against the provider's count.
+type promptEstimate struct {
+	chars    int
+	messages int
+	tokens   int
+	// images in the prompt; providers count them too differently to calibrate on
+	images int
+}
+
+func newContextBuilder(contextTokens int) *contextBuilder {
+	return &contextBuilder{contextTokens: contextTokens, charsPerToken: defaultCharsPerToken}
+}
+
+// build returns the messages for answering userMessage after history, which
+// is ordered oldest first, leaving replyTokens of the context for the answer.
+// summary, if not empty, condenses the conversation before history and goes
+// in after the system prompt.
+func (b *contextBuilder) build(system, summary string, history []models.Message, userMessage string, replyTokens int) ([]llm.Message, promptEstimate) {
+	ratio := b.ratio()
+	budget := b.contextTokens - replyTokens - tokensPerReply
+
+	estimate := promptEstimate{}
+	add := func(content string) {
+		estimate.chars += utf8.RuneCountInString(content)
+		estimate.messages++
+		estimate.tokens += estimateTokens(content, ratio)
+	}
+
+	add(system)
+	if summary != "" {
+		summary = summaryPrefix + summary
+		add(summary)
+	}
+
+	// A message too long for the budget on its own is cut rather than sent whole
+	if remaining := budget - estimate.tokens; estimateTokens(userMessage, ratio) > remaining {
+		userMessage = truncate(userMessage, remaining, ratio)
+	}
+	add(userMessage)
+
+	// Fill from the newest stored message backwards
+	start := len(history)
+	for start > 0 {
+		cost := estimateTokens(promptText(history[start-1]), ratio)
+		if estimate.tokens+cost > budget {
+			break
+		}
+		start--
+		add(promptText(history[start]))
+	}
+
+	// Tool results whose call did not fit would be rejected without it
+	for start < len(history) && history[start].Role == "tool" {
+		content := promptText(history[start])
+		estimate.chars -= utf8.RuneCountInString(content)
+		estimate.messages--
+		estimate.tokens -= estimateTokens(content, ratio)
+		start++
+	}
+
+	messages := make([]llm.Message, 0, len(history)-start+3)
+	messages = append(messages, llm.Message{Role: "system", Content: system})
+	if summary != "" {
+		messages = append(messages, llm.Message{Role: "system", Content: summary})
+	}
+	for _, msg := range history[start:] {
+		messages = append(messages, promptMessage(msg))
+	}
+	messages = append(messages, llm.Message{Role: "user", Content: userMessage})
+
+	estimate.tokens += tokensPerReply
+	return messages, estimate
+}
+
+// promptMessage converts a stored message to model input.
+func promptMessage(msg models.Message) llm.Message {
+	message := llm.Message{Role: msg.Role, Content: messageText(msg), ToolCallID: msg.ToolCallID}
+	for _, call := range msg.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, llm.ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+	}
+	return message
+}
+
+// storedMessage converts a turn of model input or output for userID's history.
+func storedMessage(userID string, msg llm.Message) models.Message {
+	message := models.Message{UserID: userID, Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+	for _, call := range msg.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, models.ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+	}
+	return message
+}
+
+// messageText is the content of a stored message as the model sees it, with
+// the description of the image it carried, if any.
+func messageText(msg models.Message) string {
+	if msg.Description == "" {
+		return msg.Content
+	}
+	return msg.Content + "\n[Image description: " + msg.Description + "]"
+}
+
+// promptText is the text of a stored message that counts towards the prompt,
+// including the tool calls it makes.
+func promptText(msg models.Message) string {
+	text := messageText(msg)
+	for _, call := range msg.ToolCalls {
+		text += call.Name + call.Arguments
+	}
+	return text
+}
+
+// calibrate adjusts the characters-per-token ratio towards the one implied
+// by the prompt token count a provider reported for an estimated prompt.
+func (b *contextBuilder) calibrate(estimate promptEstimate, promptTokens int) {
+	if estimate.images > 0 {
+		return
+	}
+	contentTokens := promptTokens - estimate.messages*tokensPerMessage - tokensPerReply
+	if contentTokens <= 0 || estimate.chars == 0 {
+		return
+	}
+	observed := float64(estimate.chars) / float64(contentTokens)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ratio := b.charsPerToken + calibrationWeight*(observed-b.charsPerToken)
+	b.charsPerToken = min(max(ratio, minCharsPerToken), maxCharsPerToken)
+}
+
+func (b *contextBuilder) ratio() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.charsPerToken
+}
+
+// estimateUsage estimates the usage of a call the provider reported none for,
+// from the prompt of req and the answer and tool calls it returned.
+func (b *contextBuilder) estimateUsage(req llm.Request, answer string, calls []llm.ToolCall) llm.Usage {
+	ratio := b.ratio()
+
+	prompt := tokensPerReply
+	for _, message := range req.Messages {
+		prompt += estimateTokens(message.Content+toolCallText(message.ToolCalls), ratio) + len(message.Images)*imageTokens
+	}
+	for _, tool := range req.Tools {
+		prompt += estimateTokens(tool.Name+tool.Description+string(tool.Parameters), ratio)
+	}
+	completion := estimateTokens(answer+toolCallText(calls), ratio) - tokensPerMessage
+
+	return llm.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
+
+// toolCallText is the text of calls for token estimates.
+func toolCallText(calls []llm.ToolCall) string {
+	var text strings.Builder
+	for _, call := range calls {
+		text.WriteString(call.Name)
+		text.WriteString(call.Arguments)
+	}
+	return text.String()
+}
+
+// estimateTokens estimates the tokens a message with content takes up.
+func estimateTokens(content string, charsPerToken float64) int {
+	chars := utf8.RuneCountInString(content)
+	return tokensPerMessage + int(float64(chars)/charsPerToken+0.999)
+}
+
+// truncate cuts content so that, with the truncation note, it fits in tokens.
+func truncate(content string, tokens int, charsPerToken float64) string {
+	noteChars := utf8.RuneCountInString(truncationNote)
+	keep := int(float64(tokens-tokensPerMessage)*charsPerToken) - noteChars
+	if keep <= 0 {
+		return truncationNote[1:]
+	}
+
+	runes := []rune(content)
+	if keep >= len(runes) {
+		return content
+	}
+	return string(runes[:keep]) + truncationNote
+}
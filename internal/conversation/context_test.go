@@ -0,0 +1,112 @@
+package conversation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+func TestContextBuilder(t *testing.T) {
+	// Each history message is 40 characters: 10 tokens plus 4 of framing
+	var history []models.Message
+	for i := 0; i < 20; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		history = append(history, models.Message{Role: role, Content: strings.Repeat("x", 39) + string(rune('a'+i))})
+	}
+
+	tests := []struct {
+		name          string
+		contextTokens int
+		message       string
+		wantHistory   int
+		wantTruncated bool
+	}{
+		// 200 - 50 reply - 3 priming - 6 system - 5 message leaves 136: nine messages
+		{"FillsBudgetFromNewest", 200, "Hi", 9, false},
+		{"EverythingFits", 1000, "Hi", 20, false},
+		{"NoRoomForHistory", 70, "Hi", 0, false},
+		{"TruncatesLongMessage", 200, strings.Repeat("long paste ", 200), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := newContextBuilder(tt.contextTokens)
+			messages, estimate := builder.build("Be kind", "", history, tt.message, 50)
+
+			if messages[0].Role != "system" {
+				t.Errorf("Expected the system prompt first, got %s", messages[0].Role)
+			}
+			if got := len(messages) - 2; got != tt.wantHistory {
+				t.Fatalf("Expected %d history messages, got %d", tt.wantHistory, got)
+			}
+			if tt.wantHistory > 0 && messages[len(messages)-2].Content != history[len(history)-1].Content {
+				t.Error("Expected the newest history message right before the new one")
+			}
+
+			last := messages[len(messages)-1]
+			if truncated := strings.HasSuffix(last.Content, truncationNote); truncated != tt.wantTruncated {
+				t.Errorf("Expected truncated %v, got %q", tt.wantTruncated, last.Content)
+			}
+			if estimate.tokens > tt.contextTokens-50 {
+				t.Errorf("Prompt estimate %d exceeds the budget of %d", estimate.tokens, tt.contextTokens-50)
+			}
+		})
+	}
+}
+
+func TestContextBuilderCalibrate(t *testing.T) {
+	builder := newContextBuilder(8192)
+	_, estimate := builder.build("Be kind", "", nil, strings.Repeat("word ", 100), 100)
+
+	// The provider counted two characters per token
+	contentTokens := estimate.chars / 2
+	for i := 0; i < 50; i++ {
+		builder.calibrate(estimate, contentTokens+estimate.messages*tokensPerMessage+tokensPerReply)
+	}
+	if ratio := builder.ratio(); ratio < 1.9 || ratio > 2.1 {
+		t.Errorf("Expected the ratio to converge on 2, got %.2f", ratio)
+	}
+
+	// Nonsense counts are ignored
+	builder.calibrate(estimate, 0)
+	if ratio := builder.ratio(); ratio < 1.9 || ratio > 2.1 {
+		t.Errorf("Expected the ratio to stay near 2, got %.2f", ratio)
+	}
+}
+
+func TestContextBuilderToolTurns(t *testing.T) {
+	history := []models.Message{
+		{Role: "user", Content: "Weather in Oslo?"},
+		{Role: "assistant", ToolCalls: []models.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: strings.Repeat("x", 400)}}},
+		{Role: "tool", Content: "Sunny", ToolCallID: "call_1"},
+		{Role: "assistant", Content: "It's sunny."},
+	}
+
+	tests := []struct {
+		name          string
+		contextTokens int
+		wantRoles     string
+	}{
+		{"Whole", 1000, "system,user,assistant,tool,assistant,user"},
+		// The call's arguments do not fit, so its result is left out too
+		{"CallTooLong", 120, "system,assistant,user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, _ := newContextBuilder(tt.contextTokens).build("Be kind", "", history, "Thanks", 50)
+
+			var roles []string
+			for _, msg := range messages {
+				roles = append(roles, msg.Role)
+			}
+			if got := strings.Join(roles, ","); got != tt.wantRoles {
+				t.Errorf("Expected %s, got %s", tt.wantRoles, got)
+			}
+		})
+	}
+}
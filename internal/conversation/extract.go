@@ -0,0 +1,105 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/jsonschema"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// extractPrompt instructs the model to extract data; %s is the JSON schema.
+const extractPrompt = `Extract information from the user's message as JSON following this JSON schema:
+%s
+Reply with only the JSON. Where the message does not give a value and the schema allows null, use null rather than guessing.`
+
+// ExtractRequest describes data to extract from a message.
+type ExtractRequest struct {
+	// UserID is the sender of Message, if known; it routes the request as
+	// it would a reply to them.
+	UserID  string
+	Message string
+	// Schema is the JSON schema the extracted data must follow.
+	Schema json.RawMessage
+	// Instructions add to the extraction prompt, if not empty.
+	Instructions string
+	// Format is llm.FormatJSONSchema (the default) to have the provider
+	// enforce Schema, or llm.FormatJSONObject for providers that only
+	// support JSON mode; the answer is validated against Schema either way.
+	Format string
+}
+
+// Extraction is data extracted from a message.
+type Extraction struct {
+	Data json.RawMessage
+	// Attempts counts the answers requested, including repairs.
+	Attempts int
+	Usage    *models.MessageUsage
+}
+
+// Extract asks the language model for the data described by req.Schema in
+// req.Message. An answer that does not follow the schema is sent back for
+// repair, up to LLM_JSON_REPAIRS times, before it fails with
+// llm.ErrInvalidJSON. The message passes the input guardrails, failing with
+// ErrBlocked if they block it, and is not stored in the history, though the
+// usage of every attempt is. Like Reply, it fails with ErrCanceled when ctx
+// ends first.
+func (s *Service) Extract(ctx context.Context, req ExtractRequest) (*Extraction, error) {
+	if req.Message == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+	schema, err := jsonschema.Parse(req.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	format := llm.ResponseFormat{Type: req.Format, Name: "extraction", Schema: req.Schema}
+	switch format.Type {
+	case "":
+		format.Type = llm.FormatJSONSchema
+	case llm.FormatJSONSchema, llm.FormatJSONObject:
+	default:
+		return nil, fmt.Errorf("unknown format %q (want %s or %s)", req.Format, llm.FormatJSONSchema, llm.FormatJSONObject)
+	}
+	if s.provider == nil {
+		return nil, fmt.Errorf("no language model is configured")
+	}
+
+	message, blocked := s.checkInput(ctx, req.UserID, req.Message)
+	if blocked {
+		return nil, ErrBlocked
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	prompt := fmt.Sprintf(extractPrompt, req.Schema)
+	if req.Instructions != "" {
+		prompt += "\n\n" + req.Instructions
+	}
+	meter := newMeter()
+	provider, model := s.route(ctx, req.UserID, nil, message, nil)
+	data, responses, err := llm.CompleteJSON(ctx, provider, llm.Request{
+		Model: model,
+		Messages: []llm.Message{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: message},
+		},
+		MaxTokens:      s.replyTokens,
+		ResponseFormat: &format,
+	}, schema, s.jsonRepairs)
+
+	for _, response := range responses {
+		meter.add(response.Model, response.Usage, response.FinishReason)
+	}
+	s.recordUsage(models.UsageKindExtract, req.UserID, meter)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, canceled(ctx)
+		}
+		return nil, err
+	}
+
+	return &Extraction{Data: data, Attempts: len(responses), Usage: s.usage(meter)}, nil
+}
@@ -0,0 +1,87 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+func TestExtract(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	schema := json.RawMessage(`{"type":"object","properties":{"order_number":{"type":"string"},"intent":{"enum":["track","cancel"]}},"required":["order_number","intent"]}`)
+	message := "Where is order AB-1234?"
+
+	tests := []struct {
+		name         string
+		replies      []string
+		request      ExtractRequest
+		want         string
+		wantAttempts int
+		wantErr      error
+	}{
+		{"Valid", []string{`{"order_number":"AB-1234","intent":"track"}`}, ExtractRequest{UserID: "user1", Message: message, Schema: schema}, `{"order_number":"AB-1234","intent":"track"}`, 1, nil},
+		{"Repaired", []string{`{"order_number":"AB-1234"}`, `{"order_number":"AB-1234","intent":"track"}`}, ExtractRequest{UserID: "user1", Message: message, Schema: schema}, `{"order_number":"AB-1234","intent":"track"}`, 2, nil},
+		{"GivesUp", []string{`{}`, `{}`, `{}`}, ExtractRequest{UserID: "user1", Message: message, Schema: schema}, "", 0, llm.ErrInvalidJSON},
+		{"InvalidSchema", nil, ExtractRequest{UserID: "user1", Message: message, Schema: json.RawMessage(`{"type":"thing"}`)}, "", 0, nil},
+		{"UnknownFormat", nil, ExtractRequest{UserID: "user1", Message: message, Schema: schema, Format: "yaml"}, "", 0, nil},
+		{"NoMessage", nil, ExtractRequest{Schema: schema}, "", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := llm.NewScripted(tt.replies...)
+			service := NewService(db, &configs.Config{LLMProvider: "none", LLMJSONRepairs: 2})
+			service.SetProvider(provider)
+
+			extraction, err := service.Extract(context.Background(), tt.request)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("Expected an error, got %s", extraction.Data)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if string(extraction.Data) != tt.want || extraction.Attempts != tt.wantAttempts {
+				t.Errorf("Expected %s in %d attempts, got %s in %d", tt.want, tt.wantAttempts, extraction.Data, extraction.Attempts)
+			}
+
+			// The provider is asked to enforce the schema
+			format := provider.Requests()[0].ResponseFormat
+			if format == nil || format.Type != llm.FormatJSONSchema || string(format.Schema) != string(schema) {
+				t.Errorf("Expected a json_schema response format, got %+v", format)
+			}
+		})
+	}
+
+	// Extractions are not part of the conversation
+	history, err := db.GetChatHistory("user1", 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("Expected no stored messages, got %d", len(history))
+	}
+
+	// but their usage is, including that of the one that gave up
+	byKind, err := db.GetUsage(database.UsageByKind, 1, "user1", 0)
+	if err != nil || len(byKind) != 1 || byKind[0].Key != models.UsageKindExtract || byKind[0].Messages != 3 {
+		t.Errorf("Expected the usage of 3 extractions, got %+v, error %v", byKind, err)
+	}
+}
@@ -0,0 +1,92 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/guardrails"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// ErrBlocked is returned by Extract for a message the guardrails block, and
+// by CheckOutput for a text they block or would rewrite.
+var ErrBlocked = errors.New("message blocked by guardrails")
+
+// Canned texts used in place of what the guardrails block.
+const (
+	// blockedMessage is stored in the history in place of a blocked user
+	// message, so it never reaches the model in a later prompt either.
+	blockedMessage = "[Message blocked]"
+	// blockedInputReply answers a blocked user message.
+	blockedInputReply = "Sorry, I can't help with that message."
+	// blockedOutputReply is sent in place of a blocked reply.
+	blockedOutputReply = "Sorry, I can't share a response to that. Could you ask in another way?"
+)
+
+// SetGuardrails replaces the rules user messages and replies are checked
+// against; nil lets everything through.
+func (s *Service) SetGuardrails(pipeline *guardrails.Pipeline) {
+	if pipeline == nil {
+		pipeline = guardrails.NewPipeline()
+	}
+	s.guardrails = pipeline
+}
+
+// GuardrailStats returns the guardrail decisions counted per stage.
+func (s *Service) GuardrailStats() map[string]interface{} {
+	return s.guardrails.Stats()
+}
+
+// checkInput runs a message from userID through the guardrails and returns
+// the text to store and show the model, and whether the message was blocked,
+// in which case the text is blockedMessage.
+func (s *Service) checkInput(ctx context.Context, userID, text string) (string, bool) {
+	decision := s.check(ctx, guardrails.Input, userID, text)
+	if decision.Blocked {
+		return blockedMessage, true
+	}
+	return decision.Text, false
+}
+
+// checkOutput runs a reply to userID through the guardrails and returns the
+// text to send.
+func (s *Service) checkOutput(ctx context.Context, userID, text string) string {
+	decision := s.check(ctx, guardrails.Output, userID, text)
+	if decision.Blocked {
+		return blockedOutputReply
+	}
+	return decision.Text
+}
+
+// CheckOutput runs texts sent to users other than as a reply, such as media
+// captions, template parameters and button titles, through the output
+// guardrails. Unlike a reply, such a text cannot be rewritten or replaced
+// behind the caller's back, so one the rules block, redact or shorten fails
+// with ErrBlocked and their reasons. userID is who the texts go to.
+func (s *Service) CheckOutput(ctx context.Context, userID string, texts ...string) error {
+	for _, text := range texts {
+		if text == "" {
+			continue
+		}
+		decision := s.check(ctx, guardrails.Output, userID, text)
+		if decision.Blocked || decision.Text != text {
+			return fmt.Errorf("%w: %s", ErrBlocked, strings.Join(decision.Reasons, ", "))
+		}
+	}
+	return nil
+}
+
+// check runs text from or to userID through the guardrails at stage and
+// records what any model calls of the rules consumed.
+func (s *Service) check(ctx context.Context, stage guardrails.Stage, userID, text string) guardrails.Decision {
+	decision := s.guardrails.Check(ctx, stage, text)
+	for _, call := range decision.Calls {
+		meter := &meter{start: time.Now().Add(-call.Latency)}
+		meter.add(call.Model, call.Usage, call.FinishReason)
+		s.recordUsage(models.UsageKindGuardrail, userID, meter)
+	}
+	return decision
+}
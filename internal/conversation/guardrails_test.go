@@ -0,0 +1,99 @@
+package conversation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/guardrails"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+func TestGuardrails(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	provider := llm.NewScripted("Your card [REDACTED CARD] is on file.", "Send it to GB82 WEST 1234 5698 7654 32.")
+	service := NewService(db, &configs.Config{LLMProvider: "none", GuardrailBlockedTerms: "casino", GuardrailPII: "card,iban"})
+	service.SetProvider(provider)
+	ctx := context.Background()
+
+	// PII is redacted before it is stored or reaches the model
+	if _, err := service.Reply(ctx, "user1", "Charge 4111 1111 1111 1111 please"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	messages := provider.Requests()[0].Messages
+	if got := messages[len(messages)-1].Content; got != "Charge [REDACTED CARD] please" {
+		t.Errorf("Expected the card redacted for the model, got %q", got)
+	}
+
+	// A blocked message never reaches the model, now or later
+	reply, err := service.Reply(ctx, "user1", "Any casino tips?")
+	if err != nil || reply != blockedInputReply {
+		t.Fatalf("Expected the canned refusal, got %q, error %v", reply, err)
+	}
+	if n := len(provider.Requests()); n != 1 {
+		t.Errorf("Expected no model call for a blocked message, got %d calls", n)
+	}
+	history, err := db.GetChatHistory("user1", 2)
+	if err != nil || len(history) != 2 || history[0].Content != blockedMessage {
+		t.Errorf("Expected the blocked message stored as a placeholder, got %+v, error %v", history, err)
+	}
+
+	// Replies are checked as a whole before any of it is delivered
+	var deltas []string
+	reply, err = service.ReplyStream(ctx, "user1", "Where do I pay?", func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil || reply != "Send it to [REDACTED IBAN]." {
+		t.Fatalf("Expected the IBAN redacted, got %q, error %v", reply, err)
+	}
+	if len(deltas) != 1 || deltas[0] != reply {
+		t.Errorf("Expected the checked reply delivered in one piece, got %q", deltas)
+	}
+
+	stats := service.GuardrailStats()
+	if output := stats[string(guardrails.Output)].(map[string]interface{}); output["modified"] != int64(1) {
+		t.Errorf("Expected one modified reply counted, got %v", output)
+	}
+}
+
+func TestGuardrailUsage(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	verdict := llm.Response{Content: `{"flagged": false}`, Model: "moderation", Usage: llm.Usage{PromptTokens: 90, CompletionTokens: 10, TotalTokens: 100}}
+	classifier := llm.NewScripted().Respond(verdict).Respond(verdict)
+	service := NewService(db, &configs.Config{LLMProvider: "none", LLMPrices: "moderation=1:2"})
+	service.SetProvider(llm.NewScripted("It ships tomorrow."))
+	service.SetGuardrails(guardrails.NewPipeline(guardrails.Classifier(classifier, "moderation")))
+
+	if _, err := service.Reply(context.Background(), "user1", "Where is my order?"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The message and the reply were each classified
+	byKind, err := db.GetUsage(database.UsageByKind, 1, "user1", 0)
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	for _, summary := range byKind {
+		if summary.Key == models.UsageKindGuardrail {
+			if summary.Messages != 2 || summary.TotalTokens != 200 || summary.CostUSD == 0 {
+				t.Errorf("Expected two priced classifications, got %+v", summary)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected guardrail usage, got %+v", byKind)
+}
@@ -0,0 +1,64 @@
+package conversation
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// Image description settings. imageTokens is what an image is assumed to
+// take of the prompt budget.
+const (
+	imageTokens            = 1000
+	descriptionTokens      = 300
+	descriptionTemperature = 0.2
+)
+
+// describePrompt instructs the vision model to describe an inbound image.
+const describePrompt = "Describe the image a customer sent in a WhatsApp conversation in a few sentences, for a support agent who cannot see it. Mention any visible text, products, brands, damage or defects. The customer's caption, if any, follows the [Image] marker."
+
+// UnderstandsImages reports whether inbound images are shown to a vision model;
+// otherwise they reach the history as placeholders only.
+func (s *Service) UnderstandsImages() bool {
+	return s.visionModel != "" && s.provider != nil
+}
+
+// describeImage has the vision model describe the image of in and stores the
+// description with the message, so that later replies, which only see the
+// text history, know what it showed.
+func (s *Service) describeImage(ctx context.Context, in Inbound) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	meter := newMeter()
+	response, err := s.provider.Complete(ctx, llm.Request{
+		Model: s.visionModel,
+		Messages: []llm.Message{
+			{Role: "system", Content: describePrompt},
+			{Role: "user", Content: in.Text, Images: []llm.Image{*in.Image}},
+		},
+		Temperature: llm.Temperature(descriptionTemperature),
+		MaxTokens:   descriptionTokens,
+	})
+	if err != nil {
+		log.Printf("Error describing image of message %s: %v", in.WAMID, err)
+		return
+	}
+	model := response.Model
+	if model == "" {
+		model = s.visionModel
+	}
+	meter.add(model, response.Usage, response.FinishReason)
+	s.recordUsage(models.UsageKindDescription, in.UserID, meter)
+
+	description := strings.TrimSpace(response.Content)
+	if description == "" {
+		return
+	}
+	if err := s.db.SetMessageDescription(in.WAMID, description); err != nil {
+		log.Printf("Error saving description of message %s: %v", in.WAMID, err)
+	}
+}
@@ -0,0 +1,67 @@
+package conversation
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+func TestAnswerInboundImage(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	provider := llm.NewScripted("A phone with a cracked screen.", "Sorry about your screen!", "It is under warranty.")
+	service := NewService(db, &configs.Config{LLMProvider: "none", LLMVisionModel: "grok-vision-beta"})
+	service.SetProvider(provider)
+	ctx := context.Background()
+
+	image := &llm.Image{MimeType: "image/jpeg", Data: []byte("jpeg")}
+	reply, err := service.AnswerInbound(ctx, Inbound{UserID: "15550001111", WAMID: "wamid.img", Text: "[Image] It arrived like this", Image: image})
+	if err != nil || reply != "Sorry about your screen!" {
+		t.Fatalf("Expected the reply, got %q, error %v", reply, err)
+	}
+
+	// The vision model describes the image, then answers with it in view
+	requests := provider.Requests()
+	for i, req := range requests {
+		last := req.Messages[len(req.Messages)-1]
+		if req.Model != "grok-vision-beta" || len(last.Images) != 1 || last.Content != "[Image] It arrived like this" {
+			t.Errorf("Expected request %d to show the image to the vision model, got %+v", i, req)
+		}
+	}
+
+	history, err := db.GetChatHistory("15550001111", 1)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if history[0].Description != "A phone with a cracked screen." {
+		t.Errorf("Expected the description stored with the message, got %+v", history[0])
+	}
+
+	// Describing the image counts towards the user's usage
+	byKind, err := db.GetUsage(database.UsageByKind, 1, "15550001111", 0)
+	if err != nil || len(byKind) != 1 || byKind[0].Key != models.UsageKindDescription {
+		t.Errorf("Expected the description's usage, got %+v, error %v", byKind, err)
+	}
+
+	// Later replies see the description instead of the image
+	if err := service.RecordReply("15550001111", "wamid.img", reply, "wamid.out"); err != nil {
+		t.Fatalf("Failed to record reply: %v", err)
+	}
+	if _, err := service.Reply(ctx, "15550001111", "Is it covered?"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	req := provider.Requests()[2]
+	if req.Model == "grok-vision-beta" || !strings.Contains(req.Messages[1].Content, "[Image description: A phone with a cracked screen.]") {
+		t.Errorf("Expected the description in the text history, got %+v", req.Messages)
+	}
+}
@@ -0,0 +1,164 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/routing"
+)
+
+// modelKey is the context key of the model asked for with WithModel.
+type modelKey struct{}
+
+// WithModel returns a context asking for replies generated with it to come
+// from model, "[provider:]model", in place of the routed one. Check it with
+// CheckModel first; a model that cannot be used is ignored.
+func WithModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelKey{}, model)
+}
+
+// CheckModel reports whether model can be asked for with WithModel.
+func (s *Service) CheckModel(model string) error {
+	target, err := routing.ParseTarget(model)
+	if err != nil {
+		return err
+	}
+	if s.provider == nil {
+		return fmt.Errorf("no language model is configured")
+	}
+	_, err = s.providerFor(target.Provider)
+	return err
+}
+
+// AddProvider makes provider available to routes naming it, in place of
+// one created from the configuration.
+func (s *Service) AddProvider(provider llm.Provider) {
+	s.providersMu.Lock()
+	defer s.providersMu.Unlock()
+	s.providers[provider.Name()] = provider
+}
+
+// newProvider creates the provider called name from config, behind a
+// circuit breaker of its own if breakers are configured.
+func newProvider(config *configs.Config, name string) (llm.Provider, error) {
+	provider, err := llm.NewNamed(config, name, "")
+	if err != nil || provider == nil {
+		return provider, err
+	}
+	if config.LLMBreakerFailurePercent > 0 {
+		provider = llm.NewBreaker(provider, llm.BreakerSettings{
+			FailureRate: float64(config.LLMBreakerFailurePercent) / 100,
+			Window:      config.LLMBreakerWindow,
+			MinCalls:    config.LLMBreakerMinCalls,
+			Cooldown:    config.LLMBreakerCooldown,
+		})
+	}
+	return provider, nil
+}
+
+// providerFor returns the provider called name, or the default one for "",
+// creating it on first use.
+func (s *Service) providerFor(name string) (llm.Provider, error) {
+	if name == "" || name == s.provider.Name() {
+		return s.provider, nil
+	}
+
+	s.providersMu.Lock()
+	defer s.providersMu.Unlock()
+	if provider, ok := s.providers[name]; ok {
+		return provider, nil
+	}
+	if s.config == nil {
+		return nil, fmt.Errorf("provider %s is not configured", name)
+	}
+
+	provider, err := newProvider(s.config, name)
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, fmt.Errorf("provider %s is not configured", name)
+	}
+	log.Printf("LLM provider %s initialized for routing", name)
+	s.providers[name] = provider
+	return provider, nil
+}
+
+// route picks the provider and model answering userMessage: the vision
+// model on the default provider if images come with it, else the model asked
+// for with WithModel, else the first LLM_ROUTES rule matching the message
+// and its sender, else persona's model on the default provider. Unless
+// images come with it, the provider falls back to LLM_FALLBACK_MODEL when
+// the model fails. The model is "" for the provider's default.
+func (s *Service) route(ctx context.Context, userID string, persona *models.Persona, userMessage string, images []llm.Image) (llm.Provider, string) {
+	if len(images) > 0 {
+		return s.provider, s.visionModel
+	}
+
+	var target routing.Target
+	if persona != nil {
+		target.Model = persona.Model
+	}
+	if model, ok := ctx.Value(modelKey{}).(string); ok {
+		requested, err := routing.ParseTarget(model)
+		if err != nil {
+			log.Printf("Ignoring requested model %q: %v", model, err)
+		} else {
+			target = requested
+		}
+	} else if rule, ok := s.router.Route(s.facts(userID, persona, userMessage)); ok {
+		log.Printf("Routing message from %s to %s by rule %q", userID, rule.Target, rule.Text)
+		target = rule.Target
+	}
+
+	provider, err := s.providerFor(target.Provider)
+	if err != nil {
+		log.Printf("Error routing to %s: %v; using the default provider", target, err)
+		provider, target = s.provider, routing.Target{}
+		if persona != nil {
+			target.Model = persona.Model
+		}
+	}
+
+	if s.fallback == nil {
+		return provider, target.Model
+	}
+	secondary, err := s.providerFor(s.fallback.Provider)
+	if err != nil {
+		log.Printf("Error configuring LLM_FALLBACK_MODEL: %v", err)
+		return provider, target.Model
+	}
+	if secondary == provider && effectiveModel(secondary, s.fallback.Model) == effectiveModel(provider, target.Model) {
+		return provider, target.Model
+	}
+	return llm.NewFallback(provider, secondary, s.fallback.Model), target.Model
+}
+
+// facts describes userMessage and its sender for the routing rules.
+func (s *Service) facts(userID string, persona *models.Persona, userMessage string) routing.Facts {
+	facts := routing.Facts{Message: userMessage}
+	if persona != nil {
+		facts.Persona = persona.Name
+	}
+	if userID != "" && len(s.router.Rules()) > 0 {
+		user, err := s.db.GetUser(userID)
+		if err != nil {
+			log.Printf("Error getting user %s for routing: %v", userID, err)
+		} else if user != nil {
+			facts.Tier, facts.Tags = user.Tier, user.Tags
+		}
+	}
+	return facts
+}
+
+// effectiveModel is the model provider answers a request for model with.
+func effectiveModel(provider llm.Provider, model string) string {
+	if model == "" {
+		return provider.Model()
+	}
+	return model
+}
@@ -0,0 +1,85 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+)
+
+// namedProvider is a scripted provider under another name, standing in for
+// a second backend.
+type namedProvider struct {
+	*llm.Scripted
+	name string
+}
+
+func (p namedProvider) Name() string { return p.name }
+
+func TestRouting(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{
+		LLMProvider:      "none",
+		LLMRoutes:        "tier=premium => ollama:llama3:70b; length>40 => big-model",
+		LLMFallbackModel: "ollama:llama3:8b",
+	}
+	primary := llm.NewScripted("Cheap answer.", "Big answer.").Fail(errors.New("overloaded"))
+	secondary := namedProvider{llm.NewScripted("Premium answer.", "Fallback answer.", "Chosen answer."), "ollama"}
+	service := NewService(db, config)
+	service.SetProvider(primary)
+	service.AddProvider(secondary)
+	ctx := context.Background()
+
+	if err := db.SetUserTier("premium-user", "premium"); err != nil {
+		t.Fatalf("Failed to set tier: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		userID    string
+		message   string
+		want      string
+		wantModel string
+	}{
+		{"Default", ctx, "user1", "Hi!", "Cheap answer.", ""},
+		{"Length", ctx, "user1", "Can you compare the two plans in detail for me?", "Big answer.", "big-model"},
+		{"Tier", ctx, "premium-user", "Hi!", "Premium answer.", "llama3:70b"},
+		{"Fallback", ctx, "user1", "Hello again", "Fallback answer.", "llama3:8b"},
+		{"Requested", WithModel(ctx, "ollama:mistral"), "user1", "Hi!", "Chosen answer.", "mistral"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := service.Reply(tt.ctx, tt.userID, tt.message)
+			if err != nil || reply != tt.want {
+				t.Fatalf("Expected %q, got %q, error %v", tt.want, reply, err)
+			}
+
+			// The answering request is the last either provider received
+			requests := append(primary.Requests(), secondary.Requests()...)
+			var last llm.Request
+			for _, req := range requests {
+				if msgs := req.Messages; msgs[len(msgs)-1].Content == tt.message {
+					last = req
+				}
+			}
+			if last.Model != tt.wantModel {
+				t.Errorf("Expected model %q, got %q", tt.wantModel, last.Model)
+			}
+		})
+	}
+
+	if err := service.CheckModel("openai:gpt-4o"); err == nil {
+		t.Error("Expected an error for a provider that is not configured")
+	}
+}
@@ -0,0 +1,70 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SelectionHandler answers a tapped reply button or list row. It returns the
+// reply to send, bypassing the model.
+type SelectionHandler func(ctx context.Context, in Inbound) (string, error)
+
+// selectionRoutes maps selection ID patterns to handlers. A pattern ending in
+// "*" matches every ID with that prefix.
+type selectionRoutes map[string]SelectionHandler
+
+// OnSelection registers handler for selection IDs matching pattern, e.g.
+// "confirm_order" or "order:*". Exact patterns win over prefixes, and longer
+// prefixes over shorter ones. Register handlers before serving traffic.
+func (s *Service) OnSelection(pattern string, handler SelectionHandler) {
+	s.selections[pattern] = handler
+}
+
+func (r selectionRoutes) match(id string) SelectionHandler {
+	if id == "" {
+		return nil
+	}
+
+	if handler, ok := r[id]; ok {
+		return handler
+	}
+
+	var best SelectionHandler
+	bestLen := -1
+	for pattern, handler := range r {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && strings.HasPrefix(id, prefix) && len(prefix) > bestLen {
+			best, bestLen = handler, len(prefix)
+		}
+	}
+	return best
+}
+
+// parseSelectionReplies parses SELECTION_REPLIES, a JSON object mapping
+// selection ID patterns to fixed replies, e.g.
+// {"answer:yes": "Glad it helped!", "agent:*": "An agent will be in touch."}.
+func parseSelectionReplies(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var replies map[string]string
+	if err := json.Unmarshal([]byte(raw), &replies); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of pattern to reply: %w", err)
+	}
+	for pattern, reply := range replies {
+		if pattern == "" || strings.TrimSpace(reply) == "" {
+			return nil, fmt.Errorf("pattern %q needs a non-empty reply", pattern)
+		}
+	}
+	return replies, nil
+}
+
+// fixedReply is a SelectionHandler that always answers with reply.
+func fixedReply(reply string) SelectionHandler {
+	return func(ctx context.Context, in Inbound) (string, error) {
+		return reply, nil
+	}
+}
@@ -0,0 +1,738 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/guardrails"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/routing"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/speech"
+)
+
+// maxHistoryMessages bounds how many stored messages are considered as context
+// for a reply; the token budget usually stops well before.
+const maxHistoryMessages = 200
+
+// systemPrompt sets the assistant's behaviour for users without a persona.
+const systemPrompt = "You are a helpful AI assistant integrated with WhatsApp. Provide concise, helpful responses to user messages. Keep responses conversational and appropriate for a messaging context."
+
+// replyTemperature is the sampling temperature for replies.
+const replyTemperature = 0.7
+
+// Limits used when the configuration leaves them unset.
+const (
+	defaultContextTokens = 8192
+	defaultReplyTokens   = 1000
+	defaultMaxToolRounds = 5
+	defaultSummaryKeep   = 20
+	defaultSpeechTimeout = 60 * time.Second
+)
+
+// errorReply is sent to the user when the pipeline fails after their message was stored.
+const errorReply = "I apologize, but I'm having trouble generating a response right now. Please try again."
+
+// Service is the chat pipeline shared by the MCP tools and the WhatsApp webhook.
+// It persists the user's message, generates a reply with the configured
+// language model (or a fallback) and persists the reply.
+type Service struct {
+	db          *database.DB
+	provider    llm.Provider
+	timeout     time.Duration
+	context     *contextBuilder
+	replyTokens int
+	selections  selectionRoutes
+
+	tools         *llm.Toolbox
+	maxToolRounds int
+
+	prices  llm.PriceTable
+	pending pendingUsage
+
+	summaryThreshold int
+	summaryKeep      int
+	summaryWake      chan struct{}
+
+	visionModel string
+
+	jsonRepairs int
+
+	transcriber   speech.Transcriber
+	speechTimeout time.Duration
+
+	guardrails *guardrails.Pipeline
+
+	// Providers other than the default one are created from config when a
+	// route first names them
+	config      *configs.Config
+	router      *routing.Router
+	fallback    *routing.Target
+	providersMu sync.Mutex
+	providers   map[string]llm.Provider
+}
+
+// Inbound is a WhatsApp message to store and answer.
+type Inbound struct {
+	UserID string
+	WAMID  string
+	// Text is the message as stored in the history and shown to the model.
+	Text string
+	// SelectionID is the ID of the tapped reply button or list row, if any.
+	SelectionID string
+	// PhoneNumberID is the business number the message was sent to; it
+	// selects that number's persona for users without one of their own.
+	PhoneNumberID string
+	// Image is the picture the message carried, downloaded when
+	// UnderstandsImages reports that the model can see it.
+	Image *llm.Image
+	// Audio is the recording of a voice note, downloaded when
+	// TranscribesAudio reports that it can be transcribed.
+	Audio *speech.Audio
+	// FromAudio marks Text as transcribed from Audio.
+	FromAudio bool
+}
+
+func NewService(db *database.DB, config *configs.Config) *Service {
+	contextTokens, replyTokens := config.LLMContextTokens, config.LLMReplyTokens
+	if contextTokens <= 0 {
+		contextTokens = defaultContextTokens
+	}
+	if replyTokens <= 0 {
+		replyTokens = defaultReplyTokens
+	}
+	maxToolRounds := config.LLMMaxToolRounds
+	if maxToolRounds <= 0 {
+		maxToolRounds = defaultMaxToolRounds
+	}
+	if contextTokens <= replyTokens {
+		log.Printf("Warning: LLM_CONTEXT_TOKENS (%d) leaves no room for history after LLM_REPLY_TOKENS (%d)",
+			contextTokens, replyTokens)
+	}
+
+	summaryThreshold, summaryKeep := config.LLMSummaryThreshold, config.LLMSummaryKeep
+	if summaryKeep <= 0 {
+		summaryKeep = defaultSummaryKeep
+	}
+	if summaryThreshold > 0 && summaryKeep >= summaryThreshold {
+		log.Printf("Warning: LLM_SUMMARY_KEEP (%d) must be below LLM_SUMMARY_THRESHOLD (%d), keeping %d",
+			summaryKeep, summaryThreshold, summaryThreshold/2)
+		summaryKeep = summaryThreshold / 2
+	}
+
+	prices, err := llm.ParsePrices(config.LLMPrices)
+	if err != nil {
+		log.Printf("Warning: ignoring LLM_PRICES: %v", err)
+	}
+
+	s := &Service{
+		db:          db,
+		timeout:     config.GrokTimeout,
+		context:     newContextBuilder(contextTokens),
+		replyTokens: replyTokens,
+		selections:  make(selectionRoutes),
+
+		tools:         llm.NewToolbox(),
+		maxToolRounds: maxToolRounds,
+
+		prices: prices,
+
+		summaryThreshold: summaryThreshold,
+		summaryKeep:      summaryKeep,
+		summaryWake:      make(chan struct{}, 1),
+
+		visionModel: config.LLMVisionModel,
+
+		jsonRepairs: max(config.LLMJSONRepairs, 0),
+
+		speechTimeout: config.SpeechTimeout,
+
+		config:    config,
+		router:    &routing.Router{},
+		providers: make(map[string]llm.Provider),
+	}
+	if s.speechTimeout <= 0 {
+		s.speechTimeout = defaultSpeechTimeout
+	}
+
+	replies, err := parseSelectionReplies(config.SelectionReplies)
+	if err != nil {
+		log.Printf("Error configuring SELECTION_REPLIES: %v; selections will be answered by the model", err)
+	}
+	for pattern, reply := range replies {
+		s.OnSelection(pattern, fixedReply(reply))
+	}
+	if len(replies) > 0 {
+		log.Printf("Answering %d selection patterns with fixed replies", len(replies))
+	}
+
+	transcriber, err := speech.New(config)
+	if err != nil {
+		log.Printf("Error configuring speech-to-text: %v; voice notes will not be transcribed", err)
+	} else if transcriber != nil {
+		s.SetTranscriber(transcriber)
+	}
+
+	// Initialize the language model
+	provider, err := newProvider(config, config.LLMProvider)
+	if err != nil {
+		log.Printf("Error configuring LLM provider: %v; using fallback responses", err)
+	} else if provider != nil {
+		s.SetProvider(provider)
+	}
+
+	if router, err := routing.Parse(config.LLMRoutes); err != nil {
+		log.Printf("Error configuring LLM_ROUTES: %v; messages will not be routed", err)
+	} else {
+		s.router = router
+	}
+	if config.LLMFallbackModel != "" {
+		if target, err := routing.ParseTarget(config.LLMFallbackModel); err != nil {
+			log.Printf("Error configuring LLM_FALLBACK_MODEL: %v", err)
+		} else {
+			s.fallback = &target
+		}
+	}
+
+	// The classifier runs on the configured model, not one set later
+	pipeline, err := guardrails.New(config, provider)
+	if err != nil {
+		log.Printf("Error configuring guardrails: %v; messages will not be checked", err)
+	}
+	s.SetGuardrails(pipeline)
+
+	return s
+}
+
+// SetProvider replaces the language model used for replies; nil selects fallback replies.
+func (s *Service) SetProvider(provider llm.Provider) {
+	s.provider = provider
+	if provider != nil {
+		log.Printf("LLM provider %s initialized with model: %s", provider.Name(), provider.Model())
+	}
+}
+
+// RegisterTool offers tool to the model in every reply. When the model calls
+// it, fn runs with the arguments the model supplied and its result is sent
+// back to the model, until the model answers in text.
+func (s *Service) RegisterTool(tool llm.Tool, fn llm.ToolFunc) {
+	s.tools.Register(tool, fn)
+}
+
+// Provider returns the language model used for replies, or nil if replies are fallbacks.
+func (s *Service) Provider() llm.Provider {
+	return s.provider
+}
+
+// Stats returns the language model's request and retry counters.
+func (s *Service) Stats() map[string]interface{} {
+	if s.provider == nil {
+		return map[string]interface{}{"configured": false}
+	}
+
+	stats := map[string]interface{}{}
+	if reporter, ok := s.provider.(llm.StatsReporter); ok {
+		stats = reporter.Stats()
+	}
+	stats["configured"] = true
+	stats["provider"] = s.provider.Name()
+	stats["model"] = s.provider.Model()
+	stats["chars_per_token"] = s.context.ratio()
+	stats["routes"] = len(s.router.Rules())
+	if s.fallback != nil {
+		stats["fallback_model"] = s.fallback.String()
+	}
+	return stats
+}
+
+// Reply stores message for userID, generates an answer and stores it.
+// It fails when the user's message cannot be saved or with ErrCanceled when
+// ctx ends first; other generation errors are logged and answered with a
+// canned apology. Both the message and the answer pass the guardrails first;
+// a blocked message is answered with a canned refusal without the model.
+func (s *Service) Reply(ctx context.Context, userID, message string) (string, error) {
+	if userID == "" || message == "" {
+		return "", fmt.Errorf("userID and message are required")
+	}
+
+	message, blocked := s.checkInput(ctx, userID, message)
+
+	// Save user message
+	if err := s.db.SaveMessage(userID, message, "user"); err != nil {
+		log.Printf("Error saving user message: %v", err)
+		return "", fmt.Errorf("failed to save message: %v", err)
+	}
+
+	response := blockedInputReply
+	var usage *models.MessageUsage
+	if !blocked {
+		// Generate response using the language model
+		var err error
+		response, usage, err = s.generate(ctx, userID, s.persona(userID, ""), message, nil, s.priorHistory(userID, message))
+		if errors.Is(err, ErrCanceled) {
+			return "", err
+		}
+		if err != nil {
+			log.Printf("Error generating response: %v", err)
+			response = errorReply
+		}
+		response = s.checkOutput(ctx, userID, response)
+	}
+
+	// Save assistant response
+	if err := s.db.SaveAssistantMessage(userID, response, usage); err != nil {
+		log.Printf("Error saving assistant message: %v", err)
+	}
+	s.notifySummarizer()
+
+	return response, nil
+}
+
+// ReplyStream is Reply with the answer delivered incrementally: onDelta is
+// called with each piece of text as the model generates it. Without a model,
+// or when the stream fails before producing any text, the fallback reply is
+// delivered as a single piece. A stream that breaks midway keeps the text received so
+// far, unless it broke because ctx ended, which fails with ErrCanceled.
+// Since nothing may be sent before the guardrails checked it, a service
+// with guardrails holds the answer back and delivers it as a single piece.
+func (s *Service) ReplyStream(ctx context.Context, userID, message string, onDelta func(string)) (string, error) {
+	if userID == "" || message == "" {
+		return "", fmt.Errorf("userID and message are required")
+	}
+
+	message, blocked := s.checkInput(ctx, userID, message)
+
+	// Save user message
+	if err := s.db.SaveMessage(userID, message, "user"); err != nil {
+		log.Printf("Error saving user message: %v", err)
+		return "", fmt.Errorf("failed to save message: %v", err)
+	}
+
+	response := blockedInputReply
+	var usage *models.MessageUsage
+	if blocked {
+		onDelta(response)
+	} else {
+		deliver := onDelta
+		if s.guardrails.Enabled() {
+			deliver = func(string) {}
+		}
+
+		var err error
+		response, usage, err = s.streamResponse(ctx, userID, s.persona(userID, ""), message, s.priorHistory(userID, message), deliver)
+		if err != nil {
+			return "", err
+		}
+
+		if s.guardrails.Enabled() {
+			response = s.checkOutput(ctx, userID, response)
+			onDelta(response)
+		}
+	}
+
+	// Save assistant response
+	if err := s.db.SaveAssistantMessage(userID, response, usage); err != nil {
+		log.Printf("Error saving assistant message: %v", err)
+	}
+	s.notifySummarizer()
+
+	return response, nil
+}
+
+func (s *Service) streamResponse(ctx context.Context, userID string, persona *models.Persona, userMessage string, history []models.Message, onDelta func(string)) (string, *models.MessageUsage, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	meter := newMeter()
+	if s.provider != nil {
+		summary, history := s.summarized(userID, history)
+		provider, model := s.route(ctx, userID, persona, userMessage, nil)
+		req, _ := s.request(persona, model, summary, userMessage, nil, history)
+
+		var response strings.Builder
+		for round := 0; ; round++ {
+			text, calls, err := s.streamRound(ctx, provider, req, meter, onDelta)
+			response.WriteString(text)
+			if err != nil {
+				log.Printf("LLM stream error after %d bytes: %v", response.Len(), err)
+				break
+			}
+			if len(calls) == 0 {
+				break
+			}
+			if round == s.maxToolRounds {
+				log.Printf("LLM provider error: %v", fmt.Errorf("%w (%d)", ErrTooManyToolRounds, round))
+				break
+			}
+			req.Messages = append(req.Messages, s.runTools(ctx, userID, text, calls)...)
+		}
+
+		if response.Len() > 0 {
+			return response.String(), s.usage(meter), nil
+		}
+		if ctx.Err() != nil {
+			return "", nil, canceled(ctx)
+		}
+	}
+
+	response := fallbackResponse(userMessage)
+	onDelta(response)
+	return response, s.usage(meter), nil
+}
+
+// streamRound streams provider's answer to req, passing its text to onDelta
+// and recording the call with meter, and returns the text and the tools the
+// model called, if any.
+func (s *Service) streamRound(ctx context.Context, provider llm.Provider, req llm.Request, meter *meter, onDelta func(string)) (string, []llm.ToolCall, error) {
+	chunks, err := provider.Stream(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var text strings.Builder
+	var calls []llm.ToolCall
+	var usage llm.Usage
+	finishReason, model := "", req.Model
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			return text.String(), nil, chunk.Err
+		}
+		if chunk.Delta != "" {
+			text.WriteString(chunk.Delta)
+			onDelta(chunk.Delta)
+		}
+		if len(chunk.ToolCalls) > 0 {
+			calls = chunk.ToolCalls
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		if chunk.FinishReason != "" {
+			finishReason = chunk.FinishReason
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+	}
+
+	if model == "" {
+		model = provider.Model()
+	}
+	if usage == (llm.Usage{}) {
+		meter.addEstimate(model, s.context.estimateUsage(req, text.String(), calls), finishReason)
+	} else {
+		meter.add(model, usage, finishReason)
+	}
+	return text.String(), calls, nil
+}
+
+// ErrCanceled is returned when the caller's context is cancelled or its
+// deadline passes before a reply was generated. It wraps the context error,
+// so errors.Is also distinguishes context.Canceled from context.DeadlineExceeded.
+var ErrCanceled = errors.New("reply generation canceled")
+
+func canceled(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+}
+
+// withTimeout applies the configured timeout unless ctx already has a deadline.
+// Unlike the caller's deadline, the breaker counts it running out as a failure.
+func (s *Service) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return llm.WithTimeout(ctx, s.timeout)
+}
+
+// ErrAlreadyAnswered is returned by AnswerInbound for a redelivered message that was already replied to.
+var ErrAlreadyAnswered = errors.New("message already answered")
+
+// ErrAlreadyRecorded is returned by RecordInbound for a redelivered message that was already stored.
+var ErrAlreadyRecorded = errors.New("message already recorded")
+
+// AnswerInbound stores a WhatsApp message identified by its wamid and
+// generates a reply without storing it; call RecordReply once the reply was
+// delivered. A redelivery of a message whose reply was recorded returns
+// ErrAlreadyAnswered, while one that was stored but never answered is
+// answered again. Like Reply, it fails with ErrCanceled when ctx ends before
+// a reply was generated. Messages carrying a SelectionID go to the handler
+// registered for it with OnSelection, if any, instead of the model. The
+// message, once transcribed, and the reply pass the guardrails; a blocked
+// message is stored as such and answered with a canned refusal.
+func (s *Service) AnswerInbound(ctx context.Context, in Inbound) (string, error) {
+	// Check redeliveries before transcribing or classifying, which cost model calls
+	answered, err := s.db.IsAnswered(in.WAMID)
+	if err != nil {
+		return "", err
+	}
+	if answered {
+		return "", ErrAlreadyAnswered
+	}
+
+	in, err = s.transcribe(ctx, in)
+	if err != nil {
+		return "", err
+	}
+
+	var blocked bool
+	in.Text, blocked = s.checkInput(ctx, in.UserID, in.Text)
+	if blocked {
+		in.Image = nil
+	}
+
+	err = s.db.SaveInboundMessage(in.message())
+	if errors.Is(err, database.ErrDuplicateMessage) {
+		log.Printf("Message %s was stored but not answered, answering again", in.WAMID)
+	} else if err != nil {
+		return "", fmt.Errorf("failed to save message: %w", err)
+	}
+
+	if blocked {
+		return blockedInputReply, nil
+	}
+
+	if handler := s.selections.match(in.SelectionID); handler != nil {
+		response, err := handler(ctx, in)
+		if err != nil {
+			log.Printf("Error handling selection %s: %v", in.SelectionID, err)
+			return errorReply, nil
+		}
+		return s.checkOutput(ctx, in.UserID, response), nil
+	}
+
+	var images []llm.Image
+	if in.Image != nil && s.UnderstandsImages() {
+		s.describeImage(ctx, in)
+		images = []llm.Image{*in.Image}
+	}
+
+	persona := s.persona(in.UserID, in.PhoneNumberID)
+	response, usage, err := s.generate(ctx, in.UserID, persona, in.Text, images, s.priorHistory(in.UserID, in.Text))
+	if errors.Is(err, ErrCanceled) {
+		return "", err
+	}
+	if err != nil {
+		log.Printf("Error generating response: %v", err)
+		response = errorReply
+	}
+	if usage != nil {
+		s.pending.put(in.WAMID, usage)
+	}
+
+	return s.checkOutput(ctx, in.UserID, response), nil
+}
+
+// RecordInbound stores a WhatsApp message that does not get a reply, such as a
+// reaction. A redelivery is not stored again and returns ErrAlreadyRecorded.
+func (s *Service) RecordInbound(in Inbound) error {
+	err := s.db.SaveInboundMessage(in.message())
+	if errors.Is(err, database.ErrDuplicateMessage) {
+		return ErrAlreadyRecorded
+	}
+	return err
+}
+
+func (in Inbound) message() models.Message {
+	return models.Message{
+		UserID:      in.UserID,
+		Content:     in.Text,
+		Role:        "user",
+		WAMID:       in.WAMID,
+		SelectionID: in.SelectionID,
+		FromAudio:   in.FromAudio,
+	}
+}
+
+// RecordReply stores a reply produced by AnswerInbound after it was sent as
+// the WhatsApp message wamid, with the tokens generating it took.
+func (s *Service) RecordReply(userID, replyTo, reply, wamid string) error {
+	if err := s.db.SaveReply(userID, reply, replyTo, wamid, s.pending.take(replyTo)); err != nil {
+		return err
+	}
+	s.notifySummarizer()
+	return nil
+}
+
+// priorHistory loads the context for answering message. The message itself was
+// just stored, so it is dropped from the history rather than sent twice, along
+// with the tool calls of an earlier attempt to answer it.
+func (s *Service) priorHistory(userID, message string) []models.Message {
+	history, err := s.db.GetChatHistory(userID, maxHistoryMessages+1)
+	if err != nil {
+		log.Printf("Error getting chat history: %v", err)
+		return nil
+	}
+
+	n := len(history)
+	for n > 0 && (history[n-1].Role == "tool" || len(history[n-1].ToolCalls) > 0) {
+		n--
+	}
+	if n > 0 && history[n-1].Role == "user" && history[n-1].Content == message {
+		return history[:n-1]
+	}
+	if len(history) > maxHistoryMessages {
+		return history[1:]
+	}
+	return history
+}
+
+// RecordContact creates the user on first contact and refreshes their profile afterwards.
+func (s *Service) RecordContact(userID, phoneNumber, name string) error {
+	return s.db.CreateOrUpdateUser(userID, phoneNumber, name)
+}
+
+// GenerateResponse generates a response with the default persona using the
+// language model or fallback. It fails with ErrCanceled instead of falling
+// back when ctx ends first. Tool calls made on the way are not stored.
+func (s *Service) GenerateResponse(ctx context.Context, userMessage string, history []models.Message) (string, error) {
+	response, _, err := s.generate(ctx, "", s.persona("", ""), userMessage, nil, history)
+	return response, err
+}
+
+// generate is GenerateResponse with the settings of persona, which may be nil,
+// storing tool calls in the history of userID unless it is empty and showing
+// the model images with userMessage. It also returns the usage of the model
+// calls made, or nil if none completed.
+func (s *Service) generate(ctx context.Context, userID string, persona *models.Persona, userMessage string, images []llm.Image, history []models.Message) (string, *models.MessageUsage, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Try the language model first
+	meter := newMeter()
+	if s.provider != nil {
+		summary, history := s.summarized(userID, history)
+		provider, model := s.route(ctx, userID, persona, userMessage, images)
+		req, estimate := s.request(persona, model, summary, userMessage, images, history)
+		response, err := s.complete(ctx, provider, userID, req, estimate, meter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", nil, canceled(ctx)
+			}
+			log.Printf("LLM provider error: %v", err)
+			// Fall through to fallback
+		} else {
+			return response.Content, s.usage(meter), nil
+		}
+	}
+
+	return fallbackResponse(userMessage), s.usage(meter), nil
+}
+
+// ErrTooManyToolRounds is returned when the model is still calling tools
+// after the configured number of rounds.
+var ErrTooManyToolRounds = errors.New("model kept calling tools")
+
+// complete sends req to provider and runs the tools the model calls, sending
+// their results back, until the model answers in text or maxToolRounds rounds
+// of calls were made. estimate describes the initial prompt; meter records each call.
+func (s *Service) complete(ctx context.Context, provider llm.Provider, userID string, req llm.Request, estimate promptEstimate, meter *meter) (*llm.Response, error) {
+	for round := 0; ; round++ {
+		response, err := provider.Complete(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		meter.add(response.Model, response.Usage, response.FinishReason)
+		if round == 0 && response.Usage.PromptTokens > 0 {
+			s.context.calibrate(estimate, response.Usage.PromptTokens)
+		}
+
+		if len(response.ToolCalls) == 0 {
+			return response, nil
+		}
+		if round == s.maxToolRounds {
+			return nil, fmt.Errorf("%w (%d)", ErrTooManyToolRounds, round)
+		}
+		req.Messages = append(req.Messages, s.runTools(ctx, userID, response.Content, response.ToolCalls)...)
+	}
+}
+
+// runTools runs the tools the model called in a message with content and
+// returns that message followed by the results, storing them all in the
+// history of userID unless it is empty.
+func (s *Service) runTools(ctx context.Context, userID, content string, calls []llm.ToolCall) []llm.Message {
+	messages := []llm.Message{{Role: "assistant", Content: content, ToolCalls: calls}}
+	for _, call := range calls {
+		log.Printf("Running tool %s for %s", call.Name, userID)
+		messages = append(messages, s.tools.Run(ctx, call))
+	}
+
+	if userID != "" {
+		for _, msg := range messages {
+			if err := s.db.SaveToolTurn(storedMessage(userID, msg)); err != nil {
+				log.Printf("Error saving tool turn: %v", err)
+			}
+		}
+	}
+
+	return messages
+}
+
+// request builds the input of model, as chosen by route, for answering
+// userMessage, showing images with it, after summary and as much of history
+// as fits the token budget, with the settings of persona if not nil.
+func (s *Service) request(persona *models.Persona, model, summary, userMessage string, images []llm.Image, history []models.Message) (llm.Request, promptEstimate) {
+	req := llm.Request{
+		Model:       model,
+		Temperature: llm.Temperature(replyTemperature),
+		MaxTokens:   s.replyTokens,
+	}
+	prompt := systemPrompt
+
+	if persona != nil {
+		prompt = persona.SystemPrompt
+		if persona.Temperature != nil {
+			req.Temperature = llm.Temperature(*persona.Temperature)
+		}
+		if persona.MaxTokens > 0 {
+			req.MaxTokens = persona.MaxTokens
+		}
+	}
+
+	// Images take room the text estimate does not see
+	var estimate promptEstimate
+	req.Messages, estimate = s.context.build(prompt, summary, history, userMessage, req.MaxTokens+len(images)*imageTokens)
+	if len(images) > 0 {
+		req.Messages[len(req.Messages)-1].Images = images
+		estimate.images = len(images)
+	}
+	req.Tools = s.tools.Tools()
+	return req, estimate
+}
+
+// persona returns the persona for userID on the business number phoneNumberID,
+// or nil to use the built-in settings.
+func (s *Service) persona(userID, phoneNumberID string) *models.Persona {
+	persona, err := s.db.ResolvePersona(userID, phoneNumberID)
+	if err != nil {
+		log.Printf("Error resolving persona for %s: %v", userID, err)
+		return nil
+	}
+	return persona
+}
+
+// fallbackResponse picks a canned reply when no language model is available.
+func fallbackResponse(userMessage string) string {
+	fallbackResponses := []string{
+		"I understand what you're saying. Let me help you with that.",
+		"That's an interesting point. Here's what I think about it.",
+		"I see what you mean. Let me provide some assistance.",
+		"Thanks for sharing that with me. I'm here to help.",
+		"I appreciate your message. How can I assist you further?",
+	}
+
+	// Simple hash-based response selection for consistency
+	hash := 0
+	for _, char := range userMessage {
+		hash += int(char)
+	}
+
+	responseIndex := hash % len(fallbackResponses)
+	return fallbackResponses[responseIndex]
+}
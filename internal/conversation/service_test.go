@@ -0,0 +1,490 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+func TestReply(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewService(db, &configs.Config{GrokModel: "grok-beta"})
+
+	t.Run("StoresBothTurns", func(t *testing.T) {
+		reply, err := service.Reply(context.Background(), "test-user", "Hello test")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if reply == "" {
+			t.Fatal("Expected a reply, got empty string")
+		}
+
+		history, err := db.GetChatHistory("test-user", 10)
+		if err != nil {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+
+		if len(history) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(history))
+		}
+
+		if history[0].Role != "user" || history[1].Role != "assistant" {
+			t.Errorf("Expected user then assistant, got %s then %s", history[0].Role, history[1].Role)
+		}
+	})
+
+	t.Run("MissingMessage", func(t *testing.T) {
+		if _, err := service.Reply(context.Background(), "test-user", ""); err == nil {
+			t.Error("Expected error for empty message")
+		}
+	})
+}
+
+func TestAnswerInbound(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewService(db, &configs.Config{GrokModel: "grok-beta"})
+	ctx := context.Background()
+	inbound := Inbound{UserID: "15550001111", WAMID: "wamid.1", Text: "Hello"}
+
+	reply, err := service.AnswerInbound(ctx, inbound)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Redelivered before the reply was recorded: answer again
+	if _, err := service.AnswerInbound(ctx, inbound); err != nil {
+		t.Fatalf("Expected unanswered redelivery to be answered, got: %v", err)
+	}
+
+	if err := service.RecordReply("15550001111", "wamid.1", reply, "wamid.out.1"); err != nil {
+		t.Fatalf("Failed to record reply: %v", err)
+	}
+
+	// Redelivered after the reply was recorded: skip
+	if _, err := service.AnswerInbound(ctx, inbound); !errors.Is(err, ErrAlreadyAnswered) {
+		t.Errorf("Expected ErrAlreadyAnswered, got: %v", err)
+	}
+
+	count, err := db.GetUserMessageCount("15550001111")
+	if err != nil {
+		t.Fatalf("Failed to count messages: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 stored messages, got %d", count)
+	}
+}
+
+func TestAnswerInboundSelection(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{
+		GrokModel:        "grok-beta",
+		SelectionReplies: `{"answer:yes": "Glad it helped!", "order:*": "Overridden in code"}`,
+	}
+	service := NewService(db, config)
+	service.OnSelection("order:*", func(ctx context.Context, in Inbound) (string, error) {
+		return "Tracking order " + strings.TrimPrefix(in.SelectionID, "order:"), nil
+	})
+	service.OnSelection("order:cancel", func(ctx context.Context, in Inbound) (string, error) {
+		return "Order cancelled", nil
+	})
+
+	tests := []struct {
+		selectionID string
+		want        string
+	}{
+		{"order:1042", "Tracking order 1042"},
+		{"order:cancel", "Order cancelled"},
+		{"answer:yes", "Glad it helped!"},
+	}
+
+	for i, tt := range tests {
+		reply, err := service.AnswerInbound(context.Background(), Inbound{
+			UserID:      "15550001111",
+			WAMID:       fmt.Sprintf("wamid.%d", i),
+			Text:        "Some button title",
+			SelectionID: tt.selectionID,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if reply != tt.want {
+			t.Errorf("Selection %s: expected %q, got %q", tt.selectionID, tt.want, reply)
+		}
+	}
+
+	history, err := db.GetChatHistory("15550001111", 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 3 || history[0].SelectionID == "" {
+		t.Errorf("Expected stored messages to keep their selection IDs, got %+v", history)
+	}
+}
+
+func TestReplyCanceled(t *testing.T) {
+	// Grok API that answers only after the caller gave up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(500 * time.Millisecond):
+		}
+	}))
+	defer server.Close()
+
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewService(db, &configs.Config{
+		GrokAPIKey:  "test-key",
+		GrokModel:   "grok-beta",
+		GrokBaseURL: server.URL,
+		GrokTimeout: 20 * time.Millisecond,
+	})
+
+	t.Run("DefaultTimeout", func(t *testing.T) {
+		_, err := service.Reply(context.Background(), "test-user", "Hello")
+		if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected ErrCanceled after the deadline, got: %v", err)
+		}
+	})
+
+	t.Run("CallerCancels", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := service.Reply(ctx, "test-user", "Hello again")
+		if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected ErrCanceled after cancellation, got: %v", err)
+		}
+	})
+
+	// Neither attempt may store the canned fallback as an answer
+	history, err := db.GetChatHistory("test-user", 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	for _, msg := range history {
+		if msg.Role == "assistant" {
+			t.Errorf("Expected no assistant reply, got %q", msg.Content)
+		}
+	}
+}
+
+func TestReplyWithProvider(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	provider := llm.NewScripted("First answer", "Second answer")
+	service := NewService(db, &configs.Config{LLMProvider: "none"})
+	service.SetProvider(provider)
+
+	ctx := context.Background()
+	if _, err := service.Reply(ctx, "test-user", "First question"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	reply, err := service.Reply(ctx, "test-user", "Second question")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if reply != "Second answer" {
+		t.Errorf("Expected scripted answer, got %q", reply)
+	}
+
+	// System prompt, the first exchange, then the new question exactly once
+	requests := provider.Requests()
+	messages := requests[1].Messages
+	roles := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		roles = append(roles, msg.Role)
+	}
+	if got := strings.Join(roles, ","); got != "system,user,assistant,user" {
+		t.Errorf("Expected system,user,assistant,user, got %s", got)
+	}
+	if last := messages[len(messages)-1]; last.Content != "Second question" {
+		t.Errorf("Expected the new question last, got %q", last.Content)
+	}
+}
+
+func TestPersonas(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	temperature, precise := 0.2, 0.0
+	for _, persona := range []models.Persona{
+		{Name: "support", SystemPrompt: "You are patient support.", Temperature: &temperature, MaxTokens: 300},
+		{Name: "sales", SystemPrompt: "You are an upbeat sales rep.", Model: "grok-sales"},
+		{Name: "billing", SystemPrompt: "You answer billing questions.", Temperature: &precise},
+	} {
+		if err := db.CreatePersona(&persona); err != nil {
+			t.Fatalf("Failed to create persona: %v", err)
+		}
+	}
+	if err := db.AssignTenantPersona("support-number", "support"); err != nil {
+		t.Fatalf("Failed to assign persona: %v", err)
+	}
+	if err := db.AssignUserPersona("15550002222", "sales"); err != nil {
+		t.Fatalf("Failed to assign persona: %v", err)
+	}
+	if err := db.AssignTenantPersona("billing-number", "billing"); err != nil {
+		t.Fatalf("Failed to assign persona: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		inbound         Inbound
+		wantPrompt      string
+		wantTemperature float64
+		wantMaxTokens   int
+		wantModel       string
+	}{
+		{"NoAssignment", Inbound{UserID: "15550001111", PhoneNumberID: "other-number"}, systemPrompt, replyTemperature, defaultReplyTokens, ""},
+		{"Tenant", Inbound{UserID: "15550001111", PhoneNumberID: "support-number"}, "You are patient support.", 0.2, 300, ""},
+		{"UserWinsOverTenant", Inbound{UserID: "15550002222", PhoneNumberID: "support-number"}, "You are an upbeat sales rep.", replyTemperature, defaultReplyTokens, "grok-sales"},
+		{"ZeroTemperature", Inbound{UserID: "15550003333", PhoneNumberID: "billing-number"}, "You answer billing questions.", 0, defaultReplyTokens, ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := llm.NewScripted("Hi!")
+			service := NewService(db, &configs.Config{LLMProvider: "none"})
+			service.SetProvider(provider)
+
+			tt.inbound.WAMID = fmt.Sprintf("wamid.%d", i)
+			tt.inbound.Text = "Hello"
+			if _, err := service.AnswerInbound(context.Background(), tt.inbound); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			req := provider.Requests()[0]
+			if req.Messages[0].Content != tt.wantPrompt {
+				t.Errorf("Expected system prompt %q, got %q", tt.wantPrompt, req.Messages[0].Content)
+			}
+			if req.Temperature == nil || *req.Temperature != tt.wantTemperature || req.MaxTokens != tt.wantMaxTokens || req.Model != tt.wantModel {
+				t.Errorf("Expected temperature %g, max tokens %d and model %q, got %+v",
+					tt.wantTemperature, tt.wantMaxTokens, tt.wantModel, req)
+			}
+		})
+	}
+
+	// A default persona replaces the built-in prompt for everyone else
+	if err := db.CreatePersona(&models.Persona{Name: database.DefaultPersona, SystemPrompt: "You are the default."}); err != nil {
+		t.Fatalf("Failed to create persona: %v", err)
+	}
+	provider := llm.NewScripted("Hi!")
+	service := NewService(db, &configs.Config{LLMProvider: "none"})
+	service.SetProvider(provider)
+	if _, err := service.Reply(context.Background(), "15550003333", "Hello"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := provider.Requests()[0].Messages[0].Content; got != "You are the default." {
+		t.Errorf("Expected the default persona's prompt, got %q", got)
+	}
+}
+
+func TestReplyWithTools(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	weatherCall := llm.Response{
+		ToolCalls:    []llm.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Oslo"}`}},
+		FinishReason: "tool_calls",
+	}
+	ctx := context.Background()
+
+	newService := func(provider llm.Provider, maxRounds int) *Service {
+		service := NewService(db, &configs.Config{LLMProvider: "none", LLMMaxToolRounds: maxRounds})
+		service.SetProvider(provider)
+		service.RegisterTool(llm.Tool{Name: "get_weather", Parameters: json.RawMessage(`{"type":"object"}`)},
+			func(ctx context.Context, arguments json.RawMessage) (string, error) {
+				return "Sunny, 21C in " + string(arguments), nil
+			})
+		return service
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		provider := llm.NewScripted().Respond(weatherCall).Reply("It's sunny in Oslo.")
+		reply, err := newService(provider, 0).Reply(ctx, "tool-user", "Weather in Oslo?")
+		if err != nil || reply != "It's sunny in Oslo." {
+			t.Fatalf("Expected the final answer, got %q, error %v", reply, err)
+		}
+
+		requests := provider.Requests()
+		if len(requests[0].Tools) != 1 {
+			t.Errorf("Expected the tool to be offered, got %+v", requests[0].Tools)
+		}
+		result := requests[1].Messages[len(requests[1].Messages)-1]
+		if result.Role != "tool" || result.ToolCallID != "call_1" || result.Content != `Sunny, 21C in {"city":"Oslo"}` {
+			t.Errorf("Expected the tool result sent back, got %+v", result)
+		}
+
+		history, err := db.GetChatHistory("tool-user", 10)
+		if err != nil {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+		var roles []string
+		for _, msg := range history {
+			roles = append(roles, msg.Role)
+		}
+		if got := strings.Join(roles, ","); got != "user,assistant,tool,assistant" {
+			t.Fatalf("Expected user,assistant,tool,assistant, got %s", got)
+		}
+		if history[1].ToolCalls[0].Name != "get_weather" || history[2].ToolCallID != "call_1" {
+			t.Errorf("Expected the tool turns stored, got %+v", history[1:3])
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		provider := llm.NewScripted().Respond(weatherCall).Reply("Still sunny.")
+		var streamed strings.Builder
+		reply, err := newService(provider, 0).ReplyStream(ctx, "stream-tool-user", "And now?", func(delta string) {
+			streamed.WriteString(delta)
+		})
+		if err != nil || reply != "Still sunny." || streamed.String() != reply {
+			t.Fatalf("Expected the final answer streamed, got %q (%q), error %v", reply, streamed.String(), err)
+		}
+		if got := len(provider.Requests()); got != 2 {
+			t.Errorf("Expected 2 requests, got %d", got)
+		}
+	})
+
+	t.Run("RoundLimit", func(t *testing.T) {
+		provider := llm.NewScripted().Respond(weatherCall).Respond(weatherCall).Respond(weatherCall)
+		reply, err := newService(provider, 2).Reply(ctx, "loop-user", "Weather?")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if reply != fallbackResponse("Weather?") {
+			t.Errorf("Expected the fallback reply, got %q", reply)
+		}
+		if got := len(provider.Requests()); got != 3 {
+			t.Errorf("Expected the initial request and 2 rounds, got %d requests", got)
+		}
+	})
+}
+
+func TestUsage(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	answer := llm.Response{
+		Content:      "Hello!",
+		Model:        "grok-beta",
+		FinishReason: "stop",
+		Usage:        llm.Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100},
+	}
+	provider := llm.NewScripted().Respond(answer).Respond(answer).Respond(answer)
+	service := NewService(db, &configs.Config{LLMProvider: "none", LLMPrices: "grok-beta=5:15"})
+	service.SetProvider(provider)
+	ctx := context.Background()
+
+	lastUsage := func(userID string) *models.MessageUsage {
+		history, err := db.GetChatHistory(userID, 1)
+		if err != nil || len(history) != 1 {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+		usage, err := db.GetMessageUsage(int64(history[0].ID))
+		if err != nil || usage == nil {
+			t.Fatalf("Expected usage for message %d, got %v", history[0].ID, err)
+		}
+		return usage
+	}
+
+	if _, err := service.Reply(ctx, "15550001111", "Hi"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	usage := lastUsage("15550001111")
+	if usage.Model != "grok-beta" || usage.TotalTokens != 1100 || usage.FinishReason != "stop" {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+	if usage.CostUSD == nil || *usage.CostUSD != 0.0065 {
+		t.Errorf("Expected a cost of $0.0065, got %v", usage.CostUSD)
+	}
+
+	// WhatsApp replies get their usage once they were sent
+	inbound := Inbound{UserID: "15550002222", WAMID: "wamid.usage", Text: "Hi"}
+	reply, err := service.AnswerInbound(ctx, inbound)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := service.RecordReply(inbound.UserID, inbound.WAMID, reply, "wamid.out"); err != nil {
+		t.Fatalf("Failed to record reply: %v", err)
+	}
+	if usage := lastUsage("15550002222"); usage.PromptTokens != 1000 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	if _, err := service.Reply(ctx, "15550001111", "Again"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	byUser, err := db.GetUsage(database.UsageByUser, 1, "", 0)
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if len(byUser) != 2 || byUser[0].Key != "15550001111" || byUser[0].Messages != 2 || byUser[0].TotalTokens != 2200 {
+		t.Errorf("Expected 15550001111 first with 2 messages, got %+v", byUser)
+	}
+
+	// Streams whose provider reports no usage get an estimate
+	service.SetProvider(llm.NewScripted("Hello there, how can I help you today?"))
+	if _, err := service.ReplyStream(ctx, "15550003333", "Hi", func(string) {}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	usage = lastUsage("15550003333")
+	if !usage.Estimated || usage.PromptTokens == 0 || usage.CompletionTokens == 0 {
+		t.Errorf("Expected estimated usage, got %+v", usage)
+	}
+	byModel, err := db.GetUsage(database.UsageByModel, 1, "15550003333", 0)
+	if err != nil || len(byModel) != 1 || byModel[0].EstimatedMessages != 1 {
+		t.Errorf("Expected one estimated message, got %+v, error %v", byModel, err)
+	}
+}
@@ -0,0 +1,53 @@
+package conversation
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/speech"
+)
+
+// SetTranscriber replaces the speech-to-text backend used for voice notes;
+// nil leaves them as placeholders.
+func (s *Service) SetTranscriber(transcriber speech.Transcriber) {
+	s.transcriber = transcriber
+	if transcriber != nil {
+		log.Printf("Speech-to-text %s initialized", transcriber.Name())
+	}
+}
+
+// TranscribesAudio reports whether inbound voice notes are transcribed.
+func (s *Service) TranscribesAudio() bool {
+	return s.transcriber != nil
+}
+
+// transcribe replaces the placeholder text of a voice note with what was said
+// in it. A recording that cannot be transcribed keeps its placeholder; only
+// ctx ending first is an error, ErrCanceled.
+func (s *Service) transcribe(ctx context.Context, in Inbound) (Inbound, error) {
+	if in.Audio == nil || s.transcriber == nil {
+		return in, nil
+	}
+
+	start := time.Now()
+	transcribeCtx, cancel := context.WithTimeout(ctx, s.speechTimeout)
+	defer cancel()
+
+	text, err := s.transcriber.Transcribe(transcribeCtx, *in.Audio)
+	if ctx.Err() != nil {
+		return in, canceled(ctx)
+	}
+	if err != nil {
+		log.Printf("Error transcribing voice note %s: %v", in.WAMID, err)
+		return in, nil
+	}
+	if text == "" {
+		log.Printf("No speech found in voice note %s", in.WAMID)
+		return in, nil
+	}
+
+	log.Printf("Transcribed voice note %s from %s in %s", in.WAMID, in.UserID, time.Since(start).Round(time.Millisecond))
+	in.Text, in.FromAudio = text, true
+	return in, nil
+}
@@ -0,0 +1,116 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/speech"
+)
+
+// fakeTranscriber returns a fixed transcript or error, counting its calls
+// if calls is set.
+type fakeTranscriber struct {
+	text  string
+	err   error
+	calls *int
+}
+
+func (f fakeTranscriber) Name() string { return "fake" }
+
+func (f fakeTranscriber) Transcribe(ctx context.Context, audio speech.Audio) (string, error) {
+	if f.calls != nil {
+		*f.calls++
+	}
+	return f.text, f.err
+}
+
+func TestAnswerInboundVoiceNote(t *testing.T) {
+	tests := []struct {
+		name          string
+		transcriber   fakeTranscriber
+		wantText      string
+		wantFromAudio bool
+	}{
+		{"Transcribed", fakeTranscriber{text: "Where is my order?"}, "Where is my order?", true},
+		{"Failed", fakeTranscriber{err: errors.New("unsupported codec")}, "[Voice note]", false},
+		{"Silent", fakeTranscriber{}, "[Voice note]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create test database
+			db, err := database.InitDB(":memory:")
+			if err != nil {
+				t.Fatalf("Failed to create test database: %v", err)
+			}
+			defer db.Close()
+
+			provider := llm.NewScripted("It ships tomorrow.")
+			service := NewService(db, &configs.Config{LLMProvider: "none"})
+			service.SetProvider(provider)
+			service.SetTranscriber(tt.transcriber)
+
+			in := Inbound{
+				UserID: "15550001111",
+				WAMID:  "wamid.voice",
+				Text:   "[Voice note]",
+				Audio:  &speech.Audio{MimeType: "audio/ogg", Data: []byte("opus")},
+			}
+			if _, err := service.AnswerInbound(context.Background(), in); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			history, err := db.GetChatHistory("15550001111", 1)
+			if err != nil || len(history) != 1 {
+				t.Fatalf("Failed to get history: %v", err)
+			}
+			if history[0].Content != tt.wantText || history[0].FromAudio != tt.wantFromAudio {
+				t.Errorf("Expected %q (from audio %v), got %+v", tt.wantText, tt.wantFromAudio, history[0])
+			}
+
+			messages := provider.Requests()[0].Messages
+			if got := messages[len(messages)-1].Content; got != tt.wantText {
+				t.Errorf("Expected the model to be asked about %q, got %q", tt.wantText, got)
+			}
+		})
+	}
+}
+
+func TestAnswerInboundVoiceNoteRedelivery(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	var calls int
+	service := NewService(db, &configs.Config{LLMProvider: "none"})
+	service.SetProvider(llm.NewScripted("It ships tomorrow."))
+	service.SetTranscriber(fakeTranscriber{text: "Where is my order?", calls: &calls})
+
+	in := Inbound{
+		UserID: "15550001111",
+		WAMID:  "wamid.voice",
+		Text:   "[Voice note]",
+		Audio:  &speech.Audio{MimeType: "audio/ogg", Data: []byte("opus")},
+	}
+	reply, err := service.AnswerInbound(context.Background(), in)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := service.RecordReply(in.UserID, in.WAMID, reply, "wamid.reply"); err != nil {
+		t.Fatalf("Failed to record reply: %v", err)
+	}
+
+	if _, err := service.AnswerInbound(context.Background(), in); !errors.Is(err, ErrAlreadyAnswered) {
+		t.Fatalf("Expected ErrAlreadyAnswered, got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the voice note to be transcribed once, got %d calls", calls)
+	}
+}
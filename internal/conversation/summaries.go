@@ -0,0 +1,215 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// Summarizer settings. summaryInterval is how often conversations are checked
+// when no reply wakes the summarizer; summaryBatch bounds the conversations
+// summarized per check and maxSummaryMessages the messages folded in at once.
+const (
+	summaryInterval    = 10 * time.Minute
+	summaryBatch       = 20
+	maxSummaryMessages = 400
+	summaryTokens      = 500
+	summaryTemperature = 0.2
+)
+
+// summarizerPrompt instructs the model to fold new messages into a summary.
+const summarizerPrompt = "You maintain a running summary of a WhatsApp conversation between a user and an assistant. Merge the previous summary, if any, with the new messages into one updated summary. Keep what the user told about themselves, their preferences, open questions and anything the assistant promised; leave out small talk. Write a few short paragraphs at most."
+
+// StartSummarizer starts a goroutine that keeps the summaries of long
+// conversations up to date until ctx is cancelled. It checks for
+// conversations that outgrew their summary after each stored reply and every
+// summaryInterval. It does nothing when summaries are disabled.
+func (s *Service) StartSummarizer(ctx context.Context) {
+	if s.summaryThreshold <= 0 {
+		log.Printf("Conversation summaries disabled")
+		return
+	}
+
+	go s.runSummarizer(ctx)
+	log.Printf("Summarizing conversations over %d messages, keeping the last %d", s.summaryThreshold, s.summaryKeep)
+}
+
+func (s *Service) runSummarizer(ctx context.Context) {
+	ticker := time.NewTicker(summaryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.summarizeDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.summaryWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// notifySummarizer wakes the summarizer without blocking; a check already
+// pending covers this one.
+func (s *Service) notifySummarizer() {
+	select {
+	case s.summaryWake <- struct{}{}:
+	default:
+	}
+}
+
+// summarizeDue updates the summaries of the conversations with more than
+// summaryThreshold messages beyond their summary.
+func (s *Service) summarizeDue(ctx context.Context) {
+	if s.provider == nil {
+		return
+	}
+
+	users, err := s.db.GetUsersToSummarize(s.summaryThreshold, summaryBatch)
+	if err != nil {
+		log.Printf("Error finding conversations to summarize: %v", err)
+		return
+	}
+
+	for _, userID := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.summarize(ctx, userID); err != nil {
+			log.Printf("Error summarizing conversation of %s: %v", userID, err)
+		}
+	}
+}
+
+// summarize folds the messages of userID beyond the summary, except the
+// newest summaryKeep, into the summary.
+func (s *Service) summarize(ctx context.Context, userID string) error {
+	previous, err := s.db.GetSummary(userID)
+	if err != nil {
+		return err
+	}
+	summary := models.Summary{UserID: userID}
+	if previous != nil {
+		summary = *previous
+	}
+
+	messages, err := s.db.GetMessagesAfter(userID, summary.ThroughMessageID, maxSummaryMessages+s.summaryKeep)
+	if err != nil {
+		return err
+	}
+	cut := len(messages) - s.summaryKeep
+	// A tool call stays with its results
+	for cut > 0 && messages[cut].Role == "tool" {
+		cut--
+	}
+	if cut <= 0 {
+		return nil
+	}
+
+	// The previous summary and the new messages have to fit the context
+	ratio := s.context.ratio()
+	budget := s.context.contextTokens - summaryTokens - tokensPerReply -
+		estimateTokens(summarizerPrompt, ratio) - estimateTokens(summary.Content, ratio)
+	lines := make([]string, 0, cut)
+	chars := 0
+	for _, msg := range messages[:cut] {
+		line := transcriptLine(msg)
+		chars += utf8.RuneCountInString(line)
+		if len(lines) > 0 && tokensPerMessage+int(float64(chars)/ratio) > budget {
+			break
+		}
+		lines = append(lines, line)
+	}
+	n := len(lines)
+	for n < cut && n > 1 && messages[n].Role == "tool" {
+		n--
+	}
+	content := strings.Join(lines[:n], "")
+	if estimateTokens(content, ratio) > budget {
+		content = truncate(content, budget, ratio)
+	}
+
+	prompt := "New messages:\n" + content
+	if summary.Content != "" {
+		prompt = "Previous summary:\n" + summary.Content + "\n\n" + prompt
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	meter := newMeter()
+	response, err := s.provider.Complete(ctx, llm.Request{
+		Messages: []llm.Message{
+			{Role: "system", Content: summarizerPrompt},
+			{Role: "user", Content: prompt},
+		},
+		Temperature: llm.Temperature(summaryTemperature),
+		MaxTokens:   summaryTokens,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate summary: %w", err)
+	}
+	meter.add(response.Model, response.Usage, response.FinishReason)
+	s.recordUsage(models.UsageKindSummary, userID, meter)
+	if strings.TrimSpace(response.Content) == "" {
+		return fmt.Errorf("failed to generate summary: empty response")
+	}
+
+	summary.Content = strings.TrimSpace(response.Content)
+	summary.ThroughMessageID = int64(messages[n-1].ID)
+	summary.MessageCount += n
+	if err := s.db.SaveSummary(summary); err != nil {
+		return err
+	}
+
+	log.Printf("Summarized %d messages of %s (%d tokens)", n, userID, response.Usage.TotalTokens)
+	return nil
+}
+
+// transcriptLine renders a stored message for the summarizer.
+func transcriptLine(msg models.Message) string {
+	switch {
+	case msg.Role == "tool":
+		return "Tool result: " + msg.Content + "\n"
+	case len(msg.ToolCalls) > 0:
+		var calls []string
+		for _, call := range msg.ToolCalls {
+			calls = append(calls, call.Name+call.Arguments)
+		}
+		return "Assistant called " + strings.Join(calls, ", ") + "\n"
+	case msg.Role == "user":
+		return "User: " + messageText(msg) + "\n"
+	default:
+		return "Assistant: " + msg.Content + "\n"
+	}
+}
+
+// summarized returns the summary of userID's conversation, if any, and the
+// part of history, ordered oldest first, that it does not cover.
+func (s *Service) summarized(userID string, history []models.Message) (string, []models.Message) {
+	if userID == "" || s.summaryThreshold <= 0 {
+		return "", history
+	}
+
+	summary, err := s.db.GetSummary(userID)
+	if err != nil {
+		log.Printf("Error getting summary for %s: %v", userID, err)
+		return "", history
+	}
+	if summary == nil {
+		return "", history
+	}
+
+	start := 0
+	for start < len(history) && int64(history[start].ID) <= summary.ThroughMessageID {
+		start++
+	}
+	return summary.Content, history[start:]
+}
@@ -0,0 +1,79 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+func TestSummaries(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	userID := "15550001111"
+	for i := 1; i <= 8; i++ {
+		role := "user"
+		if i%2 == 0 {
+			role = "assistant"
+		}
+		if err := db.SaveMessage(userID, fmt.Sprintf("m%d", i), role); err != nil {
+			t.Fatalf("Failed to save message: %v", err)
+		}
+	}
+
+	summarized := llm.Response{Content: "The user likes tea.", Model: "grok-beta", Usage: llm.Usage{PromptTokens: 200, CompletionTokens: 10, TotalTokens: 210}}
+	provider := llm.NewScripted().Respond(summarized).Reply("Sure.")
+	service := NewService(db, &configs.Config{LLMProvider: "none", LLMSummaryThreshold: 6, LLMSummaryKeep: 2, LLMPrices: "grok-beta=5:15"})
+	service.SetProvider(provider)
+	ctx := context.Background()
+
+	// Everything but the newest two messages is summarized
+	service.summarizeDue(ctx)
+	summary, err := db.GetSummary(userID)
+	if err != nil || summary == nil {
+		t.Fatalf("Expected a summary, got %v", err)
+	}
+	if summary.Content != "The user likes tea." || summary.MessageCount != 6 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	// The summary's cost is reported with the user's usage
+	byKind, err := db.GetUsage(database.UsageByKind, 1, userID, 0)
+	if err != nil || len(byKind) != 1 || byKind[0].Key != models.UsageKindSummary || byKind[0].TotalTokens != 210 || byKind[0].CostUSD == 0 {
+		t.Errorf("Expected the summary's usage, got %+v, error %v", byKind, err)
+	}
+
+	prompt := provider.Requests()[0].Messages[1].Content
+	if !strings.Contains(prompt, "User: m1") || !strings.Contains(prompt, "Assistant: m6") || strings.Contains(prompt, "m7") {
+		t.Errorf("Expected m1 to m6 in the summarizer prompt, got %q", prompt)
+	}
+
+	// Replies see the summary instead of the messages it covers
+	if _, err := service.Reply(ctx, userID, "m9"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	messages := provider.Requests()[1].Messages
+	var contents []string
+	for _, msg := range messages[1:] {
+		contents = append(contents, msg.Content)
+	}
+	want := []string{summaryPrefix + "The user likes tea.", "m7", "m8", "m9"}
+	if strings.Join(contents, "|") != strings.Join(want, "|") || messages[1].Role != "system" {
+		t.Errorf("Expected %q after the system prompt, got %q", want, contents)
+	}
+
+	// Four messages beyond the summary do not call for a new one
+	service.summarizeDue(ctx)
+	if got := len(provider.Requests()); got != 2 {
+		t.Errorf("Expected no summarizer request below the threshold, got %d requests", got)
+	}
+}
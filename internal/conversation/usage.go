@@ -0,0 +1,122 @@
+package conversation
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// pendingUsageTTL bounds how long the usage of a reply from AnswerInbound is
+// kept waiting for RecordReply, for replies that are never sent.
+const pendingUsageTTL = time.Hour
+
+// meter adds up the model calls behind one reply.
+type meter struct {
+	start time.Time
+	calls int
+	model string
+	usage llm.Usage
+	// finishReason is that of the last call
+	finishReason string
+	// estimated is set once a call's usage had to be estimated
+	estimated bool
+}
+
+func newMeter() *meter {
+	return &meter{start: time.Now()}
+}
+
+// add records a model call that answered with usage.
+func (m *meter) add(model string, usage llm.Usage, finishReason string) {
+	m.calls++
+	if model != "" {
+		m.model = model
+	}
+	m.usage.PromptTokens += usage.PromptTokens
+	m.usage.CompletionTokens += usage.CompletionTokens
+	m.usage.TotalTokens += usage.TotalTokens
+	m.finishReason = finishReason
+}
+
+// addEstimate records a model call whose usage the provider did not report
+// and was estimated instead.
+func (m *meter) addEstimate(model string, usage llm.Usage, finishReason string) {
+	m.add(model, usage, finishReason)
+	m.estimated = true
+}
+
+// usage returns what the metered calls consumed and cost, or nil if no call
+// completed.
+func (s *Service) usage(m *meter) *models.MessageUsage {
+	if m == nil || m.calls == 0 {
+		return nil
+	}
+
+	usage := &models.MessageUsage{
+		Model:            m.model,
+		PromptTokens:     m.usage.PromptTokens,
+		CompletionTokens: m.usage.CompletionTokens,
+		TotalTokens:      m.usage.TotalTokens,
+		LatencyMs:        time.Since(m.start).Milliseconds(),
+		FinishReason:     m.finishReason,
+		Estimated:        m.estimated,
+		CreatedAt:        time.Now(),
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if cost, ok := s.prices.Cost(m.model, m.usage); ok {
+		usage.CostUSD = &cost
+	}
+	return usage
+}
+
+// recordUsage stores what the metered calls made for userID outside of a
+// reply, such as a summary, consumed under kind.
+func (s *Service) recordUsage(kind, userID string, m *meter) {
+	usage := s.usage(m)
+	if usage == nil {
+		return
+	}
+	usage.Kind, usage.UserID = kind, userID
+	if usage.Model == "" && s.provider != nil {
+		usage.Model = s.provider.Model()
+	}
+	if err := s.db.RecordUsage(usage); err != nil {
+		log.Printf("Error saving %s usage for %s: %v", kind, userID, err)
+	}
+}
+
+// pendingUsage holds the usage of replies generated by AnswerInbound until
+// RecordReply stores them, keyed by the wamid of the message answered.
+type pendingUsage struct {
+	mu      sync.Mutex
+	byWAMID map[string]*models.MessageUsage
+}
+
+func (p *pendingUsage) put(wamid string, usage *models.MessageUsage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.byWAMID == nil {
+		p.byWAMID = make(map[string]*models.MessageUsage)
+	}
+	for key, pending := range p.byWAMID {
+		if time.Since(pending.CreatedAt) > pendingUsageTTL {
+			delete(p.byWAMID, key)
+		}
+	}
+	p.byWAMID[wamid] = usage
+}
+
+func (p *pendingUsage) take(wamid string) *models.MessageUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := p.byWAMID[wamid]
+	delete(p.byWAMID, wamid)
+	return usage
+}
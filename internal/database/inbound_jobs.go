@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// EnqueueInboundJob stores a raw webhook payload for asynchronous processing.
+// Jobs of the same userID, the sender of the payload's messages, are claimed
+// one at a time in the order they were queued; "" leaves the job unordered.
+func (db *DB) EnqueueInboundJob(payload []byte, userID string) (int64, error) {
+	if len(payload) == 0 {
+		return 0, fmt.Errorf("payload is required")
+	}
+
+	result, err := db.conn.Exec(`INSERT INTO inbound_jobs (payload, user_id) VALUES (?, NULLIF(?, ''))`, payload, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue inbound job: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get inbound job id: %w", err)
+	}
+
+	return id, nil
+}
+
+// ClaimInboundJob marks the oldest due job as processing and returns it.
+// A job waits while an earlier job of the same user is pending or being
+// processed, so each user's messages are answered in order. It returns nil
+// when no job is due.
+func (db *DB) ClaimInboundJob() (*models.InboundJob, error) {
+	query := `
+		UPDATE inbound_jobs
+		SET status = 'processing', attempts = attempts + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM inbound_jobs AS j
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			AND (user_id IS NULL OR NOT EXISTS (
+				SELECT 1 FROM inbound_jobs AS e
+				WHERE e.user_id = j.user_id AND e.id < j.id AND e.status IN ('pending', 'processing')
+			))
+			ORDER BY id
+			LIMIT 1
+		)
+		RETURNING id, payload, status, attempts, last_error, created_at
+	`
+
+	var job models.InboundJob
+	var lastError sql.NullString
+
+	err := db.conn.QueryRow(query).Scan(
+		&job.ID, &job.Payload, &job.Status, &job.Attempts, &lastError, &job.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Nothing due
+		}
+		return nil, fmt.Errorf("failed to claim inbound job: %w", err)
+	}
+
+	if lastError.Valid {
+		job.LastError = lastError.String
+	}
+
+	return &job, nil
+}
+
+// CompleteInboundJob marks a job as successfully processed.
+func (db *DB) CompleteInboundJob(id int64) error {
+	query := `UPDATE inbound_jobs SET status = 'done', last_error = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := db.conn.Exec(query, id); err != nil {
+		return fmt.Errorf("failed to complete inbound job: %w", err)
+	}
+	return nil
+}
+
+// RetryInboundJob records a processing failure and schedules the job again
+// after delay. Once the job has used maxAttempts it is parked as failed.
+func (db *DB) RetryInboundJob(id int64, cause error, delay time.Duration, maxAttempts int) error {
+	query := `
+		UPDATE inbound_jobs
+		SET status = CASE WHEN attempts >= ? THEN 'failed' ELSE 'pending' END,
+			last_error = ?,
+			next_attempt_at = datetime('now', ?),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	offset := fmt.Sprintf("+%d seconds", int(delay.Seconds()))
+	if _, err := db.conn.Exec(query, maxAttempts, cause.Error(), offset, id); err != nil {
+		return fmt.Errorf("failed to reschedule inbound job: %w", err)
+	}
+	return nil
+}
+
+// RecoverInboundJobs returns jobs left in processing by a previous run to the queue.
+func (db *DB) RecoverInboundJobs() (int64, error) {
+	query := `UPDATE inbound_jobs SET status = 'pending', updated_at = CURRENT_TIMESTAMP WHERE status = 'processing'`
+
+	result, err := db.conn.Exec(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover inbound jobs: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected > 0 {
+		log.Printf("Recovered %d unfinished inbound jobs", affected)
+	}
+
+	return affected, nil
+}
+
+// CleanupInboundJobs deletes completed jobs older than maxAge.
+func (db *DB) CleanupInboundJobs(maxAge time.Duration) error {
+	query := `DELETE FROM inbound_jobs WHERE status = 'done' AND updated_at < datetime('now', ?)`
+
+	offset := fmt.Sprintf("-%d seconds", int(maxAge.Seconds()))
+	result, err := db.conn.Exec(query, offset)
+	if err != nil {
+		return fmt.Errorf("failed to cleanup inbound jobs: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected > 0 {
+		log.Printf("Cleaned up %d completed inbound jobs", affected)
+	}
+
+	return nil
+}
+
+func (db *DB) inboundJobStats() (map[string]int, error) {
+	rows, err := db.conn.Query(`SELECT status, COUNT(*) FROM inbound_jobs GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count inbound jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{"pending": 0, "processing": 0, "done": 0, "failed": 0}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan inbound job count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// SaveMedia stores the media file of an inbound message, replacing one
+// stored for a previous delivery of the message.
+func (db *DB) SaveMedia(media models.StoredMedia) error {
+	if media.WAMID == "" || media.UserID == "" || len(media.Data) == 0 {
+		return fmt.Errorf("wamid, userID and data are required")
+	}
+
+	query := `
+		INSERT OR REPLACE INTO message_media (wamid, user_id, media_id, mime_type, sha256, size, data)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`
+	_, err := db.conn.Exec(query, media.WAMID, media.UserID, media.MediaID, media.MimeType, media.SHA256,
+		len(media.Data), media.Data)
+	if err != nil {
+		return fmt.Errorf("failed to save media: %w", err)
+	}
+
+	return nil
+}
+
+// GetMedia returns the media file of the inbound message wamid, or nil if
+// none was stored.
+func (db *DB) GetMedia(wamid string) (*models.StoredMedia, error) {
+	query := `
+		SELECT wamid, user_id, media_id, mime_type, sha256, data, created_at
+		FROM message_media WHERE wamid = ?
+	`
+	var media models.StoredMedia
+	var sha256 sql.NullString
+	err := db.conn.QueryRow(query, wamid).Scan(&media.WAMID, &media.UserID, &media.MediaID, &media.MimeType,
+		&sha256, &media.Data, &media.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get media: %w", err)
+	}
+
+	media.SHA256 = sha256.String
+	return &media, nil
+}
+
+// SetMessageDescription stores the model's description of the image carried
+// by the inbound message wamid.
+func (db *DB) SetMessageDescription(wamid, description string) error {
+	result, err := db.conn.Exec(`UPDATE messages SET description = ? WHERE wamid = ?`, description, wamid)
+	if err != nil {
+		return fmt.Errorf("failed to save description: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("message %s not found", wamid)
+	}
+	return nil
+}
+
+// CleanupMedia deletes media files stored more than maxAge ago; their
+// descriptions stay in the history.
+func (db *DB) CleanupMedia(maxAge time.Duration) error {
+	query := `DELETE FROM message_media WHERE created_at < datetime('now', ?)`
+
+	offset := fmt.Sprintf("-%d seconds", int(maxAge.Seconds()))
+	result, err := db.conn.Exec(query, offset)
+	if err != nil {
+		return fmt.Errorf("failed to cleanup media: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected > 0 {
+		log.Printf("Cleaned up %d stored media files", affected)
+	}
+
+	return nil
+}
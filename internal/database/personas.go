@@ -0,0 +1,221 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// DefaultPersona is the name of the persona used for users and numbers
+// without an assignment, if it exists.
+const DefaultPersona = "default"
+
+var (
+	ErrPersonaNotFound = errors.New("persona not found")
+	ErrPersonaExists   = errors.New("persona already exists")
+)
+
+const personaColumns = `id, name, system_prompt, temperature, max_tokens, model, created_at, updated_at`
+
+// CreatePersona stores a new persona and sets its ID.
+func (db *DB) CreatePersona(persona *models.Persona) error {
+	if err := validatePersona(persona); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO personas (name, system_prompt, temperature, max_tokens, model)
+		VALUES (?, ?, ?, NULLIF(?, 0), NULLIF(?, ''))
+	`
+	result, err := db.conn.Exec(query, persona.Name, persona.SystemPrompt, persona.Temperature, persona.MaxTokens, persona.Model)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("%w: %s", ErrPersonaExists, persona.Name)
+		}
+		return fmt.Errorf("failed to save persona: %w", err)
+	}
+
+	persona.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get persona ID: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePersona replaces the settings of the persona with the given name.
+func (db *DB) UpdatePersona(persona models.Persona) error {
+	if err := validatePersona(&persona); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE personas
+		SET system_prompt = ?, temperature = ?, max_tokens = NULLIF(?, 0), model = NULLIF(?, ''),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?
+	`
+	result, err := db.conn.Exec(query, persona.SystemPrompt, persona.Temperature, persona.MaxTokens, persona.Model, persona.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update persona: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrPersonaNotFound, persona.Name)
+	}
+
+	return nil
+}
+
+// GetPersona returns the persona with the given name.
+func (db *DB) GetPersona(name string) (*models.Persona, error) {
+	row := db.conn.QueryRow(`SELECT `+personaColumns+` FROM personas WHERE name = ?`, name)
+
+	persona, err := scanPersona(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrPersonaNotFound, name)
+	}
+	return persona, err
+}
+
+// ListPersonas returns all personas ordered by name.
+func (db *DB) ListPersonas() ([]models.Persona, error) {
+	rows, err := db.conn.Query(`SELECT ` + personaColumns + ` FROM personas ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personas: %w", err)
+	}
+	defer rows.Close()
+
+	var personas []models.Persona
+	for rows.Next() {
+		persona, err := scanPersona(rows)
+		if err != nil {
+			return nil, err
+		}
+		personas = append(personas, *persona)
+	}
+
+	return personas, rows.Err()
+}
+
+// AssignUserPersona makes userID use the named persona; an empty name removes
+// the assignment.
+func (db *DB) AssignUserPersona(userID, name string) error {
+	if userID == "" {
+		return fmt.Errorf("userID is required")
+	}
+
+	var personaID sql.NullInt64
+	if name != "" {
+		persona, err := db.GetPersona(name)
+		if err != nil {
+			return err
+		}
+		personaID = sql.NullInt64{Int64: persona.ID, Valid: true}
+	}
+
+	query := `
+		INSERT INTO users (user_id, persona_id) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET persona_id = excluded.persona_id
+	`
+	if _, err := db.conn.Exec(query, userID, personaID); err != nil {
+		return fmt.Errorf("failed to assign persona: %w", err)
+	}
+
+	return nil
+}
+
+// AssignTenantPersona makes messages to the business number phoneNumberID
+// use the named persona; an empty name removes the assignment.
+func (db *DB) AssignTenantPersona(phoneNumberID, name string) error {
+	if phoneNumberID == "" {
+		return fmt.Errorf("phoneNumberID is required")
+	}
+
+	if name == "" {
+		if _, err := db.conn.Exec(`DELETE FROM tenant_personas WHERE phone_number_id = ?`, phoneNumberID); err != nil {
+			return fmt.Errorf("failed to remove persona assignment: %w", err)
+		}
+		return nil
+	}
+
+	persona, err := db.GetPersona(name)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tenant_personas (phone_number_id, persona_id) VALUES (?, ?)
+		ON CONFLICT(phone_number_id) DO UPDATE SET persona_id = excluded.persona_id, updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := db.conn.Exec(query, phoneNumberID, persona.ID); err != nil {
+		return fmt.Errorf("failed to assign persona: %w", err)
+	}
+
+	return nil
+}
+
+// ResolvePersona returns the persona for a conversation with userID on the
+// business number phoneNumberID (which may be empty): the user's own
+// assignment, else the number's, else the default persona. It returns nil if
+// none applies.
+func (db *DB) ResolvePersona(userID, phoneNumberID string) (*models.Persona, error) {
+	query := `
+		SELECT ` + personaColumns + ` FROM personas
+		WHERE id = COALESCE(
+			(SELECT persona_id FROM users WHERE user_id = ?),
+			(SELECT persona_id FROM tenant_personas WHERE phone_number_id = ?),
+			(SELECT id FROM personas WHERE name = ?)
+		)
+	`
+	persona, err := scanPersona(db.conn.QueryRow(query, userID, phoneNumberID, DefaultPersona))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return persona, err
+}
+
+func validatePersona(persona *models.Persona) error {
+	persona.Name = strings.TrimSpace(persona.Name)
+	if persona.Name == "" || persona.SystemPrompt == "" {
+		return fmt.Errorf("persona name and system prompt are required")
+	}
+	if t := persona.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *t)
+	}
+	if persona.MaxTokens < 0 {
+		return fmt.Errorf("max tokens must not be negative, got %d", persona.MaxTokens)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPersona(row rowScanner) (*models.Persona, error) {
+	var persona models.Persona
+	var temperature sql.NullFloat64
+	var maxTokens sql.NullInt64
+	var model sql.NullString
+
+	err := row.Scan(&persona.ID, &persona.Name, &persona.SystemPrompt, &temperature, &maxTokens, &model,
+		&persona.CreatedAt, &persona.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan persona: %w", err)
+	}
+
+	if temperature.Valid {
+		persona.Temperature = &temperature.Float64
+	}
+	persona.MaxTokens = int(maxTokens.Int64)
+	persona.Model = model.String
+
+	return &persona, nil
+}
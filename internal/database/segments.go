@@ -0,0 +1,63 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetUserTier puts userID in a tier such as "free" or "premium"; an empty
+// tier removes them from theirs.
+func (db *DB) SetUserTier(userID, tier string) error {
+	if userID == "" {
+		return fmt.Errorf("userID is required")
+	}
+
+	query := `
+		INSERT INTO users (user_id, tier) VALUES (?, NULLIF(?, ''))
+		ON CONFLICT(user_id) DO UPDATE SET tier = excluded.tier
+	`
+	if _, err := db.conn.Exec(query, userID, strings.ToLower(strings.TrimSpace(tier))); err != nil {
+		return fmt.Errorf("failed to set tier: %w", err)
+	}
+
+	return nil
+}
+
+// SetUserTags replaces the tags of userID; they are stored lowercased,
+// without duplicates.
+func (db *DB) SetUserTags(userID string, tags []string) error {
+	if userID == "" {
+		return fmt.Errorf("userID is required")
+	}
+
+	var kept []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if strings.Contains(tag, ",") {
+			return fmt.Errorf("tag %q must not contain a comma", tag)
+		}
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			kept = append(kept, tag)
+		}
+	}
+
+	query := `
+		INSERT INTO users (user_id, tags) VALUES (?, NULLIF(?, ''))
+		ON CONFLICT(user_id) DO UPDATE SET tags = excluded.tags
+	`
+	if _, err := db.conn.Exec(query, userID, strings.Join(kept, ",")); err != nil {
+		return fmt.Errorf("failed to set tags: %w", err)
+	}
+
+	return nil
+}
+
+// splitTags parses the tags column.
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
@@ -2,8 +2,11 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
@@ -14,6 +17,9 @@ type DB struct {
 	conn *sql.DB
 }
 
+// ErrDuplicateMessage is returned when an inbound WhatsApp message was already stored.
+var ErrDuplicateMessage = errors.New("duplicate message")
+
 func InitDB(dbPath string) (*DB, error) {
 	log.Printf("Initializing database at: %s", dbPath)
 	
@@ -27,6 +33,10 @@ func InitDB(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	// SQLite allows a single writer; sharing one connection also keeps
+	// ":memory:" databases from splitting across pool connections.
+	conn.SetMaxOpenConns(1)
+
 	db := &DB{conn: conn}
 	if err := db.createTables(); err != nil {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
@@ -43,20 +53,34 @@ func (db *DB) Close() error {
 	return nil
 }
 
-func (db *DB) createTables() error {
-	createMessagesTable := `
-	CREATE TABLE IF NOT EXISTS messages (
+// messagesColumns defines the messages table, shared by createTables and the
+// migration that rebuilds tables created by older versions.
+const messagesColumns = `
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		user_id TEXT NOT NULL,
 		content TEXT NOT NULL,
-		role TEXT NOT NULL CHECK(role IN ('user', 'assistant')),
+		role TEXT NOT NULL CHECK(role IN ('user', 'assistant', 'tool')),
+		wamid TEXT,
+		reply_to TEXT,
+		delivery_status TEXT,
+		delivery_error_code INTEGER,
+		delivery_error TEXT,
+		selection_id TEXT,
+		tool_calls TEXT,
+		tool_call_id TEXT,
+		description TEXT,
+		from_audio INTEGER NOT NULL DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	
+`
+
+const messagesIndexes = `
 	CREATE INDEX IF NOT EXISTS idx_messages_user_id ON messages(user_id);
 	CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at);
 	CREATE INDEX IF NOT EXISTS idx_messages_user_time ON messages(user_id, created_at);
-	`
+`
+
+func (db *DB) createTables() error {
+	createMessagesTable := `CREATE TABLE IF NOT EXISTS messages (` + messagesColumns + `);` + messagesIndexes
 
 	createUsersTable := `
 	CREATE TABLE IF NOT EXISTS users (
@@ -65,7 +89,8 @@ func (db *DB) createTables() error {
 		phone_number TEXT,
 		name TEXT,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		last_seen DATETIME DEFAULT CURRENT_TIMESTAMP
+		last_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_inbound_at DATETIME
 	);
 	
 	CREATE INDEX IF NOT EXISTS idx_users_user_id ON users(user_id);
@@ -86,7 +111,91 @@ func (db *DB) createTables() error {
 	CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
 	`
 
-	tables := []string{createMessagesTable, createUsersTable, createSessionsTable}
+	createInboundJobsTable := `
+	CREATE TABLE IF NOT EXISTS inbound_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		payload BLOB NOT NULL,
+		user_id TEXT,
+		status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'processing', 'done', 'failed')),
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_inbound_jobs_due ON inbound_jobs(status, next_attempt_at);
+	`
+
+	createMessageStatusesTable := `
+	CREATE TABLE IF NOT EXISTS message_statuses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		wamid TEXT NOT NULL,
+		recipient_id TEXT,
+		status TEXT NOT NULL,
+		error_code INTEGER,
+		error_title TEXT,
+		error_details TEXT,
+		status_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(wamid, status)
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_message_statuses_wamid ON message_statuses(wamid);
+	`
+
+	createPersonasTable := `
+	CREATE TABLE IF NOT EXISTS personas (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE NOT NULL,
+		system_prompt TEXT NOT NULL,
+		temperature REAL,
+		max_tokens INTEGER,
+		model TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS tenant_personas (
+		phone_number_id TEXT PRIMARY KEY,
+		persona_id INTEGER NOT NULL REFERENCES personas(id),
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	createMessageUsageTable := `CREATE TABLE IF NOT EXISTS message_usage (` + messageUsageColumns + `);` + messageUsageIndexes
+
+	createConversationSummariesTable := `
+	CREATE TABLE IF NOT EXISTS conversation_summaries (
+		user_id TEXT PRIMARY KEY,
+		content TEXT NOT NULL,
+		through_message_id INTEGER NOT NULL,
+		message_count INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	createMessageMediaTable := `
+	CREATE TABLE IF NOT EXISTS message_media (
+		wamid TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		media_id TEXT NOT NULL,
+		mime_type TEXT NOT NULL,
+		sha256 TEXT,
+		size INTEGER NOT NULL,
+		data BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_message_media_created_at ON message_media(created_at);
+	`
+
+	tables := []string{
+		createMessagesTable, createUsersTable, createSessionsTable,
+		createInboundJobsTable, createMessageStatusesTable, createPersonasTable,
+		createMessageUsageTable, createConversationSummariesTable, createMessageMediaTable,
+	}
 	
 	for _, table := range tables {
 		if _, err := db.conn.Exec(table); err != nil {
@@ -94,6 +203,128 @@ func (db *DB) createTables() error {
 		}
 	}
 
+	return db.migrate()
+}
+
+// migrate brings databases created by older versions up to the current schema.
+// Indexes on added columns live here so they are created after the column exists.
+func (db *DB) migrate() error {
+	columns := []struct{ table, column, definition string }{
+		{"messages", "wamid", "TEXT"},
+		{"messages", "reply_to", "TEXT"},
+		{"messages", "delivery_status", "TEXT"},
+		{"messages", "delivery_error_code", "INTEGER"},
+		{"messages", "delivery_error", "TEXT"},
+		{"messages", "selection_id", "TEXT"},
+		{"messages", "tool_calls", "TEXT"},
+		{"messages", "tool_call_id", "TEXT"},
+		{"messages", "description", "TEXT"},
+		{"messages", "from_audio", "INTEGER NOT NULL DEFAULT 0"},
+		{"inbound_jobs", "user_id", "TEXT"},
+		{"message_usage", "estimated", "INTEGER NOT NULL DEFAULT 0"},
+		{"users", "last_inbound_at", "DATETIME"},
+		{"users", "persona_id", "INTEGER REFERENCES personas(id)"},
+		{"users", "tier", "TEXT"},
+		{"users", "tags", "TEXT"},
+	}
+
+	for _, c := range columns {
+		if err := db.addColumnIfMissing(c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	if err := db.allowToolMessages(); err != nil {
+		return err
+	}
+	if err := db.allowUsageWithoutMessage(); err != nil {
+		return err
+	}
+
+	indexes := `
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_wamid ON messages(wamid) WHERE wamid IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_messages_reply_to ON messages(reply_to) WHERE reply_to IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS idx_inbound_jobs_user ON inbound_jobs(user_id, status) WHERE user_id IS NOT NULL;
+	`
+	if _, err := db.conn.Exec(indexes); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	return nil
+}
+
+// allowToolMessages rebuilds a messages table whose role constraint predates
+// tool-call turns; SQLite cannot change a CHECK constraint in place.
+func (db *DB) allowToolMessages() error {
+	var schema string
+	err := db.conn.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'messages'`).Scan(&schema)
+	if err != nil {
+		return fmt.Errorf("failed to inspect table messages: %w", err)
+	}
+	if strings.Contains(schema, "'tool'") {
+		return nil
+	}
+
+	columns := `id, user_id, content, role, wamid, reply_to, delivery_status, delivery_error_code,
+		delivery_error, selection_id, tool_calls, tool_call_id, description, from_audio, created_at`
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`CREATE TABLE messages_rebuild (` + messagesColumns + `)`,
+		`INSERT INTO messages_rebuild (` + columns + `) SELECT ` + columns + ` FROM messages`,
+		`DROP TABLE messages`,
+		`ALTER TABLE messages_rebuild RENAME TO messages`,
+		messagesIndexes,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to rebuild table messages: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to rebuild table messages: %w", err)
+	}
+
+	log.Printf("Rebuilt table messages to store tool calls")
+	return nil
+}
+
+func (db *DB) addColumnIfMissing(table, column, definition string) error {
+	rows, err := db.conn.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+
+	exists := false
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == column {
+			exists = true
+		}
+	}
+	rows.Close()
+
+	if exists {
+		return nil
+	}
+
+	if _, err := db.conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+
+	log.Printf("Added column %s.%s", table, column)
 	return nil
 }
 
@@ -116,6 +347,110 @@ func (db *DB) SaveMessage(userID, content, role string) error {
 	return nil
 }
 
+// SaveToolTurn stores a step of a tool-calling exchange: an assistant message
+// with msg.ToolCalls, or a "tool" message with the result of call msg.ToolCallID.
+func (db *DB) SaveToolTurn(msg models.Message) error {
+	if msg.UserID == "" {
+		return fmt.Errorf("userID is required")
+	}
+
+	var toolCalls sql.NullString
+	switch {
+	case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+		data, err := json.Marshal(msg.ToolCalls)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tool calls: %w", err)
+		}
+		toolCalls = sql.NullString{String: string(data), Valid: true}
+	case msg.Role == "tool" && msg.ToolCallID != "":
+	default:
+		return fmt.Errorf("a tool turn is an assistant message with tool calls or a tool message with a call ID")
+	}
+
+	query := `INSERT INTO messages (user_id, content, role, tool_calls, tool_call_id) VALUES (?, ?, ?, ?, NULLIF(?, ''))`
+	if _, err := db.conn.Exec(query, msg.UserID, msg.Content, msg.Role, toolCalls, msg.ToolCallID); err != nil {
+		return fmt.Errorf("failed to save tool turn: %w", err)
+	}
+
+	return nil
+}
+
+// SaveInboundMessage stores a user message received from WhatsApp, keyed on
+// msg.WAMID. It returns ErrDuplicateMessage if a message with that wamid is already stored.
+func (db *DB) SaveInboundMessage(msg models.Message) error {
+	if msg.UserID == "" || msg.Content == "" || msg.WAMID == "" {
+		return fmt.Errorf("userID, content, and wamid are required")
+	}
+
+	query := `
+		INSERT INTO messages (user_id, content, role, wamid, selection_id, from_audio)
+		VALUES (?, ?, 'user', ?, NULLIF(?, ''), ?)
+		ON CONFLICT DO NOTHING
+	`
+	result, err := db.conn.Exec(query, msg.UserID, msg.Content, msg.WAMID, msg.SelectionID, msg.FromAudio)
+	if err != nil {
+		return fmt.Errorf("failed to save message: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrDuplicateMessage
+	}
+
+	log.Printf("Saved user message %s for user %s", msg.WAMID, msg.UserID)
+	return nil
+}
+
+// SaveReply stores an assistant message answering the inbound message replyTo,
+// with what generating it consumed if usage is not nil. wamid is the ID
+// WhatsApp assigned to the sent reply, used to match status events.
+func (db *DB) SaveReply(userID, content, replyTo, wamid string, usage *models.MessageUsage) error {
+	if userID == "" || content == "" || replyTo == "" {
+		return fmt.Errorf("userID, content, and replyTo are required")
+	}
+
+	query := `INSERT INTO messages (user_id, content, role, reply_to, wamid) VALUES (?, ?, 'assistant', ?, NULLIF(?, ''))`
+	if err := db.saveWithUsage(userID, usage, query, userID, content, replyTo, wamid); err != nil {
+		return fmt.Errorf("failed to save reply: %w", err)
+	}
+	if wamid != "" {
+		if err := db.applyRecordedStatus(wamid); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Saved assistant reply to %s for user %s", replyTo, userID)
+	return nil
+}
+
+// SaveOutboundMessage stores an assistant message that was sent to WhatsApp on
+// request rather than in reply to an inbound message.
+func (db *DB) SaveOutboundMessage(userID, content, wamid string) error {
+	if userID == "" || content == "" || wamid == "" {
+		return fmt.Errorf("userID, content, and wamid are required")
+	}
+
+	query := `INSERT INTO messages (user_id, content, role, wamid) VALUES (?, ?, 'assistant', ?)`
+	if _, err := db.conn.Exec(query, userID, content, wamid); err != nil {
+		return fmt.Errorf("failed to save outbound message: %w", err)
+	}
+	if err := db.applyRecordedStatus(wamid); err != nil {
+		return err
+	}
+
+	log.Printf("Saved outbound message %s for user %s", wamid, userID)
+	return nil
+}
+
+// IsAnswered reports whether an assistant reply to the inbound message wamid is stored.
+func (db *DB) IsAnswered(wamid string) (bool, error) {
+	var exists bool
+	err := db.conn.QueryRow(`SELECT EXISTS(SELECT 1 FROM messages WHERE reply_to = ?)`, wamid).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check reply: %w", err)
+	}
+	return exists, nil
+}
+
 func (db *DB) GetChatHistory(userID string, limit int) ([]models.Message, error) {
 	if userID == "" {
 		return nil, fmt.Errorf("userID is required")
@@ -126,10 +461,10 @@ func (db *DB) GetChatHistory(userID string, limit int) ([]models.Message, error)
 	}
 
 	query := `
-		SELECT id, user_id, content, role, created_at 
+		SELECT ` + messageColumns + `
 		FROM messages 
 		WHERE user_id = ? 
-		ORDER BY created_at DESC 
+		ORDER BY created_at DESC, id DESC
 		LIMIT ?
 	`
 
@@ -137,27 +472,77 @@ func (db *DB) GetChatHistory(userID string, limit int) ([]models.Message, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query messages: %w", err)
 	}
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reverse to get chronological order (oldest first)
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+// GetMessagesAfter returns up to limit of userID's messages with IDs above
+// afterID, oldest first.
+func (db *DB) GetMessagesAfter(userID string, afterID int64, limit int) ([]models.Message, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("userID is required")
+	}
+
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE user_id = ? AND id > ?
+		ORDER BY id
+		LIMIT ?
+	`
+	rows, err := db.conn.Query(query, userID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+
+	return scanMessages(rows)
+}
+
+const messageColumns = `id, user_id, content, role, wamid, selection_id, tool_calls, tool_call_id,
+			description, from_audio, delivery_status, delivery_error_code, delivery_error, created_at`
+
+// scanMessages reads and closes rows selecting messageColumns.
+func scanMessages(rows *sql.Rows) ([]models.Message, error) {
 	defer rows.Close()
 
 	var messages []models.Message
 	for rows.Next() {
 		var msg models.Message
-		err := rows.Scan(&msg.ID, &msg.UserID, &msg.Content, &msg.Role, &msg.CreatedAt)
+		var wamid, selectionID, toolCalls, toolCallID, description, deliveryStatus, deliveryError sql.NullString
+		var deliveryErrorCode sql.NullInt64
+		err := rows.Scan(&msg.ID, &msg.UserID, &msg.Content, &msg.Role, &wamid, &selectionID, &toolCalls, &toolCallID,
+			&description, &msg.FromAudio, &deliveryStatus, &deliveryErrorCode, &deliveryError, &msg.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
+		if toolCalls.Valid {
+			if err := json.Unmarshal([]byte(toolCalls.String), &msg.ToolCalls); err != nil {
+				return nil, fmt.Errorf("failed to decode tool calls of message %d: %w", msg.ID, err)
+			}
+		}
+		msg.WAMID = wamid.String
+		msg.SelectionID = selectionID.String
+		msg.ToolCallID = toolCallID.String
+		msg.Description = description.String
+		msg.DeliveryStatus = deliveryStatus.String
+		msg.DeliveryErrorCode = int(deliveryErrorCode.Int64)
+		msg.DeliveryError = deliveryError.String
 		messages = append(messages, msg)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
-
-	// Reverse to get chronological order (oldest first)
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
-	}
-
 	return messages, nil
 }
 
@@ -198,18 +583,62 @@ func (db *DB) CreateOrUpdateUser(userID, phoneNumber, name string) error {
 	return nil
 }
 
+// RecordInboundAt notes that userID messaged us at the given time, which opens
+// or extends their customer service window. Older timestamps are ignored so
+// late webhook deliveries cannot shorten the window.
+func (db *DB) RecordInboundAt(userID string, at time.Time) error {
+	if userID == "" {
+		return fmt.Errorf("userID is required")
+	}
+
+	query := `
+		INSERT INTO users (user_id, last_inbound_at, last_seen)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			last_inbound_at = CASE
+				WHEN last_inbound_at IS NULL OR last_inbound_at < excluded.last_inbound_at THEN excluded.last_inbound_at
+				ELSE last_inbound_at
+			END,
+			last_seen = CURRENT_TIMESTAMP
+	`
+
+	if _, err := db.conn.Exec(query, userID, at.UTC()); err != nil {
+		return fmt.Errorf("failed to record inbound time: %w", err)
+	}
+
+	return nil
+}
+
+// GetLastInboundAt returns when userID last messaged us, or the zero time if they never have.
+func (db *DB) GetLastInboundAt(userID string) (time.Time, error) {
+	if userID == "" {
+		return time.Time{}, fmt.Errorf("userID is required")
+	}
+
+	var lastInbound sql.NullTime
+	err := db.conn.QueryRow(`SELECT last_inbound_at FROM users WHERE user_id = ?`, userID).Scan(&lastInbound)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil // Unknown user
+		}
+		return time.Time{}, fmt.Errorf("failed to get last inbound time: %w", err)
+	}
+
+	return lastInbound.Time, nil
+}
+
 func (db *DB) GetUser(userID string) (*models.User, error) {
 	if userID == "" {
 		return nil, fmt.Errorf("userID is required")
 	}
 
-	query := `SELECT user_id, phone_number, name, created_at, last_seen FROM users WHERE user_id = ?`
+	query := `SELECT user_id, phone_number, name, created_at, last_seen, tier, tags FROM users WHERE user_id = ?`
 	
 	var user models.User
-	var phoneNumber, name sql.NullString
+	var phoneNumber, name, tier, tags sql.NullString
 	
 	err := db.conn.QueryRow(query, userID).Scan(
-		&user.UserID, &phoneNumber, &name, &user.CreatedAt, &user.LastSeen,
+		&user.UserID, &phoneNumber, &name, &user.CreatedAt, &user.LastSeen, &tier, &tags,
 	)
 	
 	if err != nil {
@@ -225,6 +654,7 @@ func (db *DB) GetUser(userID string) (*models.User, error) {
 	if name.Valid {
 		user.Name = name.String
 	}
+	user.Tier, user.Tags = tier.String, splitTags(tags.String)
 
 	return &user, nil
 }
@@ -235,7 +665,7 @@ func (db *DB) GetRecentUsers(limit int) ([]models.User, error) {
 	}
 
 	query := `
-		SELECT user_id, phone_number, name, created_at, last_seen 
+		SELECT user_id, phone_number, name, created_at, last_seen, tier, tags
 		FROM users 
 		ORDER BY last_seen DESC 
 		LIMIT ?
@@ -250,9 +680,9 @@ func (db *DB) GetRecentUsers(limit int) ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		var phoneNumber, name sql.NullString
+		var phoneNumber, name, tier, tags sql.NullString
 		
-		err := rows.Scan(&user.UserID, &phoneNumber, &name, &user.CreatedAt, &user.LastSeen)
+		err := rows.Scan(&user.UserID, &phoneNumber, &name, &user.CreatedAt, &user.LastSeen, &tier, &tags)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -263,6 +693,7 @@ func (db *DB) GetRecentUsers(limit int) ([]models.User, error) {
 		if name.Valid {
 			user.Name = name.String
 		}
+		user.Tier, user.Tags = tier.String, splitTags(tags.String)
 
 		users = append(users, user)
 	}
@@ -369,5 +800,33 @@ func (db *DB) GetStats() (map[string]interface{}, error) {
 	}
 	stats["messages_today"] = messagesToday
 
+	// Inbound webhook queue
+	jobStats, err := db.inboundJobStats()
+	if err != nil {
+		return nil, err
+	}
+	stats["inbound_jobs"] = jobStats
+
+	// Outbound delivery
+	deliveryStats, err := db.deliveryStats()
+	if err != nil {
+		return nil, err
+	}
+	stats["delivery"] = deliveryStats
+
+	// Language model usage and cost
+	usageStats, err := db.usageStats()
+	if err != nil {
+		return nil, err
+	}
+	stats["usage"] = usageStats
+
+	// Conversation summaries
+	summaryStats, err := db.summaryStats()
+	if err != nil {
+		return nil, err
+	}
+	stats["summaries"] = summaryStats
+
 	return stats, nil
 }
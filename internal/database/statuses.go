@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// statusRank orders delivery states so late or redelivered events never move
+// a message backwards, e.g. a "delivered" arriving after "read".
+var statusRank = map[string]int{
+	"sent":      1,
+	"delivered": 2,
+	"read":      3,
+	"failed":    4,
+}
+
+// RecordMessageStatus stores a delivery status event and updates the delivery
+// state of the matching outbound message. Repeated events are ignored.
+func (db *DB) RecordMessageStatus(status models.MessageStatus) error {
+	if status.WAMID == "" || status.Status == "" {
+		return fmt.Errorf("wamid and status are required")
+	}
+
+	insert := `
+		INSERT INTO message_statuses (wamid, recipient_id, status, error_code, error_title, error_details, status_at)
+		VALUES (?, ?, ?, NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''), ?)
+		ON CONFLICT(wamid, status) DO NOTHING
+	`
+	_, err := db.conn.Exec(insert, status.WAMID, status.RecipientID, status.Status,
+		status.ErrorCode, status.ErrorTitle, status.ErrorDetails, status.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to save message status: %w", err)
+	}
+
+	update := `
+		UPDATE messages
+		SET delivery_status = ?, delivery_error_code = NULLIF(?, 0), delivery_error = NULLIF(?, '')
+		WHERE wamid = ? AND COALESCE(CASE delivery_status
+			WHEN 'sent' THEN 1 WHEN 'delivered' THEN 2 WHEN 'read' THEN 3 WHEN 'failed' THEN 4
+		END, 0) < ?
+	`
+	reason := status.ErrorTitle
+	if status.ErrorDetails != "" {
+		reason = status.ErrorTitle + ": " + status.ErrorDetails
+	}
+	_, err = db.conn.Exec(update, status.Status, status.ErrorCode, reason, status.WAMID, statusRank[status.Status])
+	if err != nil {
+		return fmt.Errorf("failed to update delivery status: %w", err)
+	}
+
+	return nil
+}
+
+// applyRecordedStatus sets the delivery state of the message wamid from the
+// highest-ranked status already recorded for it. Status webhooks can be
+// handled before the message they describe is stored, in which case
+// RecordMessageStatus finds no message to update.
+func (db *DB) applyRecordedStatus(wamid string) error {
+	query := `
+		UPDATE messages
+		SET (delivery_status, delivery_error_code, delivery_error) = (
+			SELECT status, error_code, CASE
+				WHEN error_details IS NULL THEN error_title
+				ELSE COALESCE(error_title, '') || ': ' || error_details
+			END
+			FROM message_statuses
+			WHERE wamid = messages.wamid
+			ORDER BY CASE status WHEN 'sent' THEN 1 WHEN 'delivered' THEN 2 WHEN 'read' THEN 3 WHEN 'failed' THEN 4 ELSE 0 END DESC
+			LIMIT 1
+		)
+		WHERE wamid = ? AND EXISTS (SELECT 1 FROM message_statuses WHERE wamid = ?)
+	`
+	if _, err := db.conn.Exec(query, wamid, wamid); err != nil {
+		return fmt.Errorf("failed to apply recorded delivery status: %w", err)
+	}
+	return nil
+}
+
+// GetMessageStatuses returns the delivery status events for one outbound message, oldest first.
+func (db *DB) GetMessageStatuses(wamid string) ([]models.MessageStatus, error) {
+	if wamid == "" {
+		return nil, fmt.Errorf("wamid is required")
+	}
+
+	query := `
+		SELECT wamid, recipient_id, status, error_code, error_title, error_details, status_at
+		FROM message_statuses
+		WHERE wamid = ?
+		ORDER BY status_at, id
+	`
+
+	rows, err := db.conn.Query(query, wamid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message statuses: %w", err)
+	}
+	defer rows.Close()
+
+	var statuses []models.MessageStatus
+	for rows.Next() {
+		var status models.MessageStatus
+		var recipientID, errorTitle, errorDetails sql.NullString
+		var errorCode sql.NullInt64
+		var statusAt sql.NullTime
+
+		err := rows.Scan(&status.WAMID, &recipientID, &status.Status,
+			&errorCode, &errorTitle, &errorDetails, &statusAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message status: %w", err)
+		}
+
+		status.RecipientID = recipientID.String
+		status.ErrorCode = int(errorCode.Int64)
+		status.ErrorTitle = errorTitle.String
+		status.ErrorDetails = errorDetails.String
+		status.Timestamp = statusAt.Time
+		statuses = append(statuses, status)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return statuses, nil
+}
+
+func (db *DB) deliveryStats() (map[string]interface{}, error) {
+	rows, err := db.conn.Query(`
+		SELECT delivery_status, COUNT(*) FROM messages
+		WHERE delivery_status IS NOT NULL
+		GROUP BY delivery_status
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count delivery statuses: %w", err)
+	}
+
+	byStatus := map[string]int{"sent": 0, "delivered": 0, "read": 0, "failed": 0}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan delivery status count: %w", err)
+		}
+		byStatus[status] = count
+	}
+	rows.Close()
+
+	rows, err = db.conn.Query(`
+		SELECT error_code, COALESCE(MAX(error_title), ''), COUNT(*) FROM message_statuses
+		WHERE status = 'failed'
+		GROUP BY error_code
+		ORDER BY COUNT(*) DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count delivery failures: %w", err)
+	}
+	defer rows.Close()
+
+	failures := []map[string]interface{}{}
+	for rows.Next() {
+		var code sql.NullInt64
+		var title string
+		var count int
+		if err := rows.Scan(&code, &title, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery failure count: %w", err)
+		}
+		failures = append(failures, map[string]interface{}{
+			"error_code": code.Int64,
+			"title":      title,
+			"count":      count,
+		})
+	}
+
+	return map[string]interface{}{
+		"by_status": byStatus,
+		"failures":  failures,
+	}, rows.Err()
+}
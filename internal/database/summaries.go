@@ -0,0 +1,99 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// GetSummary returns the stored summary of userID's conversation, or nil if
+// there is none yet.
+func (db *DB) GetSummary(userID string) (*models.Summary, error) {
+	query := `
+		SELECT user_id, content, through_message_id, message_count, created_at, updated_at
+		FROM conversation_summaries WHERE user_id = ?
+	`
+	var summary models.Summary
+	err := db.conn.QueryRow(query, userID).Scan(&summary.UserID, &summary.Content, &summary.ThroughMessageID,
+		&summary.MessageCount, &summary.CreatedAt, &summary.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+
+	return &summary, nil
+}
+
+// SaveSummary stores summary as the summary of its user's conversation,
+// replacing the previous one.
+func (db *DB) SaveSummary(summary models.Summary) error {
+	if summary.UserID == "" || summary.Content == "" {
+		return fmt.Errorf("userID and content are required")
+	}
+
+	query := `
+		INSERT INTO conversation_summaries (user_id, content, through_message_id, message_count)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			content = excluded.content,
+			through_message_id = excluded.through_message_id,
+			message_count = excluded.message_count,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := db.conn.Exec(query, summary.UserID, summary.Content, summary.ThroughMessageID, summary.MessageCount)
+	if err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+
+	return nil
+}
+
+// GetUsersToSummarize returns up to limit users with more than unsummarized
+// messages newer than their summary, those furthest behind first.
+func (db *DB) GetUsersToSummarize(unsummarized, limit int) ([]string, error) {
+	query := `
+		SELECT m.user_id
+		FROM messages m
+		LEFT JOIN conversation_summaries s ON s.user_id = m.user_id
+		WHERE m.id > COALESCE(s.through_message_id, 0)
+		GROUP BY m.user_id
+		HAVING COUNT(*) > ?
+		ORDER BY COUNT(*) DESC
+		LIMIT ?
+	`
+	rows, err := db.conn.Query(query, unsummarized, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users to summarize: %w", err)
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, userID)
+	}
+
+	return users, rows.Err()
+}
+
+// summaryStats counts stored summaries for GetStats.
+func (db *DB) summaryStats() (map[string]interface{}, error) {
+	var users, messages int
+	err := db.conn.QueryRow("SELECT COUNT(*), COALESCE(SUM(message_count), 0) FROM conversation_summaries").
+		Scan(&users, &messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count summaries: %w", err)
+	}
+
+	return map[string]interface{}{
+		"users":               users,
+		"summarized_messages": messages,
+	}, nil
+}
@@ -0,0 +1,285 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+// Groupings of usage reports.
+const (
+	UsageByUser  = "user"
+	UsageByDay   = "day"
+	UsageByModel = "model"
+	UsageByKind  = "kind"
+)
+
+// usageGroupings maps each grouping to the column it groups on.
+var usageGroupings = map[string]string{
+	UsageByUser:  "user_id",
+	UsageByDay:   "DATE(created_at)",
+	UsageByModel: "model",
+	UsageByKind:  "kind",
+}
+
+// messageUsageColumns defines the message_usage table. Model calls that do
+// not produce a message, such as summaries, have no message_id.
+const messageUsageColumns = `
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message_id INTEGER UNIQUE REFERENCES messages(id),
+		kind TEXT NOT NULL DEFAULT 'reply',
+		user_id TEXT NOT NULL,
+		model TEXT NOT NULL,
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		completion_tokens INTEGER NOT NULL DEFAULT 0,
+		total_tokens INTEGER NOT NULL DEFAULT 0,
+		latency_ms INTEGER NOT NULL DEFAULT 0,
+		finish_reason TEXT,
+		cost_usd REAL,
+		estimated INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	`
+
+const messageUsageIndexes = `
+	CREATE INDEX IF NOT EXISTS idx_message_usage_user ON message_usage(user_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_message_usage_created_at ON message_usage(created_at);
+	`
+
+// allowUsageWithoutMessage rebuilds a message_usage table keyed by message,
+// from before usage was recorded for calls that produce none.
+func (db *DB) allowUsageWithoutMessage() error {
+	var schema string
+	err := db.conn.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'message_usage'`).Scan(&schema)
+	if err != nil {
+		return fmt.Errorf("failed to inspect table message_usage: %w", err)
+	}
+	if strings.Contains(schema, "kind TEXT") {
+		return nil
+	}
+
+	columns := `message_id, user_id, model, prompt_tokens, completion_tokens, total_tokens,
+		latency_ms, finish_reason, cost_usd, estimated, created_at`
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`CREATE TABLE message_usage_rebuild (` + messageUsageColumns + `)`,
+		`INSERT INTO message_usage_rebuild (` + columns + `) SELECT ` + columns + ` FROM message_usage`,
+		`DROP TABLE message_usage`,
+		`ALTER TABLE message_usage_rebuild RENAME TO message_usage`,
+		messageUsageIndexes,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to rebuild table message_usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to rebuild table message_usage: %w", err)
+	}
+
+	log.Printf("Rebuilt table message_usage to record summary and description usage")
+	return nil
+}
+
+// RecordUsage stores the usage of a model call that produced no assistant
+// message, such as a summary or an image description, under usage.Kind.
+func (db *DB) RecordUsage(usage *models.MessageUsage) error {
+	if usage == nil || usage.Kind == "" {
+		return fmt.Errorf("usage with a kind is required")
+	}
+
+	query := `
+		INSERT INTO message_usage (message_id, kind, user_id, model, prompt_tokens, completion_tokens,
+			total_tokens, latency_ms, finish_reason, cost_usd, estimated)
+		VALUES (NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`
+	_, err := db.conn.Exec(query, usage.MessageID, usage.Kind, usage.UserID, usage.Model, usage.PromptTokens,
+		usage.CompletionTokens, usage.TotalTokens, usage.LatencyMs, usage.FinishReason, usage.CostUSD, usage.Estimated)
+	if err != nil {
+		return fmt.Errorf("failed to save usage: %w", err)
+	}
+	return nil
+}
+
+// SaveAssistantMessage stores an assistant message generated for userID
+// together with what generating it consumed; usage may be nil.
+func (db *DB) SaveAssistantMessage(userID, content string, usage *models.MessageUsage) error {
+	if userID == "" || content == "" {
+		return fmt.Errorf("userID and content are required")
+	}
+
+	return db.saveWithUsage(userID, usage, `INSERT INTO messages (user_id, content, role) VALUES (?, ?, 'assistant')`,
+		userID, content)
+}
+
+// saveWithUsage runs insert, which adds one assistant message for userID,
+// and stores usage for it in the same transaction.
+func (db *DB) saveWithUsage(userID string, usage *models.MessageUsage, insert string, args ...any) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(insert, args...)
+	if err != nil {
+		return fmt.Errorf("failed to save message: %w", err)
+	}
+
+	if usage != nil {
+		messageID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get message ID: %w", err)
+		}
+
+		query := `
+			INSERT INTO message_usage (message_id, user_id, model, prompt_tokens, completion_tokens,
+				total_tokens, latency_ms, finish_reason, cost_usd, estimated)
+			VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+		`
+		_, err = tx.Exec(query, messageID, userID, usage.Model, usage.PromptTokens, usage.CompletionTokens,
+			usage.TotalTokens, usage.LatencyMs, usage.FinishReason, usage.CostUSD, usage.Estimated)
+		if err != nil {
+			return fmt.Errorf("failed to save usage: %w", err)
+		}
+		usage.MessageID, usage.Kind = messageID, models.UsageKindReply
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save message: %w", err)
+	}
+	return nil
+}
+
+// GetMessageUsage returns what generating the assistant message messageID
+// consumed, or nil if nothing was recorded.
+func (db *DB) GetMessageUsage(messageID int64) (*models.MessageUsage, error) {
+	query := `
+		SELECT message_id, kind, user_id, model, prompt_tokens, completion_tokens, total_tokens,
+			latency_ms, finish_reason, cost_usd, estimated, created_at
+		FROM message_usage WHERE message_id = ?
+	`
+	var usage models.MessageUsage
+	var finishReason sql.NullString
+	var cost sql.NullFloat64
+	err := db.conn.QueryRow(query, messageID).Scan(&usage.MessageID, &usage.Kind, &usage.UserID, &usage.Model,
+		&usage.PromptTokens, &usage.CompletionTokens, &usage.TotalTokens, &usage.LatencyMs,
+		&finishReason, &cost, &usage.Estimated, &usage.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	usage.FinishReason = finishReason.String
+	if cost.Valid {
+		usage.CostUSD = &cost.Float64
+	}
+	return &usage, nil
+}
+
+// GetUsage totals the usage of the last days days grouped by user, day,
+// model or kind, highest cost and then most tokens first. userID, if not empty,
+// restricts the report to one user; limit caps the number of rows if positive.
+func (db *DB) GetUsage(groupBy string, days int, userID string, limit int) ([]models.UsageSummary, error) {
+	column, ok := usageGroupings[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown usage grouping %q (want user, day, model or kind)", groupBy)
+	}
+	if days <= 0 {
+		return nil, fmt.Errorf("days must be positive")
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+
+	// Days run newest first; users and models by what they cost
+	order := "6 DESC, 5 DESC, 1"
+	if groupBy == UsageByDay {
+		order = "1 DESC"
+	}
+
+	query := `
+		SELECT ` + column + `, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens), SUM(total_tokens),
+			COALESCE(SUM(cost_usd), 0), COUNT(*) - COUNT(cost_usd), SUM(estimated), CAST(AVG(latency_ms) AS INTEGER)
+		FROM message_usage
+		WHERE created_at >= datetime('now', ?) AND (? = '' OR user_id = ?)
+		GROUP BY 1
+		ORDER BY ` + order + `
+		LIMIT ?
+	`
+
+	rows, err := db.conn.Query(query, fmt.Sprintf("-%d days", days), userID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []models.UsageSummary{}
+	for rows.Next() {
+		var s models.UsageSummary
+		err := rows.Scan(&s.Key, &s.Messages, &s.PromptTokens, &s.CompletionTokens, &s.TotalTokens,
+			&s.CostUSD, &s.UnpricedMessages, &s.EstimatedMessages, &s.AvgLatencyMs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// usageStats summarises the last 30 days of usage for GetStats.
+func (db *DB) usageStats() (map[string]interface{}, error) {
+	byModel, err := db.GetUsage(UsageByModel, 30, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	byDay, err := db.GetUsage(UsageByDay, 7, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	topUsers, err := db.GetUsage(UsageByUser, 30, "", 10)
+	if err != nil {
+		return nil, err
+	}
+	byKind, err := db.GetUsage(UsageByKind, 30, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	total := models.UsageSummary{Key: "last_30_days"}
+	var latency int64
+	for _, s := range byModel {
+		total.Messages += s.Messages
+		total.PromptTokens += s.PromptTokens
+		total.CompletionTokens += s.CompletionTokens
+		total.TotalTokens += s.TotalTokens
+		total.CostUSD += s.CostUSD
+		total.UnpricedMessages += s.UnpricedMessages
+		total.EstimatedMessages += s.EstimatedMessages
+		latency += s.AvgLatencyMs * int64(s.Messages)
+	}
+	if total.Messages > 0 {
+		total.AvgLatencyMs = latency / int64(total.Messages)
+	}
+
+	return map[string]interface{}{
+		"total":     total,
+		"by_model":  byModel,
+		"by_day":    byDay,
+		"top_users": topUsers,
+		"by_kind":   byKind,
+	}, nil
+}
@@ -1,34 +1,57 @@
 package grok
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
-
-	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
 )
 
 type Client struct {
 	APIKey  string
 	BaseURL string
 	Model   string
-	client  *http.Client
+
+	// Retries of rate limited (429) and failed (5xx) calls
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	client *http.Client
+	stats  clientStats
 }
 
 type ChatCompletionRequest struct {
 	Model       string    `json:"model"`
 	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Stream      bool      `json:"stream"`
+
+	// StreamOptions asks a streamed completion for a final usage chunk
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
+	// Tools the model may call, and whether it must ("required"), may
+	// ("auto", the default when Tools is set) or must not ("none")
+	Tools      []Tool `json:"tools,omitempty"`
+	ToolChoice string `json:"tool_choice,omitempty"`
+
+	// ResponseFormat, if set, asks for a JSON reply
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
 }
 
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Parts replace Content with an array mixing text and images when set
+	Parts []ContentPart `json:"-"`
+
+	// ToolCalls are the functions an assistant message asks to run
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the call a "tool" message returns the result of
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 type ChatCompletionResponse struct {
@@ -60,96 +83,51 @@ type ErrorResponse struct {
 	} `json:"error"`
 }
 
+// NewClient creates a Grok API client. Calls are bounded by the context passed
+// to them rather than by a client-wide timeout.
 func NewClient(apiKey, baseURL, model string) *Client {
 	return &Client{
-		APIKey:  apiKey,
-		BaseURL: baseURL,
-		Model:   model,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		APIKey:         apiKey,
+		BaseURL:        baseURL,
+		Model:          model,
+		MaxRetries:     DefaultMaxRetries,
+		RetryBaseDelay: DefaultRetryBaseDelay,
+		RetryMaxDelay:  DefaultRetryMaxDelay,
+		client:         &http.Client{},
 	}
 }
 
-func (c *Client) GenerateResponse(userMessage string, history []models.Message) (string, error) {
-	// Convert history to Grok messages format
-	messages := c.convertHistoryToMessages(history)
-
-	// Add current user message
-	messages = append(messages, Message{
-		Role:    "user",
-		Content: userMessage,
-	})
-
-	// Create request
-	req := ChatCompletionRequest{
-		Model:       c.Model,
-		Messages:    messages,
-		Temperature: 0.7,
-		MaxTokens:   1000,
-		Stream:      false,
+// Complete sends a chat completion request. An empty Model uses the client's model.
+func (c *Client) Complete(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if req.Model == "" {
+		req.Model = c.Model
 	}
+	req.Stream = false
 
 	// Make API call
-	response, err := c.makeAPICall(req)
+	response, err := c.makeAPICall(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("grok API call failed: %w", err)
+		return nil, fmt.Errorf("grok API call failed: %w", err)
 	}
 
 	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned from Grok")
+		return nil, fmt.Errorf("no response choices returned from Grok")
 	}
 
-	return response.Choices[0].Message.Content, nil
+	return response, nil
 }
 
-func (c *Client) convertHistoryToMessages(history []models.Message) []Message {
-	var messages []Message
-
-	// Add system message
-	messages = append(messages, Message{
-		Role:    "system",
-		Content: "You are a helpful AI assistant integrated with WhatsApp. Provide concise, helpful responses to user messages. Keep responses conversational and appropriate for a messaging context.",
-	})
-
-	// Convert last 10 messages for context (to avoid token limits)
-	start := 0
-	if len(history) > 10 {
-		start = len(history) - 10
-	}
-
-	for i := start; i < len(history); i++ {
-		msg := history[i]
-		messages = append(messages, Message{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
-
-	return messages
-}
-
-func (c *Client) makeAPICall(req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+func (c *Client) makeAPICall(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
 	// Marshal request
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequest("POST", c.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
-
-	// Make request
-	resp, err := c.client.Do(httpReq)
+	// Make request, retrying transient failures
+	resp, err := c.post(ctx, "/chat/completions", jsonData, "application/json")
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -159,15 +137,6 @@ func (c *Client) makeAPICall(req ChatCompletionRequest) (*ChatCompletionResponse
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	// Check for HTTP errors
-	if resp.StatusCode != http.StatusOK {
-		var errorResp ErrorResponse
-		if err := json.Unmarshal(body, &errorResp); err != nil {
-			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-		}
-		return nil, fmt.Errorf("API error: %s", errorResp.Error.Message)
-	}
-
 	// Parse response
 	var chatResp ChatCompletionResponse
 	if err := json.Unmarshal(body, &chatResp); err != nil {
@@ -0,0 +1,128 @@
+package grok
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func userRequest(message string) ChatCompletionRequest {
+	return ChatCompletionRequest{Messages: []Message{{Role: "user", Content: message}}}
+}
+
+func TestCompleteRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		maxRetries   int
+		wantAttempts int64
+		wantErr      error
+	}{
+		{"RecoversFromRateLimit", 2, http.StatusTooManyRequests, 2, 3, nil},
+		{"RecoversFromServerError", 1, http.StatusBadGateway, 2, 2, nil},
+		{"GivesUpAfterMaxRetries", 5, http.StatusServiceUnavailable, 2, 3, ErrServer},
+		{"NoRetryOnAuth", 1, http.StatusUnauthorized, 2, 1, ErrAuth},
+		{"NoRetryOnBadRequest", 1, http.StatusBadRequest, 2, 1, ErrBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(calls.Add(1)) <= tt.failures {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.status)
+					w.Write([]byte(`{"error":{"message":"try later","type":"error"}}`))
+					return
+				}
+				w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Hi"}}]}`))
+			}))
+			defer server.Close()
+
+			client := NewClient("key", server.URL, "grok-beta")
+			client.MaxRetries = tt.maxRetries
+			client.RetryBaseDelay = time.Millisecond
+
+			response, err := client.Complete(context.Background(), userRequest("Hello"))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got: %v", tt.wantErr, err)
+				}
+			} else if err != nil || response.Choices[0].Message.Content != "Hi" {
+				t.Fatalf("Expected Hi, got %+v, error %v", response, err)
+			}
+
+			stats := client.Stats()
+			if stats["attempts"] != tt.wantAttempts {
+				t.Errorf("Expected %d attempts, got %v", tt.wantAttempts, stats["attempts"])
+			}
+			if stats["retries"] != tt.wantAttempts-1 {
+				t.Errorf("Expected %d retries, got %v", tt.wantAttempts-1, stats["retries"])
+			}
+		})
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient("key", server.URL, "grok-beta")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Complete(ctx, userRequest("Hello"))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected to give up without waiting for Retry-After, took %s", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"-1", 0},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := NewClient("key", "", "grok-beta")
+
+	for attempt := 0; attempt < 40; attempt++ {
+		delay := client.backoff(attempt, 0)
+		ceiling := min(DefaultRetryBaseDelay<<min(attempt, 30), DefaultRetryMaxDelay)
+		if delay < ceiling/2 || delay > ceiling {
+			t.Fatalf("Attempt %d: delay %s outside [%s, %s]", attempt, delay, ceiling/2, ceiling)
+		}
+	}
+
+	if delay := client.backoff(0, time.Minute); delay != time.Minute {
+		t.Errorf("Expected Retry-After to win, got %s", delay)
+	}
+}
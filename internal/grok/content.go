@@ -0,0 +1,88 @@
+package grok
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// ContentPart is one part of a message whose content mixes text and images.
+type ContentPart struct {
+	Type     string    `json:"type"` // "text" or "image_url"
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL points a content part at an image, by https or data URL.
+type ImageURL struct {
+	URL string `json:"url"`
+	// Detail is "low", "high" or "auto" (the default)
+	Detail string `json:"detail,omitempty"`
+}
+
+// TextPart returns a text content part.
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: "text", Text: text}
+}
+
+// ImagePart returns an image content part for url.
+func ImagePart(url string) ContentPart {
+	return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}}
+}
+
+// DataURL encodes data of the given MIME type as a data URL for ImagePart.
+func DataURL(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// MarshalJSON sends Parts as the content array when set, and Content as a
+// plain string otherwise.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	var content interface{} = m.Content
+	if len(m.Parts) > 0 {
+		content = m.Parts
+	}
+
+	return json.Marshal(struct {
+		message
+		Content interface{} `json:"content"`
+	}{message(m), content})
+}
+
+// UnmarshalJSON accepts content as a string, null or an array of parts; the
+// text of an array is also joined into Content.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	var raw struct {
+		message
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = Message(raw.message)
+	m.Content, m.Parts = "", nil
+
+	content := bytes.TrimSpace(raw.Content)
+	switch {
+	case len(content) == 0 || string(content) == "null":
+		return nil
+	case content[0] == '[':
+		if err := json.Unmarshal(content, &m.Parts); err != nil {
+			return err
+		}
+		var text []string
+		for _, part := range m.Parts {
+			if part.Type == "text" {
+				text = append(text, part.Text)
+			}
+		}
+		m.Content = strings.Join(text, "\n")
+		return nil
+	default:
+		return json.Unmarshal(content, &m.Content)
+	}
+}
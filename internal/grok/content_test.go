@@ -0,0 +1,61 @@
+package grok
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMessageContent(t *testing.T) {
+	t.Run("Marshal", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			message Message
+			want    string
+		}{
+			{"Text", Message{Role: "user", Content: "Hi"}, `{"role":"user","content":"Hi"}`},
+			{"Empty", Message{Role: "assistant"}, `{"role":"assistant","content":""}`},
+			{
+				"Parts",
+				Message{Role: "user", Content: "ignored", Parts: []ContentPart{TextPart("What is this?"), ImagePart(DataURL("image/png", []byte("png")))}},
+				`{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,cG5n"}}]}`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				data, err := json.Marshal(tt.message)
+				if err != nil {
+					t.Fatalf("Failed to marshal: %v", err)
+				}
+				if string(data) != tt.want {
+					t.Errorf("Expected %s, got %s", tt.want, data)
+				}
+			})
+		}
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			data      string
+			content   string
+			wantParts int
+		}{
+			{"String", `{"role":"assistant","content":"Hello"}`, "Hello", 0},
+			{"Null", `{"role":"assistant","content":null,"tool_calls":[]}`, "", 0},
+			{"Parts", `{"role":"assistant","content":[{"type":"text","text":"A"},{"type":"text","text":"B"}]}`, "A\nB", 2},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var message Message
+				if err := json.Unmarshal([]byte(tt.data), &message); err != nil {
+					t.Fatalf("Failed to unmarshal: %v", err)
+				}
+				if message.Role != "assistant" || message.Content != tt.content || len(message.Parts) != tt.wantParts {
+					t.Errorf("Unexpected message: %+v", message)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package grok
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error classes of a failed Grok API call. An *APIError matches the one for
+// its status code with errors.Is; transport timeouts wrap ErrTimeout.
+var (
+	ErrRateLimited = errors.New("rate limited")
+	ErrServer      = errors.New("server error")
+	ErrAuth        = errors.New("authentication failed")
+	ErrBadRequest  = errors.New("bad request")
+	ErrTimeout     = errors.New("request timed out")
+)
+
+// APIError is an error response from the Grok API.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+	// RetryAfter is the delay requested by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+// parseAPIError builds an APIError from a non-200 response.
+func parseAPIError(statusCode int, body []byte, retryAfter string) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		RetryAfter: parseRetryAfter(retryAfter, time.Now()),
+	}
+
+	var errorResp ErrorResponse
+	if err := json.Unmarshal(body, &errorResp); err != nil || errorResp.Error.Message == "" {
+		apiErr.Message = string(body)
+		return apiErr
+	}
+
+	apiErr.Type = errorResp.Error.Type
+	apiErr.Code = errorResp.Error.Code
+	apiErr.Message = errorResp.Error.Message
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("Grok API returned status %d", e.StatusCode)
+	if class := e.class(); class != nil {
+		msg += " (" + class.Error() + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// class returns the error class of the status code.
+func (e *APIError) class() error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusRequestTimeout:
+		return ErrTimeout
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrAuth
+	case e.StatusCode >= 500:
+		return ErrServer
+	case e.StatusCode >= 400:
+		return ErrBadRequest
+	default:
+		return nil
+	}
+}
+
+// Is reports whether target is the error class of the status code.
+func (e *APIError) Is(target error) bool {
+	return target != nil && target == e.class()
+}
+
+// Retryable reports whether the request may succeed if sent again.
+func (e *APIError) Retryable() bool {
+	class := e.class()
+	return class == ErrRateLimited || class == ErrServer
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// isTimeout reports whether a transport error is a timeout.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,40 @@
+package grok
+
+import "encoding/json"
+
+// Response format types accepted in ResponseFormat.Type.
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat constrains the model's reply to plain text, any JSON
+// object, or JSON following a schema.
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema names the schema a "json_schema" reply follows.
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	// Strict asks the API to enforce the schema exactly, which supports
+	// only a subset of JSON Schema
+	Strict bool `json:"strict,omitempty"`
+}
+
+// JSONObjectFormat returns a ResponseFormat asking for any JSON object.
+func JSONObjectFormat() *ResponseFormat {
+	return &ResponseFormat{Type: ResponseFormatJSONObject}
+}
+
+// JSONSchemaFormat returns a ResponseFormat asking for JSON following schema,
+// identified to the model as name.
+func JSONSchemaFormat(name string, schema json.RawMessage) *ResponseFormat {
+	return &ResponseFormat{
+		Type:       ResponseFormatJSONSchema,
+		JSONSchema: &JSONSchema{Name: name, Schema: schema},
+	}
+}
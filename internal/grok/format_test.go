@@ -0,0 +1,49 @@
+package grok
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompleteResponseFormat(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","properties":{"order_number":{"type":"string"}}}`)
+
+	tests := []struct {
+		name   string
+		format *ResponseFormat
+		want   string
+	}{
+		{"None", nil, `null`},
+		{"JSONObject", JSONObjectFormat(), `{"type":"json_object"}`},
+		{"JSONSchema", JSONSchemaFormat("order", schema), `{"type":"json_schema","json_schema":{"name":"order","schema":` + string(schema) + `}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]json.RawMessage
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("Failed to decode request: %v", err)
+				}
+				got := body["response_format"]
+				if got == nil {
+					got = json.RawMessage(`null`)
+				}
+				if string(got) != tt.want {
+					t.Errorf("Expected response_format %s, got %s", tt.want, got)
+				}
+				w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"order_number\":\"A1\"}"},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			req := userRequest("Order A1 is late")
+			req.ResponseFormat = tt.format
+			if _, err := NewClient("key", server.URL, "grok-beta").Complete(context.Background(), req); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
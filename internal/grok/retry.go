@@ -0,0 +1,144 @@
+package grok
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Retry defaults used by NewClient.
+const (
+	DefaultMaxRetries     = 2
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 10 * time.Second
+)
+
+// maxErrorBodySize caps how much of an error response is read.
+const maxErrorBodySize = 64 << 10
+
+// clientStats counts API calls for the /stats endpoint.
+type clientStats struct {
+	requests     atomic.Int64
+	attempts     atomic.Int64
+	retries      atomic.Int64
+	rateLimited  atomic.Int64
+	serverErrors atomic.Int64
+	gaveUp       atomic.Int64
+}
+
+// Stats returns request and retry counters.
+func (c *Client) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"requests":      c.stats.requests.Load(),
+		"attempts":      c.stats.attempts.Load(),
+		"retries":       c.stats.retries.Load(),
+		"rate_limited":  c.stats.rateLimited.Load(),
+		"server_errors": c.stats.serverErrors.Load(),
+		"gave_up":       c.stats.gaveUp.Load(),
+	}
+}
+
+// post sends body to path and returns the response if its status is 200.
+// Rate limit and server errors are retried up to MaxRetries times with
+// jittered exponential backoff, waiting at least as long as Retry-After asks.
+// It stops early when the wait would outlast ctx.
+func (c *Client) post(ctx context.Context, path string, body []byte, accept string) (*http.Response, error) {
+	c.stats.requests.Add(1)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.do(ctx, path, body, accept)
+		if err == nil {
+			return resp, nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.Retryable() {
+			return nil, err
+		}
+		if attempt >= c.MaxRetries {
+			if attempt > 0 {
+				c.stats.gaveUp.Add(1)
+			}
+			return nil, err
+		}
+
+		delay := c.backoff(attempt, apiErr.RetryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			c.stats.gaveUp.Add(1)
+			return nil, err
+		}
+
+		log.Printf("%v, retrying in %s (retry %d of %d)", err, delay.Round(time.Millisecond), attempt+1, c.MaxRetries)
+		c.stats.retries.Add(1)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w (retry aborted: %w)", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// do makes a single attempt and classifies its failure.
+func (c *Client) do(ctx context.Context, path string, body []byte, accept string) (*http.Response, error) {
+	c.stats.attempts.Add(1)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", accept)
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		if isTimeout(err) {
+			return nil, fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	apiErr := parseAPIError(resp.StatusCode, errBody, resp.Header.Get("Retry-After"))
+
+	switch {
+	case errors.Is(apiErr, ErrRateLimited):
+		c.stats.rateLimited.Add(1)
+	case errors.Is(apiErr, ErrServer):
+		c.stats.serverErrors.Add(1)
+	}
+
+	return nil, apiErr
+}
+
+// backoff returns the wait before retry number attempt+1: a random delay
+// between half and all of RetryBaseDelay*2^attempt, capped at RetryMaxDelay,
+// or retryAfter if the server asked for longer.
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := c.RetryMaxDelay
+	if attempt < 30 {
+		delay = min(c.RetryBaseDelay<<attempt, c.RetryMaxDelay)
+	}
+	if delay > 0 {
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+	return max(delay, retryAfter)
+}
@@ -0,0 +1,151 @@
+package grok
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrStreamIncomplete is reported when a stream ends without the [DONE] marker.
+var ErrStreamIncomplete = errors.New("stream ended before completion")
+
+// StreamChunk is one piece of a streamed completion. The final chunk sent
+// before the channel closes carries either a FinishReason or an Err; the
+// tool calls of a completion are assembled and delivered with its
+// FinishReason, as is the token usage if the API reports it.
+type StreamChunk struct {
+	Delta        string
+	FinishReason string
+	ToolCalls    []ToolCall
+	Usage        *Usage
+	Err          error
+}
+
+// StreamOptions configures a streamed completion. OpenAI-compatible APIs
+// only report the token usage of a stream when IncludeUsage is set.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type streamResponse struct {
+	Choices []struct {
+		Delta struct {
+			Content   string          `json:"content"`
+			ToolCalls []toolCallDelta `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// Stream starts a streamed chat completion and returns a channel of content
+// deltas. The channel is closed when the stream ends; mid-stream failures are
+// delivered as a chunk with Err set. Cancelling ctx aborts the stream.
+func (c *Client) Stream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	if req.Model == "" {
+		req.Model = c.Model
+	}
+	req.Stream = true
+	req.StreamOptions = &StreamOptions{IncludeUsage: true}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.post(ctx, "/chat/completions", jsonData, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer resp.Body.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if err := readStream(resp.Body, send); err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			send(StreamChunk{Err: err})
+		}
+	}()
+
+	return chunks, nil
+}
+
+// readStream parses server-sent events from r and passes each content delta
+// to send until the [DONE] marker. It stops early if send returns false.
+func readStream(r io.Reader, send func(StreamChunk) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	finishReason := ""
+	var toolCalls toolCallBuilder
+	var usage *Usage
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Blank lines separate events; lines starting with ':' are comments
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+
+		if data == "[DONE]" {
+			if finishReason != "" || len(toolCalls.calls) > 0 || usage != nil {
+				send(StreamChunk{FinishReason: finishReason, ToolCalls: toolCalls.calls, Usage: usage})
+			}
+			return nil
+		}
+
+		var event streamResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if event.Error != nil {
+			return fmt.Errorf("API error: %s", event.Error.Message)
+		}
+		if event.Usage != nil {
+			usage = event.Usage
+		}
+
+		for _, choice := range event.Choices {
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				finishReason = *choice.FinishReason
+			}
+			for _, delta := range choice.Delta.ToolCalls {
+				toolCalls.add(delta)
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if !send(StreamChunk{Delta: choice.Delta.Content}) {
+				return nil
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return ErrStreamIncomplete
+}
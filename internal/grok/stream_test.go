@@ -0,0 +1,140 @@
+package grok
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantText string
+		wantErr  error
+	}{
+		{
+			name: "Complete",
+			body: ": keep-alive\n\n" +
+				`data: {"choices":[{"delta":{"role":"assistant"}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}` + "\n\n" +
+				"data: [DONE]\n\n",
+			wantText: "Hello",
+		},
+		{
+			name: "ErrorMidStream",
+			body: `data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n" +
+				`data: {"error":{"message":"overloaded","type":"server_error"}}` + "\n\n",
+			wantText: "Hel",
+		},
+		{
+			name:     "Truncated",
+			body:     `data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n",
+			wantText: "Hel",
+			wantErr:  ErrStreamIncomplete,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req ChatCompletionRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				if !req.Stream {
+					t.Error("Expected stream to be requested")
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			client := NewClient("key", server.URL, "grok-beta")
+			chunks, err := client.Stream(context.Background(), userRequest("Hi"))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			var text strings.Builder
+			var streamErr error
+			finishReason := ""
+			for chunk := range chunks {
+				text.WriteString(chunk.Delta)
+				if chunk.FinishReason != "" {
+					finishReason = chunk.FinishReason
+				}
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+				}
+			}
+
+			if text.String() != tt.wantText {
+				t.Errorf("Expected %q, got %q", tt.wantText, text.String())
+			}
+			if tt.name == "Complete" {
+				if streamErr != nil || finishReason != "stop" {
+					t.Errorf("Expected clean finish, got reason %q, error %v", finishReason, streamErr)
+				}
+				return
+			}
+			if streamErr == nil {
+				t.Fatal("Expected a stream error")
+			}
+			if tt.wantErr != nil && !errors.Is(streamErr, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, streamErr)
+			}
+		})
+	}
+}
+
+func TestStreamUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			t.Error("Expected the stream to ask for usage")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"Hello"},"finish_reason":"stop"}]}`+"\n\n"+
+			`data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":2,"total_tokens":14}}`+"\n\n"+
+			"data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewClient("key", server.URL, "grok-beta")
+	chunks, err := client.Stream(context.Background(), userRequest("Hi"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var usage *Usage
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("Expected no stream error, got: %v", chunk.Err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+	if usage == nil || usage.PromptTokens != 12 || usage.CompletionTokens != 2 || usage.TotalTokens != 14 {
+		t.Errorf("Expected the final chunk's usage, got %+v", usage)
+	}
+}
+
+func TestStreamHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"invalid api key"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("bad", server.URL, "grok-beta")
+	if _, err := client.Stream(context.Background(), userRequest("Hi")); err == nil || !strings.Contains(err.Error(), "invalid api key") {
+		t.Errorf("Expected API error, got: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package grok
+
+import "encoding/json"
+
+// Tool describes a function the model may ask to call.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function FunctionSpec `json:"function"`
+}
+
+// FunctionSpec names a function and describes its arguments.
+type FunctionSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters is the JSON schema of the arguments object
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is the model's request to run a function.
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall carries the function name and its arguments as a JSON string.
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// FunctionTool returns a Tool for the function name, whose arguments follow
+// the JSON schema parameters.
+func FunctionTool(name, description string, parameters json.RawMessage) Tool {
+	return Tool{
+		Type:     "function",
+		Function: FunctionSpec{Name: name, Description: description, Parameters: parameters},
+	}
+}
+
+// toolCallDelta is a fragment of a tool call in a streamed response. The
+// first fragment of each call carries its ID and name; the arguments arrive
+// in pieces to be concatenated.
+type toolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// toolCallBuilder assembles streamed tool call fragments.
+type toolCallBuilder struct {
+	calls []ToolCall
+}
+
+func (b *toolCallBuilder) add(delta toolCallDelta) {
+	for len(b.calls) <= delta.Index {
+		b.calls = append(b.calls, ToolCall{Type: "function"})
+	}
+
+	call := &b.calls[delta.Index]
+	if delta.ID != "" {
+		call.ID = delta.ID
+	}
+	if delta.Type != "" {
+		call.Type = delta.Type
+	}
+	call.Function.Name += delta.Function.Name
+	call.Function.Arguments += delta.Function.Arguments
+}
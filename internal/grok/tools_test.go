@@ -0,0 +1,103 @@
+package grok
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var weatherTool = FunctionTool("get_weather", "Current weather for a city",
+	json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`))
+
+func TestCompleteToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "get_weather" {
+			t.Errorf("Expected the weather tool to be offered, got %+v", req.Tools)
+		}
+
+		// The second round carries the call and its result
+		if n := len(req.Messages); n == 3 {
+			if req.Messages[1].ToolCalls[0].ID != "call_1" || req.Messages[2].ToolCallID != "call_1" {
+				t.Errorf("Expected the tool round trip, got %+v", req.Messages)
+			}
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Sunny in Oslo."},"finish_reason":"stop"}]}`))
+			return
+		}
+
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[
+			{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Oslo\"}"}}
+		]},"finish_reason":"tool_calls"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("key", server.URL, "grok-beta")
+	req := userRequest("Weather in Oslo?")
+	req.Tools = []Tool{weatherTool}
+
+	response, err := client.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	call := response.Choices[0].Message
+	if len(call.ToolCalls) != 1 || call.ToolCalls[0].Function.Arguments != `{"city":"Oslo"}` {
+		t.Fatalf("Expected a get_weather call, got %+v", call)
+	}
+
+	req.Messages = append(req.Messages, call, Message{Role: "tool", Content: "Sunny, 21C", ToolCallID: "call_1"})
+	response, err = client.Complete(context.Background(), req)
+	if err != nil || response.Choices[0].Message.Content != "Sunny in Oslo." {
+		t.Fatalf("Expected the final answer, got %+v, error %v", response, err)
+	}
+}
+
+func TestStreamToolCalls(t *testing.T) {
+	events := []string{
+		`{"choices":[{"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Oslo\"}"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Rome\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewClient("key", server.URL, "grok-beta")
+	req := userRequest("Weather in Oslo and Rome?")
+	req.Tools = []Tool{weatherTool}
+
+	chunks, err := client.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var last StreamChunk
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+		last = chunk
+	}
+
+	if last.FinishReason != "tool_calls" || len(last.ToolCalls) != 2 {
+		t.Fatalf("Expected two tool calls with the final chunk, got %+v", last)
+	}
+	if got := last.ToolCalls[0]; got.ID != "call_1" || got.Function.Arguments != `{"city":"Oslo"}` {
+		t.Errorf("Expected the fragments of call_1 joined, got %+v", got)
+	}
+	if got := last.ToolCalls[1]; got.ID != "call_2" || got.Function.Name != "get_weather" {
+		t.Errorf("Expected call_2, got %+v", got)
+	}
+}
@@ -0,0 +1,251 @@
+// Package guardrails checks user input before it reaches the language model
+// and model output before it is sent, against configurable rules.
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+)
+
+// Stage is where in the pipeline a text is checked.
+type Stage string
+
+const (
+	// Input is user text on its way to the language model.
+	Input Stage = "input"
+	// Output is text on its way out to the user.
+	Output Stage = "output"
+)
+
+// Reason codes logged and counted for each decision. The classifier's are
+// ReasonFlagged followed by the category it reported, e.g. "flagged_violence".
+const (
+	ReasonBlockedTerm     = "blocked_term"
+	ReasonPIICard         = "pii_card"
+	ReasonPIIIBAN         = "pii_iban"
+	ReasonPIINationalID   = "pii_national_id"
+	ReasonTooLong         = "too_long"
+	ReasonFlagged         = "flagged_"
+	ReasonClassifierError = "classifier_error"
+)
+
+// Finding is something a rule found in a text.
+type Finding struct {
+	Reason string
+	// Block stops the text; otherwise the rule rewrote it or only noted the finding.
+	Block bool
+}
+
+// Rule inspects a text at a stage and returns it, rewritten if the rule
+// redacts or shortens it, with what it found.
+type Rule interface {
+	Check(ctx context.Context, stage Stage, text string) (string, []Finding)
+}
+
+// MeteredRule is a Rule that asks a language model, reporting the call so
+// that what it consumed can be accounted for.
+type MeteredRule interface {
+	Rule
+	// CheckMetered is Check that also returns the model call it made, or
+	// nil if none completed.
+	CheckMetered(ctx context.Context, stage Stage, text string) (string, []Finding, *ModelCall)
+}
+
+// ModelCall is a language model call a rule made.
+type ModelCall struct {
+	Model        string
+	Usage        llm.Usage
+	FinishReason string
+	Latency      time.Duration
+}
+
+// Decision is the outcome of checking a text.
+type Decision struct {
+	// Text is the text to use in place of the original, if not blocked.
+	Text    string
+	Blocked bool
+	// Reasons are the reason codes of all findings, in rule order.
+	Reasons []string
+	// Calls are the model calls the rules made, each billed like any other.
+	Calls []ModelCall
+}
+
+// Pipeline runs rules in order and keeps count of its decisions.
+type Pipeline struct {
+	rules []Rule
+
+	mu     sync.Mutex
+	counts map[Stage]*stageCounts
+}
+
+type stageCounts struct {
+	checked  int64
+	blocked  int64
+	modified int64
+	reasons  map[string]int64
+}
+
+// NewPipeline returns a pipeline running rules in the order given.
+func NewPipeline(rules ...Rule) *Pipeline {
+	return &Pipeline{rules: rules, counts: make(map[Stage]*stageCounts)}
+}
+
+// New builds the pipeline configured by the GUARDRAILS_* settings. The
+// classifier, if configured, runs on provider, which may be nil to leave it out.
+func New(config *configs.Config, provider llm.Provider) (*Pipeline, error) {
+	var rules []Rule
+
+	if terms := splitList(config.GuardrailBlockedTerms); len(terms) > 0 {
+		rules = append(rules, BlockedTerms(terms))
+	}
+
+	if kinds := splitList(config.GuardrailPII); len(kinds) > 0 && !(len(kinds) == 1 && kinds[0] == "none") {
+		rule, err := RedactPII(kinds...)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if config.GuardrailMaxInputChars > 0 || config.GuardrailMaxOutputChars > 0 {
+		rules = append(rules, MaxLength(config.GuardrailMaxInputChars, config.GuardrailMaxOutputChars))
+	}
+
+	if config.GuardrailClassifierModel != "" {
+		if provider == nil {
+			log.Printf("Warning: GUARDRAILS_CLASSIFIER_MODEL needs a language model, skipping the classifier")
+		} else {
+			rules = append(rules, Classifier(provider, config.GuardrailClassifierModel))
+		}
+	}
+
+	return NewPipeline(rules...), nil
+}
+
+// Enabled reports whether the pipeline has any rules to run.
+func (p *Pipeline) Enabled() bool {
+	return len(p.rules) > 0
+}
+
+// Check runs the rules over text. A rule that blocks the text stops the rules after it.
+func (p *Pipeline) Check(ctx context.Context, stage Stage, text string) Decision {
+	decision := Decision{Text: text}
+	for _, rule := range p.rules {
+		var rewritten string
+		var findings []Finding
+		if metered, ok := rule.(MeteredRule); ok {
+			var call *ModelCall
+			rewritten, findings, call = metered.CheckMetered(ctx, stage, decision.Text)
+			if call != nil {
+				decision.Calls = append(decision.Calls, *call)
+			}
+		} else {
+			rewritten, findings = rule.Check(ctx, stage, decision.Text)
+		}
+		decision.Text = rewritten
+		for _, finding := range findings {
+			decision.Reasons = append(decision.Reasons, finding.Reason)
+			if finding.Block {
+				decision.Blocked = true
+			}
+		}
+		if decision.Blocked {
+			break
+		}
+	}
+
+	p.record(stage, text, decision)
+	return decision
+}
+
+// record logs and counts a decision.
+func (p *Pipeline) record(stage Stage, original string, decision Decision) {
+	modified := !decision.Blocked && decision.Text != original
+
+	p.mu.Lock()
+	counts := p.counts[stage]
+	if counts == nil {
+		counts = &stageCounts{reasons: make(map[string]int64)}
+		p.counts[stage] = counts
+	}
+	counts.checked++
+	if decision.Blocked {
+		counts.blocked++
+	}
+	if modified {
+		counts.modified++
+	}
+	for _, reason := range decision.Reasons {
+		counts.reasons[reason]++
+	}
+	p.mu.Unlock()
+
+	if len(decision.Reasons) == 0 {
+		return
+	}
+	action := "allowed"
+	if decision.Blocked {
+		action = "blocked"
+	} else if modified {
+		action = "modified"
+	}
+	log.Printf("Guardrails %s %s: %s", action, stage, strings.Join(decision.Reasons, ", "))
+}
+
+// Stats returns the decisions counted per stage.
+func (p *Pipeline) Stats() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := map[string]interface{}{"rules": len(p.rules)}
+	for _, stage := range []Stage{Input, Output} {
+		counts := p.counts[stage]
+		if counts == nil {
+			counts = &stageCounts{}
+		}
+		reasons := make(map[string]int64, len(counts.reasons))
+		for reason, n := range counts.reasons {
+			reasons[reason] = n
+		}
+		stats[string(stage)] = map[string]interface{}{
+			"checked":  counts.checked,
+			"blocked":  counts.blocked,
+			"modified": counts.modified,
+			"reasons":  reasons,
+		}
+	}
+	return stats
+}
+
+// splitList splits a comma-separated setting into trimmed, lowercased,
+// non-empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// piiKinds lists the PII detectors RedactPII accepts, for error messages.
+func piiKinds() string {
+	kinds := make([]string, len(piiDetectors))
+	for i, detector := range piiDetectors {
+		kinds[i] = detector.kind
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// errUnknownPII reports a PII detector that does not exist.
+func errUnknownPII(kind string) error {
+	return fmt.Errorf("unknown PII kind %q in GUARDRAILS_PII (want %s or none)", kind, piiKinds())
+}
@@ -0,0 +1,121 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+)
+
+func TestPipelineCheck(t *testing.T) {
+	pii, err := RedactPII("card", "iban", "national_id")
+	if err != nil {
+		t.Fatalf("Failed to create PII rule: %v", err)
+	}
+	pipeline := NewPipeline(BlockedTerms([]string{"casino", "free money"}), pii, MaxLength(40, 0))
+
+	tests := []struct {
+		name        string
+		stage       Stage
+		text        string
+		wantText    string
+		wantBlocked bool
+		wantReasons []string
+	}{
+		{"Clean", Input, "Where is my order?", "Where is my order?", false, nil},
+		{"BlockedTerm", Input, "Try our CASINO today", "Try our CASINO today", true, []string{ReasonBlockedTerm}},
+		{"BlockedPhrase", Output, "Get free money now", "Get free money now", true, []string{ReasonBlockedTerm}},
+		{"PartOfWord", Input, "Casinos are closed", "Casinos are closed", false, nil},
+		{"Card", Input, "Card 4111 1111 1111 1111", "Card [REDACTED CARD]", false, []string{ReasonPIICard}},
+		{"NotLuhn", Input, "Order 4111 1111 1111 1112", "Order 4111 1111 1111 1112", false, nil},
+		{"IBAN", Output, "Pay GB82 WEST 1234 5698 7654 32", "Pay [REDACTED IBAN]", false, []string{ReasonPIIIBAN}},
+		{"BadIBAN", Output, "Ref GB00 WEST 1234 5698 7654 32", "Ref GB00 WEST 1234 5698 7654 32", false, nil},
+		{"SSN", Input, "My SSN is 123-45-6789", "My SSN is [REDACTED ID]", false, []string{ReasonPIINationalID}},
+		{"NINO", Input, "NI number AB 12 34 56 C", "NI number [REDACTED ID]", false, []string{ReasonPIINationalID}},
+		{"TooLong", Input, "This message goes on and on for far too long to keep", "This message goes on and on for far…", false, []string{ReasonTooLong}},
+		{"OutputUnlimited", Output, "This message goes on and on for far too long to keep", "This message goes on and on for far too long to keep", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := pipeline.Check(context.Background(), tt.stage, tt.text)
+			if decision.Text != tt.wantText || decision.Blocked != tt.wantBlocked || !reflect.DeepEqual(decision.Reasons, tt.wantReasons) {
+				t.Errorf("Expected %q (blocked %v, reasons %v), got %+v", tt.wantText, tt.wantBlocked, tt.wantReasons, decision)
+			}
+		})
+	}
+
+	stats := pipeline.Stats()
+	input := stats["input"].(map[string]interface{})
+	if input["checked"] != int64(8) || input["blocked"] != int64(1) || input["modified"] != int64(4) {
+		t.Errorf("Expected 8 inputs checked, 1 blocked and 4 modified, got %v", input)
+	}
+	if reasons := input["reasons"].(map[string]int64); reasons[ReasonPIINationalID] != 2 {
+		t.Errorf("Expected 2 national IDs counted, got %v", reasons)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    *llm.Scripted
+		wantBlocked bool
+		wantReasons []string
+		wantCalls   int
+	}{
+		{"Allowed", llm.NewScripted(`{"flagged": false, "category": "none"}`), false, nil, 1},
+		{"Flagged", llm.NewScripted("```json\n{\"flagged\": true, \"category\": \"Violence\"}\n```"), true, []string{"flagged_violence"}, 1},
+		{"NoCategory", llm.NewScripted(`{"flagged": true}`), true, []string{"flagged_other"}, 1},
+		{"NotJSON", llm.NewScripted("I cannot classify this."), false, []string{ReasonClassifierError}, 1},
+		{"Failed", llm.NewScripted().Fail(errors.New("boom")), false, []string{ReasonClassifierError}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := NewPipeline(Classifier(tt.provider, "moderation-model"))
+			decision := pipeline.Check(context.Background(), Input, "Some message")
+			if decision.Blocked != tt.wantBlocked || !reflect.DeepEqual(decision.Reasons, tt.wantReasons) {
+				t.Errorf("Expected blocked %v with reasons %v, got %+v", tt.wantBlocked, tt.wantReasons, decision)
+			}
+			if decision.Text != "Some message" {
+				t.Errorf("Expected the text unchanged, got %q", decision.Text)
+			}
+			if model := tt.provider.Requests()[0].Model; model != "moderation-model" {
+				t.Errorf("Expected the classifier model, got %q", model)
+			}
+			if len(decision.Calls) != tt.wantCalls {
+				t.Errorf("Expected %d model calls reported, got %+v", tt.wantCalls, decision.Calls)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    configs.Config
+		wantRules int
+		wantErr   bool
+	}{
+		{"Defaults", configs.Config{}, 0, false},
+		{"PIIAndLength", configs.Config{GuardrailPII: "card,iban,national_id", GuardrailMaxInputChars: 4000}, 2, false},
+		{"Nothing", configs.Config{GuardrailPII: "none"}, 0, false},
+		{"Everything", configs.Config{GuardrailBlockedTerms: "casino", GuardrailPII: "card", GuardrailMaxOutputChars: 10, GuardrailClassifierModel: "m"}, 4, false},
+		{"UnknownPII", configs.Config{GuardrailPII: "card,passport"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := New(&tt.config, llm.NewScripted())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+			if err == nil && len(pipeline.rules) != tt.wantRules {
+				t.Errorf("Expected %d rules, got %d", tt.wantRules, len(pipeline.rules))
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package guardrails
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+)
+
+// blockedTerms blocks texts containing any of a list of words or phrases.
+type blockedTerms struct {
+	pattern *regexp.Regexp
+}
+
+// BlockedTerms returns a rule that blocks texts containing any of terms as
+// whole words, ignoring case, at both stages.
+func BlockedTerms(terms []string) Rule {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	// Not part of a longer word on either side
+	pattern := `(?i)(?:^|[^\pL\pN_])(?:` + strings.Join(quoted, "|") + `)(?:$|[^\pL\pN_])`
+	return blockedTerms{pattern: regexp.MustCompile(pattern)}
+}
+
+func (r blockedTerms) Check(ctx context.Context, stage Stage, text string) (string, []Finding) {
+	if r.pattern.MatchString(text) {
+		return text, []Finding{{Reason: ReasonBlockedTerm, Block: true}}
+	}
+	return text, nil
+}
+
+// piiDetector finds one kind of personal data. valid, if set, confirms a
+// candidate match, such as by its checksum.
+type piiDetector struct {
+	kind        string
+	reason      string
+	pattern     *regexp.Regexp
+	valid       func(match string) bool
+	replacement string
+}
+
+// piiDetectors run in this order, IBANs first as their digits can pass for a card number.
+var piiDetectors = []piiDetector{
+	{
+		kind:        "iban",
+		reason:      ReasonPIIIBAN,
+		pattern:     regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		valid:       ibanValid,
+		replacement: "[REDACTED IBAN]",
+	},
+	{
+		kind:        "card",
+		reason:      ReasonPIICard,
+		pattern:     regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid:       luhnValid,
+		replacement: "[REDACTED CARD]",
+	},
+	// US social security and UK national insurance numbers
+	{
+		kind:        "national_id",
+		reason:      ReasonPIINationalID,
+		pattern:     regexp.MustCompile(`\b(?:\d{3}-\d{2}-\d{4}|[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D])\b`),
+		replacement: "[REDACTED ID]",
+	},
+}
+
+// piiRedactor replaces personal data with placeholders.
+type piiRedactor struct {
+	detectors []piiDetector
+}
+
+// RedactPII returns a rule that replaces the given kinds of personal data,
+// "card", "iban" and "national_id", with placeholders at both stages.
+func RedactPII(kinds ...string) (Rule, error) {
+	wanted := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		if !knownPII(kind) {
+			return nil, errUnknownPII(kind)
+		}
+		wanted[kind] = true
+	}
+
+	var r piiRedactor
+	for _, detector := range piiDetectors {
+		if wanted[detector.kind] {
+			r.detectors = append(r.detectors, detector)
+		}
+	}
+	return r, nil
+}
+
+// knownPII reports whether kind names one of the piiDetectors.
+func knownPII(kind string) bool {
+	for _, detector := range piiDetectors {
+		if detector.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r piiRedactor) Check(ctx context.Context, stage Stage, text string) (string, []Finding) {
+	var findings []Finding
+	for _, detector := range r.detectors {
+		found := false
+		text = detector.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if detector.valid != nil && !detector.valid(match) {
+				return match
+			}
+			found = true
+			return detector.replacement
+		})
+		if found {
+			findings = append(findings, Finding{Reason: detector.reason})
+		}
+	}
+	return text, findings
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum of card numbers.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		digit := int(s[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ibanValid reports whether s passes the ISO 13616 mod-97 check.
+func ibanValid(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+
+	// Move the country code and check digits to the end, letters count as 10-35
+	var digits strings.Builder
+	for _, c := range s[4:] + s[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			digits.WriteString(big.NewInt(int64(c-'A') + 10).String())
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// maxLength cuts texts longer than a stage's limit.
+type maxLength struct {
+	limits map[Stage]int
+}
+
+// MaxLength returns a rule that cuts input and output longer than the given
+// number of characters; 0 leaves a stage unlimited.
+func MaxLength(input, output int) Rule {
+	return maxLength{limits: map[Stage]int{Input: input, Output: output}}
+}
+
+func (r maxLength) Check(ctx context.Context, stage Stage, text string) (string, []Finding) {
+	limit := r.limits[stage]
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return text, nil
+	}
+
+	// Cut at the last space in the limit so no word is split
+	runes := []rune(text)[:limit-1]
+	cut := len(runes)
+	for i := len(runes) - 1; i > len(runes)/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + "…", []Finding{{Reason: ReasonTooLong}}
+}
+
+// classifierTimeout bounds one classification.
+const classifierTimeout = 10 * time.Second
+
+// classifierPrompt asks the model for a moderation verdict.
+const classifierPrompt = `You are a content moderation classifier for a customer messaging service. Decide whether the message below must be blocked because it contains hate, harassment, threats, sexual content involving minors, instructions for violence or self-harm, or other clearly harmful content. Answer with JSON only: {"flagged": true or false, "category": "one lowercase word naming the category, or none"}.`
+
+// classifier blocks texts a language model flags as harmful.
+type classifier struct {
+	provider llm.Provider
+	model    string
+}
+
+// Classifier returns a rule that asks model on provider whether a text is
+// harmful and blocks it if so, at both stages. A classification that fails
+// lets the text through with ReasonClassifierError.
+func Classifier(provider llm.Provider, model string) Rule {
+	return classifier{provider: provider, model: model}
+}
+
+type classification struct {
+	Flagged  bool   `json:"flagged"`
+	Category string `json:"category"`
+}
+
+func (r classifier) Check(ctx context.Context, stage Stage, text string) (string, []Finding) {
+	text, findings, _ := r.CheckMetered(ctx, stage, text)
+	return text, findings
+}
+
+func (r classifier) CheckMetered(ctx context.Context, stage Stage, text string) (string, []Finding, *ModelCall) {
+	ctx, cancel := llm.WithTimeout(ctx, classifierTimeout)
+	defer cancel()
+
+	began := time.Now()
+	response, err := r.provider.Complete(ctx, llm.Request{
+		Model: r.model,
+		Messages: []llm.Message{
+			{Role: "system", Content: classifierPrompt},
+			{Role: "user", Content: text},
+		},
+		MaxTokens: 50,
+	})
+	if err != nil {
+		return text, []Finding{{Reason: ReasonClassifierError}}, nil
+	}
+	call := &ModelCall{Model: response.Model, Usage: response.Usage, FinishReason: response.FinishReason, Latency: time.Since(began)}
+	if call.Model == "" {
+		call.Model = r.model
+	}
+
+	// Models like to wrap JSON in prose or code fences
+	content := response.Content
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	var result classification
+	if start < 0 || end < start || json.Unmarshal([]byte(content[start:end+1]), &result) != nil {
+		return text, []Finding{{Reason: ReasonClassifierError}}, call
+	}
+
+	if !result.Flagged {
+		return text, nil, call
+	}
+	category := strings.ToLower(strings.TrimSpace(result.Category))
+	if category == "" || category == "none" {
+		category = "other"
+	}
+	return text, []Finding{{Reason: ReasonFlagged + category, Block: true}}, call
+}
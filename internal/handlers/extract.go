@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/conversation"
+)
+
+func (h *MCPHandler) handleExtractTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	// Extract parameters
+	message, ok := arguments["message"].(string)
+	if !ok || message == "" {
+		return nil, fmt.Errorf("message is required and must be a string")
+	}
+
+	rawSchema, ok := arguments["schema"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema is required and must be a JSON schema object")
+	}
+	schema, err := json.Marshal(rawSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	userID, _ := arguments["user_id"].(string)
+	instructions, _ := arguments["instructions"].(string)
+	format, _ := arguments["format"].(string)
+
+	ctx, cancel, err := chatContext(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	if ctx, err = h.chatModel(ctx, arguments); err != nil {
+		return nil, err
+	}
+
+	extraction, err := h.conversation.Extract(ctx, conversation.ExtractRequest{
+		UserID:       userID,
+		Message:      message,
+		Schema:       schema,
+		Instructions: instructions,
+		Format:       format,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"data":     extraction.Data,
+		"attempts": extraction.Attempts,
+	}
+	if usage := extraction.Usage; usage != nil {
+		result["model"] = usage.Model
+		result["total_tokens"] = usage.TotalTokens
+		if usage.CostUSD != nil {
+			result["cost_usd"] = *usage.CostUSD
+		}
+	}
+
+	return result, nil
+}
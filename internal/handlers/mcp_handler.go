@@ -3,38 +3,39 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/conversation"
 	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
-	"github.com/sinhaparth5/whatstyle-mcp/internal/grok"
-	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/whatsapp"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+var errToolNotFound = errors.New("tool not found")
+
+// maxChatTimeout caps the timeout_ms argument of the chat tool.
+const maxChatTimeout = 5 * time.Minute
+
 type MCPHandler struct {
-	db         *database.DB
-	grokClient *grok.Client
-	config     *configs.Config
-	server     *mcp.Server
+	db           *database.DB
+	conversation *conversation.Service
+	whatsapp     *whatsapp.Handler
+	config       *configs.Config
+	server       *mcp.Server
 }
 
 func NewMCPHandler(db *database.DB, config *configs.Config, impl *mcp.Implementation, caps interface{}) *MCPHandler {
 	h := &MCPHandler{
-		db:     db,
-		config: config,
-	}
-	
-	// Initialize Grok client
-	if config.GrokAPIKey != "" {
-		h.grokClient = grok.NewClient(config.GrokAPIKey, config.GrokBaseURL, config.GrokModel)
-		log.Printf("Grok client initialized with model: %s", config.GrokModel)
-	} else {
-		log.Printf("Warning: GROK_API_KEY not set, using fallback responses")
+		db:           db,
+		conversation: conversation.NewService(db, config),
+		config:       config,
 	}
 	
 	// Create MCP server
@@ -43,45 +44,41 @@ func NewMCPHandler(db *database.DB, config *configs.Config, impl *mcp.Implementa
 	return h
 }
 
+// Conversation returns the chat pipeline used by the chat tool so other
+// entry points, such as the WhatsApp webhook, answer messages the same way.
+func (h *MCPHandler) Conversation() *conversation.Service {
+	return h.conversation
+}
+
+// SetWhatsApp enables the tools that send WhatsApp messages.
+func (h *MCPHandler) SetWhatsApp(handler *whatsapp.Handler) {
+	h.whatsapp = handler
+}
+
 func (h *MCPHandler) RegisterTools(server *mcp.Server) {
 	// Tools will be handled through HTTP interface
-	log.Printf("MCP tools registered: chat, history")
+	log.Printf("MCP tools registered: chat, history, message_status, send_media, send_template, window_status, send_interactive, list_personas, create_persona, update_persona, assign_persona, usage_report, update_user, extract")
 }
 
 func (h *MCPHandler) handleChatTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
-	// Extract parameters
-	userID, ok := arguments["user_id"].(string)
-	if !ok || userID == "" {
-		return nil, fmt.Errorf("user_id is required and must be a string")
-	}
-
-	message, ok := arguments["message"].(string)
-	if !ok || message == "" {
-		return nil, fmt.Errorf("message is required and must be a string")
-	}
-
-	// Save user message
-	if err := h.db.SaveMessage(userID, message, "user"); err != nil {
-		log.Printf("Error saving user message: %v", err)
-		return nil, fmt.Errorf("failed to save message: %v", err)
+	userID, message, err := chatArguments(arguments)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get chat history for context
-	history, err := h.db.GetChatHistory(userID, 10)
+	ctx, cancel, err := chatContext(ctx, arguments)
 	if err != nil {
-		log.Printf("Error getting chat history: %v", err)
+		return nil, err
 	}
+	defer cancel()
 
-	// Generate response using Grok
-	response, err := h.generateResponse(message, history)
-	if err != nil {
-		log.Printf("Error generating response: %v", err)
-		response = "I apologize, but I'm having trouble generating a response right now. Please try again."
+	if ctx, err = h.chatModel(ctx, arguments); err != nil {
+		return nil, err
 	}
 
-	// Save assistant response
-	if err := h.db.SaveMessage(userID, response, "assistant"); err != nil {
-		log.Printf("Error saving assistant message: %v", err)
+	response, err := h.conversation.Reply(ctx, userID, message)
+	if err != nil {
+		return nil, err
 	}
 
 	// Return successful result
@@ -93,6 +90,59 @@ func (h *MCPHandler) handleChatTool(ctx context.Context, arguments map[string]in
 	return result, nil
 }
 
+// chatArguments extracts the parameters of the chat tool.
+func chatArguments(arguments map[string]interface{}) (userID, message string, err error) {
+	userID, ok := arguments["user_id"].(string)
+	if !ok || userID == "" {
+		return "", "", fmt.Errorf("user_id is required and must be a string")
+	}
+
+	message, ok = arguments["message"].(string)
+	if !ok || message == "" {
+		return "", "", fmt.Errorf("message is required and must be a string")
+	}
+
+	return userID, message, nil
+}
+
+// chatContext applies the optional timeout_ms argument of the chat tool to ctx.
+// Without it the configured GROK_TIMEOUT applies.
+func chatContext(ctx context.Context, arguments map[string]interface{}) (context.Context, context.CancelFunc, error) {
+	raw, ok := arguments["timeout_ms"]
+	if !ok || raw == nil {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+
+	ms, ok := raw.(float64)
+	timeout := time.Duration(ms) * time.Millisecond
+	if !ok || timeout <= 0 || timeout > maxChatTimeout {
+		return nil, nil, fmt.Errorf("timeout_ms must be a number between 1 and %d", maxChatTimeout.Milliseconds())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// chatModel applies the optional model argument of the chat tool to ctx.
+// Without it the model is chosen by LLM_ROUTES.
+func (h *MCPHandler) chatModel(ctx context.Context, arguments map[string]interface{}) (context.Context, error) {
+	raw, ok := arguments["model"]
+	if !ok || raw == nil {
+		return ctx, nil
+	}
+
+	model, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("model must be a string")
+	}
+	if err := h.conversation.CheckModel(model); err != nil {
+		return nil, fmt.Errorf("invalid model %q: %w", model, err)
+	}
+
+	return conversation.WithModel(ctx, model), nil
+}
+
 func (h *MCPHandler) handleHistoryTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
 	// Extract parameters
 	userID, ok := arguments["user_id"].(string)
@@ -114,13 +164,39 @@ func (h *MCPHandler) handleHistoryTool(ctx context.Context, arguments map[string
 	// Convert to response format
 	var messages []map[string]interface{}
 	for _, msg := range history {
-		messages = append(messages, map[string]interface{}{
+		entry := map[string]interface{}{
 			"id":         msg.ID,
 			"user_id":    msg.UserID,
 			"content":    msg.Content,
 			"role":       msg.Role,
 			"created_at": msg.CreatedAt.Format(time.RFC3339),
-		})
+		}
+		if msg.WAMID != "" {
+			entry["wamid"] = msg.WAMID
+		}
+		if msg.SelectionID != "" {
+			entry["selection_id"] = msg.SelectionID
+		}
+		if len(msg.ToolCalls) > 0 {
+			entry["tool_calls"] = msg.ToolCalls
+		}
+		if msg.ToolCallID != "" {
+			entry["tool_call_id"] = msg.ToolCallID
+		}
+		if msg.Description != "" {
+			entry["description"] = msg.Description
+		}
+		if msg.FromAudio {
+			entry["from_audio"] = true
+		}
+		if msg.DeliveryStatus != "" {
+			entry["delivery_status"] = msg.DeliveryStatus
+		}
+		if msg.DeliveryErrorCode != 0 {
+			entry["delivery_error_code"] = msg.DeliveryErrorCode
+			entry["delivery_error"] = msg.DeliveryError
+		}
+		messages = append(messages, entry)
 	}
 
 	result := map[string]interface{}{
@@ -131,6 +207,370 @@ func (h *MCPHandler) handleHistoryTool(ctx context.Context, arguments map[string
 	return result, nil
 }
 
+func (h *MCPHandler) handleMessageStatusTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	// Extract parameters
+	wamid, ok := arguments["wamid"].(string)
+	if !ok || wamid == "" {
+		return nil, fmt.Errorf("wamid is required and must be a string")
+	}
+
+	statuses, err := h.db.GetMessageStatuses(wamid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message statuses: %v", err)
+	}
+
+	// Convert to response format
+	events := []map[string]interface{}{}
+	current := ""
+	for _, status := range statuses {
+		event := map[string]interface{}{
+			"status":    status.Status,
+			"timestamp": status.Timestamp.Format(time.RFC3339),
+		}
+		if status.ErrorCode != 0 {
+			event["error_code"] = status.ErrorCode
+			event["error_title"] = status.ErrorTitle
+			event["error_details"] = status.ErrorDetails
+		}
+		events = append(events, event)
+		if current != "failed" {
+			current = status.Status
+		}
+	}
+
+	result := map[string]interface{}{
+		"wamid":    wamid,
+		"status":   current,
+		"statuses": events,
+	}
+
+	return result, nil
+}
+
+func (h *MCPHandler) handleSendMediaTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	if h.whatsapp == nil {
+		return nil, fmt.Errorf("WhatsApp is not configured")
+	}
+
+	// Extract parameters
+	to, err := recipient(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, ok := arguments["type"].(string)
+	if !ok || mediaType == "" {
+		return nil, fmt.Errorf("type is required and must be a string")
+	}
+
+	media := whatsapp.OutboundMedia{Type: mediaType}
+	media.Path, _ = arguments["path"].(string)
+	media.MediaID, _ = arguments["media_id"].(string)
+	media.Link, _ = arguments["link"].(string)
+	media.Caption, _ = arguments["caption"].(string)
+	media.Filename, _ = arguments["filename"].(string)
+
+	if err := h.conversation.CheckOutput(ctx, to, media.Caption); err != nil {
+		return nil, err
+	}
+
+	wamid, err := h.whatsapp.SendMedia(to, media)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send media: %v", err)
+	}
+
+	// Keep the conversation history complete
+	if err := h.db.SaveOutboundMessage(to, media.PlainText(), wamid); err != nil {
+		log.Printf("Error saving outbound media message: %v", err)
+	}
+
+	result := map[string]interface{}{
+		"wamid": wamid,
+		"to":    to,
+		"type":  mediaType,
+	}
+
+	return result, nil
+}
+
+func (h *MCPHandler) handleSendTemplateTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	if h.whatsapp == nil {
+		return nil, fmt.Errorf("WhatsApp is not configured")
+	}
+
+	// Extract parameters
+	to, err := recipient(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	message := whatsapp.TemplateMessage{}
+	message.Name, _ = arguments["name"].(string)
+	message.LanguageCode, _ = arguments["language_code"].(string)
+
+	if message.HeaderParams, err = stringList(arguments, "header_params"); err != nil {
+		return nil, err
+	}
+	if message.BodyParams, err = stringList(arguments, "body_params"); err != nil {
+		return nil, err
+	}
+
+	if raw, ok := arguments["header_media"].(map[string]interface{}); ok {
+		media := &whatsapp.TemplateMedia{}
+		media.Type, _ = raw["type"].(string)
+		media.MediaID, _ = raw["media_id"].(string)
+		media.Link, _ = raw["link"].(string)
+		media.Filename, _ = raw["filename"].(string)
+		message.HeaderMedia = media
+	}
+
+	if raw, ok := arguments["buttons"].([]interface{}); ok {
+		for i, item := range raw {
+			b, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("buttons[%d] must be an object", i)
+			}
+			button := whatsapp.TemplateButton{Index: i}
+			button.SubType, _ = b["sub_type"].(string)
+			button.Value, _ = b["value"].(string)
+			if index, ok := b["index"].(float64); ok {
+				button.Index = int(index)
+			}
+			message.Buttons = append(message.Buttons, button)
+		}
+	}
+
+	texts := append(append([]string{}, message.HeaderParams...), message.BodyParams...)
+	for _, button := range message.Buttons {
+		texts = append(texts, button.Value)
+	}
+	if err := h.conversation.CheckOutput(ctx, to, texts...); err != nil {
+		return nil, err
+	}
+
+	wamid, err := h.whatsapp.SendTemplate(to, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send template: %v", err)
+	}
+
+	// Keep the conversation history complete
+	if err := h.db.SaveOutboundMessage(to, message.PlainText(), wamid); err != nil {
+		log.Printf("Error saving outbound template message: %v", err)
+	}
+
+	result := map[string]interface{}{
+		"wamid":    wamid,
+		"to":       to,
+		"template": message.Name,
+	}
+
+	return result, nil
+}
+
+func (h *MCPHandler) handleWindowStatusTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	if h.whatsapp == nil {
+		return nil, fmt.Errorf("WhatsApp is not configured")
+	}
+
+	// Extract parameters
+	userID, ok := arguments["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, fmt.Errorf("user_id is required and must be a string")
+	}
+
+	state, err := h.whatsapp.WindowState(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get window state: %v", err)
+	}
+
+	result := map[string]interface{}{
+		"user_id":           state.UserID,
+		"open":              state.Open,
+		"remaining_seconds": int(state.Remaining(time.Now()).Seconds()),
+		"send_path":         "template",
+	}
+	if state.Open {
+		result["send_path"] = "free_form"
+	}
+	if !state.LastInboundAt.IsZero() {
+		result["last_inbound_at"] = state.LastInboundAt.Format(time.RFC3339)
+		result["expires_at"] = state.ExpiresAt.Format(time.RFC3339)
+	}
+
+	return result, nil
+}
+
+func (h *MCPHandler) handleSendInteractiveTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	if h.whatsapp == nil {
+		return nil, fmt.Errorf("WhatsApp is not configured")
+	}
+
+	// Extract parameters
+	to, err := recipient(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, _ := arguments["type"].(string)
+	header, _ := arguments["header"].(string)
+	body, _ := arguments["body"].(string)
+	footer, _ := arguments["footer"].(string)
+
+	var wamid, text string
+	// Every text the user sees passes the output guardrails
+	texts := []string{header, body, footer}
+
+	switch kind {
+	case "button":
+		message := whatsapp.ButtonMessage{Header: header, Body: body, Footer: footer}
+		buttons, _ := arguments["buttons"].([]interface{})
+		for _, item := range buttons {
+			b, _ := item.(map[string]interface{})
+			id, _ := b["id"].(string)
+			title, _ := b["title"].(string)
+			message.Buttons = append(message.Buttons, whatsapp.ReplyButton{ID: id, Title: title})
+			texts = append(texts, title)
+		}
+		if err := h.conversation.CheckOutput(ctx, to, texts...); err != nil {
+			return nil, err
+		}
+		text = message.PlainText()
+		wamid, err = h.whatsapp.SendButtons(to, message)
+
+	case "list":
+		message := whatsapp.ListMessage{Header: header, Body: body, Footer: footer}
+		message.ButtonText, _ = arguments["button_text"].(string)
+		texts = append(texts, message.ButtonText)
+		sections, _ := arguments["sections"].([]interface{})
+		for _, item := range sections {
+			sec, _ := item.(map[string]interface{})
+			section := whatsapp.ListSection{}
+			section.Title, _ = sec["title"].(string)
+			texts = append(texts, section.Title)
+			rows, _ := sec["rows"].([]interface{})
+			for _, rowItem := range rows {
+				r, _ := rowItem.(map[string]interface{})
+				row := whatsapp.ListRow{}
+				row.ID, _ = r["id"].(string)
+				row.Title, _ = r["title"].(string)
+				row.Description, _ = r["description"].(string)
+				section.Rows = append(section.Rows, row)
+				texts = append(texts, row.Title, row.Description)
+			}
+			message.Sections = append(message.Sections, section)
+		}
+		if err := h.conversation.CheckOutput(ctx, to, texts...); err != nil {
+			return nil, err
+		}
+		text = message.PlainText()
+		wamid, err = h.whatsapp.SendList(to, message)
+
+	default:
+		return nil, fmt.Errorf("type is required and must be \"button\" or \"list\"")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to send interactive message: %v", err)
+	}
+
+	// Keep the conversation history complete
+	if err := h.db.SaveOutboundMessage(to, text, wamid); err != nil {
+		log.Printf("Error saving outbound interactive message: %v", err)
+	}
+
+	result := map[string]interface{}{
+		"wamid": wamid,
+		"to":    to,
+		"type":  kind,
+	}
+
+	return result, nil
+}
+
+// recipient reads the "to" argument, a phone number in international format,
+// as the wa_id the contact's messages and window are stored under.
+func recipient(arguments map[string]interface{}) (string, error) {
+	to, ok := arguments["to"].(string)
+	if !ok || to == "" {
+		return "", fmt.Errorf("to is required and must be a string")
+	}
+	return whatsapp.NormalizeWAID(to)
+}
+
+// stringList reads an optional array-of-strings argument.
+func stringList(arguments map[string]interface{}, key string) ([]string, error) {
+	raw, ok := arguments[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", key)
+	}
+
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", key)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// toolCallResponse wraps the outcome of a tools/call request in a JSON-RPC response.
+func toolCallResponse(id interface{}, result interface{}, err error) map[string]interface{} {
+	if errors.Is(err, errToolNotFound) {
+		return map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      id,
+			"error": map[string]interface{}{
+				"code":    -32601,
+				"message": "Tool not found",
+			},
+		}
+	}
+
+	if err != nil {
+		return map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      id,
+			"error": map[string]interface{}{
+				"code":    -32603,
+				"message": err.Error(),
+			},
+		}
+	}
+
+	// Format as MCP tool result
+	toolResult := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": fmt.Sprintf("%v", result),
+			},
+		},
+	}
+
+	// If result is already JSON-serializable, convert to JSON string
+	if resultBytes, err := json.Marshal(result); err == nil {
+		toolResult["content"] = []map[string]interface{}{
+			{
+				"type": "text",
+				"text": string(resultBytes),
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"result":  toolResult,
+	}
+}
+
 func (h *MCPHandler) HandleHTTPRequest(w http.ResponseWriter, r *http.Request, server *mcp.Server) {
 	// Handle MCP requests over HTTP
 	var request map[string]interface{}
@@ -145,7 +585,8 @@ func (h *MCPHandler) HandleHTTPRequest(w http.ResponseWriter, r *http.Request, s
 		return
 	}
 
-	ctx := context.Background()
+	// Cancelled when the client disconnects
+	ctx := r.Context()
 	var response interface{}
 
 	switch method {
@@ -193,6 +634,12 @@ func (h *MCPHandler) HandleHTTPRequest(w http.ResponseWriter, r *http.Request, s
 			arguments = make(map[string]interface{})
 		}
 
+		// Stream the chat answer as progress notifications when the client asks for them
+		if token, ok := progressToken(params); ok && toolName == "chat" && acceptsEventStream(r) {
+			h.streamChatTool(ctx, w, request["id"], token, arguments)
+			return
+		}
+
 		var result interface{}
 		var err error
 
@@ -201,54 +648,35 @@ func (h *MCPHandler) HandleHTTPRequest(w http.ResponseWriter, r *http.Request, s
 			result, err = h.handleChatTool(ctx, arguments)
 		case "history":
 			result, err = h.handleHistoryTool(ctx, arguments)
+		case "message_status":
+			result, err = h.handleMessageStatusTool(ctx, arguments)
+		case "send_media":
+			result, err = h.handleSendMediaTool(ctx, arguments)
+		case "send_template":
+			result, err = h.handleSendTemplateTool(ctx, arguments)
+		case "window_status":
+			result, err = h.handleWindowStatusTool(ctx, arguments)
+		case "send_interactive":
+			result, err = h.handleSendInteractiveTool(ctx, arguments)
+		case "list_personas":
+			result, err = h.handleListPersonasTool(ctx, arguments)
+		case "create_persona":
+			result, err = h.handleCreatePersonaTool(ctx, arguments)
+		case "update_persona":
+			result, err = h.handleUpdatePersonaTool(ctx, arguments)
+		case "assign_persona":
+			result, err = h.handleAssignPersonaTool(ctx, arguments)
+		case "usage_report":
+			result, err = h.handleUsageReportTool(ctx, arguments)
+		case "update_user":
+			result, err = h.handleUpdateUserTool(ctx, arguments)
+		case "extract":
+			result, err = h.handleExtractTool(ctx, arguments)
 		default:
-			response = map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      request["id"],
-				"error": map[string]interface{}{
-					"code":    -32601,
-					"message": "Tool not found",
-				},
-			}
-			break
+			err = errToolNotFound
 		}
 
-		if err != nil {
-			response = map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      request["id"],
-				"error": map[string]interface{}{
-					"code":    -32603,
-					"message": err.Error(),
-				},
-			}
-		} else {
-			// Format as MCP tool result
-			toolResult := map[string]interface{}{
-				"content": []map[string]interface{}{
-					{
-						"type": "text",
-						"text": fmt.Sprintf("%v", result),
-					},
-				},
-			}
-			
-			// If result is already JSON-serializable, convert to JSON string
-			if resultBytes, err := json.Marshal(result); err == nil {
-				toolResult["content"] = []map[string]interface{}{
-					{
-						"type": "text",
-						"text": string(resultBytes),
-					},
-				}
-			}
-
-			response = map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      request["id"],
-				"result":  toolResult,
-			}
-		}
+		response = toolCallResponse(request["id"], result, err)
 
 	default:
 		response = map[string]interface{}{
@@ -269,7 +697,7 @@ func (h *MCPHandler) GetAvailableTools() []map[string]interface{} {
 	return []map[string]interface{}{
 		{
 			"name":        "chat",
-			"description": "Send a chat message and get AI response using Grok",
+			"description": "Send a chat message and get an AI response from the configured language model",
 			"inputSchema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -281,6 +709,16 @@ func (h *MCPHandler) GetAvailableTools() []map[string]interface{} {
 						"type":        "string",
 						"description": "The message content",
 					},
+					"timeout_ms": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum time to wait for the answer, in milliseconds; defaults to the server's GROK_TIMEOUT",
+						"minimum":     1,
+						"maximum":     maxChatTimeout.Milliseconds(),
+					},
+					"model": map[string]interface{}{
+						"type":        "string",
+						"description": "Model to answer with, as [provider:]model (e.g. grok:grok-2), in place of the one the routing rules pick",
+					},
 				},
 				"required": []string{"user_id", "message"},
 			},
@@ -304,57 +742,398 @@ func (h *MCPHandler) GetAvailableTools() []map[string]interface{} {
 				"required": []string{"user_id"},
 			},
 		},
+		{
+			"name":        "message_status",
+			"description": "Get the delivery status events (sent, delivered, read, failed) of an outbound WhatsApp message",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"wamid": map[string]interface{}{
+						"type":        "string",
+						"description": "WhatsApp message ID of the outbound message",
+					},
+				},
+				"required": []string{"wamid"},
+			},
+		},
+		{
+			"name":        "send_media",
+			"description": "Send an image, document, audio or video message to a WhatsApp contact",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"to": map[string]interface{}{
+						"type":        "string",
+						"description": "Recipient phone number in international format",
+					},
+					"type": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"image", "document", "audio", "video"},
+						"description": "Kind of media to send",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "File to upload, relative to the server's media directory",
+					},
+					"media_id": map[string]interface{}{
+						"type":        "string",
+						"description": "ID of media already uploaded to WhatsApp",
+					},
+					"link": map[string]interface{}{
+						"type":        "string",
+						"description": "Public HTTPS URL of the media",
+					},
+					"caption": map[string]interface{}{
+						"type":        "string",
+						"description": "Caption for images, videos and documents",
+					},
+					"filename": map[string]interface{}{
+						"type":        "string",
+						"description": "File name shown for documents",
+					},
+				},
+				"required": []string{"to", "type"},
+			},
+		},
+		{
+			"name":        "send_template",
+			"description": "Send an approved WhatsApp template message; works outside the 24-hour customer service window",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"to": map[string]interface{}{
+						"type":        "string",
+						"description": "Recipient phone number in international format",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the approved template",
+					},
+					"language_code": map[string]interface{}{
+						"type":        "string",
+						"description": "Template language code, e.g. en_US",
+					},
+					"header_params": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Values for the variables of a text header",
+					},
+					"header_media": map[string]interface{}{
+						"type":        "object",
+						"description": "Media for an image, document or video header",
+						"properties": map[string]interface{}{
+							"type":     map[string]interface{}{"type": "string", "enum": []string{"image", "document", "video"}},
+							"media_id": map[string]interface{}{"type": "string"},
+							"link":     map[string]interface{}{"type": "string"},
+							"filename": map[string]interface{}{"type": "string"},
+						},
+						"required": []string{"type"},
+					},
+					"body_params": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Values for the body variables, in order",
+					},
+					"buttons": map[string]interface{}{
+						"type":        "array",
+						"description": "Button variables: a quick_reply payload or a url suffix",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"sub_type": map[string]interface{}{"type": "string", "enum": []string{"quick_reply", "url"}},
+								"index":    map[string]interface{}{"type": "integer"},
+								"value":    map[string]interface{}{"type": "string"},
+							},
+							"required": []string{"sub_type", "value"},
+						},
+					},
+				},
+				"required": []string{"to", "name", "language_code"},
+			},
+		},
+		{
+			"name":        "window_status",
+			"description": "Check whether a contact's 24-hour customer service window is open, i.e. whether free-form messages can be sent or a template is required",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"user_id": map[string]interface{}{
+						"type":        "string",
+						"description": "WhatsApp ID (phone number) of the contact",
+					},
+				},
+				"required": []string{"user_id"},
+			},
+		},
+		{
+			"name":        "send_interactive",
+			"description": "Send a WhatsApp message with up to 3 reply buttons or a sectioned list; the chosen id comes back with the user's reply",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"to": map[string]interface{}{
+						"type":        "string",
+						"description": "Recipient phone number in international format",
+					},
+					"type": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"button", "list"},
+						"description": "Reply buttons or a list",
+					},
+					"header": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional header text",
+					},
+					"body": map[string]interface{}{
+						"type":        "string",
+						"description": "Message text",
+					},
+					"footer": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional footer text",
+					},
+					"buttons": map[string]interface{}{
+						"type":        "array",
+						"description": "For type button: 1 to 3 buttons, titles up to 20 characters",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"id":    map[string]interface{}{"type": "string"},
+								"title": map[string]interface{}{"type": "string"},
+							},
+							"required": []string{"id", "title"},
+						},
+					},
+					"button_text": map[string]interface{}{
+						"type":        "string",
+						"description": "For type list: label of the button that opens the list",
+					},
+					"sections": map[string]interface{}{
+						"type":        "array",
+						"description": "For type list: sections with up to 10 rows in total",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"title": map[string]interface{}{"type": "string"},
+								"rows": map[string]interface{}{
+									"type": "array",
+									"items": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"id":          map[string]interface{}{"type": "string"},
+											"title":       map[string]interface{}{"type": "string"},
+											"description": map[string]interface{}{"type": "string"},
+										},
+										"required": []string{"id", "title"},
+									},
+								},
+							},
+							"required": []string{"rows"},
+						},
+					},
+				},
+				"required": []string{"to", "type", "body"},
+			},
+		},
+		{
+			"name":        "list_personas",
+			"description": "List the personas that set the system prompt, temperature, reply length and model of AI replies",
+			"inputSchema": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			"name":        "create_persona",
+			"description": "Create a persona; one named \"default\" applies to everyone without an assignment",
+			"inputSchema": map[string]interface{}{
+				"type":       "object",
+				"properties": personaProperties(),
+				"required":   []string{"name", "system_prompt"},
+			},
+		},
+		{
+			"name":        "update_persona",
+			"description": "Change the settings of a persona; settings left out stay as they are",
+			"inputSchema": map[string]interface{}{
+				"type":       "object",
+				"properties": personaProperties(),
+				"required":   []string{"name"},
+			},
+		},
+		{
+			"name":        "assign_persona",
+			"description": "Make a contact, or every contact writing to a business phone number, use a persona. A contact's own persona wins over the number's",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"persona": map[string]interface{}{
+						"type":        "string",
+						"description": "Persona name, or empty to remove the assignment",
+					},
+					"user_id": map[string]interface{}{
+						"type":        "string",
+						"description": "WhatsApp ID (phone number) of the contact",
+					},
+					"phone_number_id": map[string]interface{}{
+						"type":        "string",
+						"description": "WhatsApp phone number ID of the business number",
+					},
+				},
+				"required": []string{"persona"},
+			},
+		},
+		{
+			"name":        "usage_report",
+			"description": "Report language model tokens and cost per user, per day or per model",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"group_by": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"user", "day", "model", "kind"},
+						"description": "How to group the report (default: day)",
+					},
+					"days": map[string]interface{}{
+						"type":        "integer",
+						"minimum":     1,
+						"maximum":     maxUsageDays,
+						"description": "Number of days to cover (default: 30)",
+					},
+					"user_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Only report this contact's usage",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of rows (default: 50)",
+					},
+				},
+			},
+		},
+		{
+			"name":        "update_user",
+			"description": "Set a contact's tier and tags, which model routing rules can match",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"user_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Unique identifier for the user",
+					},
+					"tier": map[string]interface{}{
+						"type":        "string",
+						"description": "Tier such as free or premium, or empty to remove it",
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Tags replacing the contact's current ones; empty to remove them all",
+					},
+				},
+				"required": []string{"user_id"},
+			},
+		},
+		{
+			"name":        "extract",
+			"description": "Extract structured data, such as an order number and intent, from a user's message as JSON validated against a schema",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"message": map[string]interface{}{
+						"type":        "string",
+						"description": "The message to extract data from",
+					},
+					"schema": map[string]interface{}{
+						"type":        "object",
+						"description": "JSON schema the extracted data must follow",
+					},
+					"user_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Sender of the message, used to route it to a model",
+					},
+					"instructions": map[string]interface{}{
+						"type":        "string",
+						"description": "Additional guidance for the model, e.g. how to normalise values",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"json_schema", "json_object"},
+						"description": "How the provider is asked for JSON (default: json_schema); use json_object for providers without schema support",
+					},
+					"model": map[string]interface{}{
+						"type":        "string",
+						"description": "Model to extract with, as [provider:]model, in place of the routed one",
+					},
+					"timeout_ms": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum time to wait for the answer, in milliseconds; defaults to the server's GROK_TIMEOUT",
+						"minimum":     1,
+						"maximum":     maxChatTimeout.Milliseconds(),
+					},
+				},
+				"required": []string{"message", "schema"},
+			},
+		},
 	}
 }
 
-// Generate response using Grok API or fallback
-func (h *MCPHandler) generateResponse(userMessage string, history []models.Message) (string, error) {
-	// Try Grok API first
-	if h.grokClient != nil {
-		response, err := h.grokClient.GenerateResponse(userMessage, history)
-		if err != nil {
-			log.Printf("Grok API error: %v", err)
-			// Fall through to fallback
-		} else {
-			return response, nil
-		}
-	}
-
-	// Fallback responses when Grok is unavailable
-	fallbackResponses := []string{
-		"I understand what you're saying. Let me help you with that.",
-		"That's an interesting point. Here's what I think about it.",
-		"I see what you mean. Let me provide some assistance.",
-		"Thanks for sharing that with me. I'm here to help.",
-		"I appreciate your message. How can I assist you further?",
-	}
-
-	// Simple hash-based response selection for consistency
-	hash := 0
-	for _, char := range userMessage {
-		hash += int(char)
+// personaProperties is the input schema shared by create_persona and update_persona.
+func personaProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"name": map[string]interface{}{
+			"type":        "string",
+			"description": "Persona name, e.g. support or sales",
+		},
+		"system_prompt": map[string]interface{}{
+			"type":        "string",
+			"description": "Instructions the model follows in every reply",
+		},
+		"temperature": map[string]interface{}{
+			"type":        []string{"number", "null"},
+			"minimum":     0,
+			"maximum":     2,
+			"description": "Sampling temperature (default 0.7)",
+		},
+		"max_tokens": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     0,
+			"description": "Maximum reply length in tokens (0 for LLM_REPLY_TOKENS)",
+		},
+		"model": map[string]interface{}{
+			"type":        "string",
+			"description": "Model to use instead of the provider's default",
+		},
 	}
-
-	responseIndex := hash % len(fallbackResponses)
-	return fallbackResponses[responseIndex], nil
 }
 
 func (h *MCPHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	grokStatus := "configured"
-	if h.grokClient == nil {
-		grokStatus = "not configured"
+	status, providerName, model := "healthy", "not configured", ""
+	var circuit interface{}
+	if provider := h.conversation.Provider(); provider != nil {
+		providerName, model = provider.Name(), provider.Model()
+		// Replies are fallbacks while the breaker keeps the provider out
+		if breaker, ok := provider.(*llm.Breaker); ok {
+			circuit = breaker.Status()
+			if breaker.State() != llm.BreakerClosed {
+				status = "degraded"
+			}
+		}
 	}
 
 	response := map[string]interface{}{
-		"status":     "healthy",
+		"status":     status,
 		"service":    "WhatsApp MCP Server",
 		"timestamp":  time.Now().Format(time.RFC3339),
 		"version":    "1.0.0",
 		"sdk":        "official-go-sdk",
-		"grok_api":   grokStatus,
-		"model":      h.config.GrokModel,
+		"llm":        providerName,
+		"model":      model,
 		"repository": "github.com/sinhaparth5/whatstyle-mcp",
 	}
+	if circuit != nil {
+		response["circuit_breaker"] = circuit
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
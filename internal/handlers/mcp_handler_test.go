@@ -2,11 +2,21 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/conversation"
 	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
-	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/whatsapp"
 )
 
 func TestNewMCPHandler(t *testing.T) {
@@ -202,4 +212,319 @@ func TestHandleHistoryTool(t *testing.T) {
 			t.Error("Expected error for missing user_id")
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestChatContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout interface{}
+		wantErr bool
+	}{
+		{"Default", nil, false},
+		{"Valid", float64(1500), false},
+		{"Zero", float64(0), true},
+		{"TooLong", float64(maxChatTimeout.Milliseconds() + 1), true},
+		{"NotANumber", "1500", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arguments := map[string]interface{}{}
+			if tt.timeout != nil {
+				arguments["timeout_ms"] = tt.timeout
+			}
+
+			ctx, cancel, err := chatContext(context.Background(), arguments)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error for invalid timeout_ms")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			defer cancel()
+
+			_, hasDeadline := ctx.Deadline()
+			if hasDeadline != (tt.timeout != nil) {
+				t.Errorf("Expected deadline %v, got %v", tt.timeout != nil, hasDeadline)
+			}
+		})
+	}
+}
+
+func TestPersonaTools(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	handler := NewMCPHandler(db, &configs.Config{}, &mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+	ctx := context.Background()
+
+	_, err = handler.handleCreatePersonaTool(ctx, map[string]interface{}{
+		"name":          "support",
+		"system_prompt": "You are patient support.",
+		"temperature":   float64(0.3),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	t.Run("Duplicate", func(t *testing.T) {
+		_, err := handler.handleCreatePersonaTool(ctx, map[string]interface{}{"name": "support", "system_prompt": "Again"})
+		if !errors.Is(err, database.ErrPersonaExists) {
+			t.Errorf("Expected ErrPersonaExists, got: %v", err)
+		}
+	})
+
+	t.Run("PartialUpdate", func(t *testing.T) {
+		result, err := handler.handleUpdatePersonaTool(ctx, map[string]interface{}{"name": "support", "max_tokens": float64(200)})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		persona := result.(map[string]interface{})
+		if persona["system_prompt"] != "You are patient support." || persona["temperature"] != 0.3 || persona["max_tokens"] != 200 {
+			t.Errorf("Expected only max_tokens to change, got %v", persona)
+		}
+	})
+
+	t.Run("Assign", func(t *testing.T) {
+		arguments := map[string]interface{}{"persona": "support", "phone_number_id": "123456"}
+		if _, err := handler.handleAssignPersonaTool(ctx, arguments); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		persona, err := db.ResolvePersona("15550001111", "123456")
+		if err != nil || persona == nil || persona.Name != "support" {
+			t.Errorf("Expected the number to use support, got %v, %v", persona, err)
+		}
+	})
+
+	t.Run("AssignNeedsOneTarget", func(t *testing.T) {
+		arguments := map[string]interface{}{"persona": "support", "user_id": "15550001111", "phone_number_id": "123456"}
+		if _, err := handler.handleAssignPersonaTool(ctx, arguments); err == nil {
+			t.Error("Expected error for two targets")
+		}
+	})
+
+	t.Run("AssignUnknown", func(t *testing.T) {
+		arguments := map[string]interface{}{"persona": "sales", "user_id": "15550001111"}
+		if _, err := handler.handleAssignPersonaTool(ctx, arguments); !errors.Is(err, database.ErrPersonaNotFound) {
+			t.Errorf("Expected ErrPersonaNotFound, got: %v", err)
+		}
+	})
+}
+
+func TestUsageReportTool(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	handler := NewMCPHandler(db, &configs.Config{}, &mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+
+	cost := 0.01
+	for _, usage := range []models.MessageUsage{
+		{Model: "grok-beta", PromptTokens: 900, CompletionTokens: 100, TotalTokens: 1000, CostUSD: &cost},
+		{Model: "llama3.2", PromptTokens: 400, CompletionTokens: 100, TotalTokens: 500},
+	} {
+		if err := db.SaveAssistantMessage("15550001111", "Hi", &usage); err != nil {
+			t.Fatalf("Failed to save message: %v", err)
+		}
+	}
+
+	result, err := handler.handleUsageReportTool(context.Background(), map[string]interface{}{"group_by": "model"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	report := result.(map[string]interface{})
+	rows := report["rows"].([]models.UsageSummary)
+	if len(rows) != 2 || rows[0].Key != "grok-beta" || rows[1].UnpricedMessages != 1 {
+		t.Errorf("Expected grok-beta first and llama3.2 unpriced, got %+v", rows)
+	}
+	total := report["total"].(map[string]interface{})
+	if total["total_tokens"] != 1500 || total["cost_usd"] != 0.01 {
+		t.Errorf("Unexpected total: %v", total)
+	}
+
+	if _, err := handler.handleUsageReportTool(context.Background(), map[string]interface{}{"group_by": "week"}); err == nil {
+		t.Error("Expected error for unknown grouping")
+	}
+}
+
+func TestUpdateUserTool(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	handler := NewMCPHandler(db, &configs.Config{}, &mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		wantTier  string
+		wantTags  []string
+		wantErr   bool
+	}{
+		{"Both", map[string]interface{}{"user_id": "15550001111", "tier": "Premium", "tags": []interface{}{"vip", " Beta ", "vip"}}, "premium", []string{"vip", "beta"}, false},
+		{"TierOnly", map[string]interface{}{"user_id": "15550001111", "tier": "free"}, "free", []string{"vip", "beta"}, false},
+		{"ClearTags", map[string]interface{}{"user_id": "15550001111", "tags": []interface{}{}}, "free", []string{}, false},
+		{"Nothing", map[string]interface{}{"user_id": "15550001111"}, "", nil, true},
+		{"BadTags", map[string]interface{}{"user_id": "15550001111", "tags": "vip"}, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := handler.handleUpdateUserTool(ctx, tt.arguments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			user := result.(map[string]interface{})
+			if user["tier"] != tt.wantTier || !reflect.DeepEqual(user["tags"], tt.wantTags) {
+				t.Errorf("Expected tier %q and tags %v, got %v", tt.wantTier, tt.wantTags, user)
+			}
+		})
+	}
+}
+
+func TestChatModel(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	handler := NewMCPHandler(db, &configs.Config{LLMProvider: "none"}, &mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+
+	arguments := map[string]interface{}{"user_id": "test-user", "message": "Hello", "model": "grok:grok-2"}
+	if _, err := handler.handleChatTool(context.Background(), arguments); err == nil {
+		t.Error("Expected error for a model without a configured provider")
+	}
+
+	arguments["model"] = float64(2)
+	if _, err := handler.handleChatTool(context.Background(), arguments); err == nil {
+		t.Error("Expected error for a model that is not a string")
+	}
+}
+
+func TestExtractTool(t *testing.T) {
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	handler := NewMCPHandler(db, &configs.Config{LLMProvider: "none"}, &mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+	handler.Conversation().SetProvider(llm.NewScripted(`{"intent":"cancel"}`))
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"intent": map[string]interface{}{"enum": []interface{}{"track", "cancel"}}},
+		"required":   []interface{}{"intent"},
+	}
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		want      string
+		wantErr   bool
+	}{
+		{"Valid", map[string]interface{}{"message": "Please cancel my order", "schema": schema}, `{"intent":"cancel"}`, false},
+		{"NoMessage", map[string]interface{}{"schema": schema}, "", true},
+		{"NoSchema", map[string]interface{}{"message": "Please cancel my order"}, "", true},
+		{"SchemaString", map[string]interface{}{"message": "Please cancel my order", "schema": `{"type":"object"}`}, "", true},
+		{"BadFormat", map[string]interface{}{"message": "Please cancel my order", "schema": schema, "format": "xml"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := handler.handleExtractTool(context.Background(), tt.arguments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			extraction := result.(map[string]interface{})
+			if data, _ := extraction["data"].(json.RawMessage); string(data) != tt.want || extraction["attempts"] != 1 {
+				t.Errorf("Expected %s in one attempt, got %v", tt.want, extraction)
+			}
+		})
+	}
+}
+
+func TestSendToolsGuardrails(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.Write([]byte(`{"messages":[{"id":"wamid.out"}]}`))
+	}))
+	defer server.Close()
+
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{
+		LLMProvider:           "none",
+		GuardrailBlockedTerms: "casino",
+		GuardrailPII:          "card",
+		WhatsAppAccessToken:   "token",
+		WhatsAppPhoneNumberID: "PHONE_ID",
+		WhatsAppAPIURL:        server.URL,
+	}
+	handler := NewMCPHandler(db, config, &mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+	handler.SetWhatsApp(whatsapp.NewHandler(config, db, handler.Conversation()))
+
+	// Open the customer service window
+	if err := db.RecordInboundAt("15550001111", time.Now()); err != nil {
+		t.Fatalf("Failed to record inbound message: %v", err)
+	}
+
+	button := map[string]interface{}{"id": "yes", "title": "Casino"}
+	row := map[string]interface{}{"id": "card", "title": "Card", "description": "Ending 4111 1111 1111 1111"}
+	tests := []struct {
+		name    string
+		tool    func(context.Context, map[string]interface{}) (interface{}, error)
+		args    map[string]interface{}
+		wantErr bool
+	}{
+		{"CleanCaption", handler.handleSendMediaTool, map[string]interface{}{"type": "image", "link": "https://example.com/a.jpg", "caption": "Your receipt"}, false},
+		{"BlockedCaption", handler.handleSendMediaTool, map[string]interface{}{"type": "image", "link": "https://example.com/a.jpg", "caption": "Casino night"}, true},
+		{"BlockedTemplateParam", handler.handleSendTemplateTool, map[string]interface{}{"name": "promo", "language_code": "en", "body_params": []interface{}{"casino"}}, true},
+		{"BlockedButton", handler.handleSendInteractiveTool, map[string]interface{}{"type": "button", "body": "Join?", "buttons": []interface{}{button}}, true},
+		{"RedactedListRow", handler.handleSendInteractiveTool, map[string]interface{}{"type": "list", "body": "Pay with", "button_text": "Choose",
+			"sections": []interface{}{map[string]interface{}{"title": "Saved", "rows": []interface{}{row}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["to"] = "15550001111"
+			_, err := tt.tool(context.Background(), tt.args)
+			if tt.wantErr != errors.Is(err, conversation.ErrBlocked) {
+				t.Errorf("Expected blocked %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if sent != 1 {
+		t.Errorf("Expected only the clean message sent, got %d requests", sent)
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+func (h *MCPHandler) handleListPersonasTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	personas, err := h.db.ListPersonas()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personas: %v", err)
+	}
+
+	items := make([]map[string]interface{}, 0, len(personas))
+	for _, persona := range personas {
+		items = append(items, personaResult(persona))
+	}
+
+	result := map[string]interface{}{
+		"personas": items,
+		"count":    len(items),
+	}
+
+	return result, nil
+}
+
+func (h *MCPHandler) handleCreatePersonaTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	// Extract parameters
+	name, ok := arguments["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("name is required and must be a string")
+	}
+
+	persona := models.Persona{Name: name}
+	if err := personaArguments(&persona, arguments); err != nil {
+		return nil, err
+	}
+
+	if err := h.db.CreatePersona(&persona); err != nil {
+		return nil, fmt.Errorf("failed to create persona: %w", err)
+	}
+
+	return personaResult(persona), nil
+}
+
+func (h *MCPHandler) handleUpdatePersonaTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	// Extract parameters
+	name, ok := arguments["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("name is required and must be a string")
+	}
+
+	// Only the settings given change
+	persona, err := h.db.GetPersona(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := personaArguments(persona, arguments); err != nil {
+		return nil, err
+	}
+
+	if err := h.db.UpdatePersona(*persona); err != nil {
+		return nil, fmt.Errorf("failed to update persona: %w", err)
+	}
+
+	updated, err := h.db.GetPersona(name)
+	if err != nil {
+		return nil, err
+	}
+	return personaResult(*updated), nil
+}
+
+func (h *MCPHandler) handleAssignPersonaTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	// Extract parameters
+	name, ok := arguments["persona"].(string)
+	if !ok {
+		return nil, fmt.Errorf("persona is required and must be a string (empty to remove the assignment)")
+	}
+
+	userID, _ := arguments["user_id"].(string)
+	phoneNumberID, _ := arguments["phone_number_id"].(string)
+	if (userID == "") == (phoneNumberID == "") {
+		return nil, fmt.Errorf("exactly one of user_id and phone_number_id is required")
+	}
+
+	result := map[string]interface{}{
+		"persona": name,
+	}
+
+	var err error
+	if userID != "" {
+		err = h.db.AssignUserPersona(userID, name)
+		result["user_id"] = userID
+	} else {
+		err = h.db.AssignTenantPersona(phoneNumberID, name)
+		result["phone_number_id"] = phoneNumberID
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign persona: %w", err)
+	}
+
+	return result, nil
+}
+
+// personaArguments applies the optional persona settings in arguments.
+func personaArguments(persona *models.Persona, arguments map[string]interface{}) error {
+	if raw, ok := arguments["system_prompt"]; ok {
+		prompt, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("system_prompt must be a string")
+		}
+		persona.SystemPrompt = prompt
+	}
+
+	if raw, ok := arguments["temperature"]; ok {
+		if raw == nil {
+			// null returns to the default temperature
+			persona.Temperature = nil
+		} else {
+			temperature, ok := raw.(float64)
+			if !ok {
+				return fmt.Errorf("temperature must be a number")
+			}
+			persona.Temperature = &temperature
+		}
+	}
+
+	if raw, ok := arguments["max_tokens"]; ok {
+		maxTokens, ok := raw.(float64)
+		if !ok || maxTokens != float64(int(maxTokens)) {
+			return fmt.Errorf("max_tokens must be an integer")
+		}
+		persona.MaxTokens = int(maxTokens)
+	}
+
+	if raw, ok := arguments["model"]; ok {
+		model, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("model must be a string")
+		}
+		persona.Model = model
+	}
+
+	return nil
+}
+
+// personaResult describes a persona in a tool result.
+func personaResult(persona models.Persona) map[string]interface{} {
+	result := map[string]interface{}{
+		"name":          persona.Name,
+		"system_prompt": persona.SystemPrompt,
+		"max_tokens":    persona.MaxTokens,
+		"model":         persona.Model,
+	}
+	if persona.Temperature != nil {
+		result["temperature"] = *persona.Temperature
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// progressToken returns the progress token a client attached to a request in
+// params._meta. Tokens are strings or numbers.
+func progressToken(params map[string]interface{}) (interface{}, bool) {
+	meta, ok := params["_meta"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	switch token := meta["progressToken"].(type) {
+	case string:
+		return token, token != ""
+	case float64:
+		return token, true
+	default:
+		return nil, false
+	}
+}
+
+// acceptsEventStream reports whether the client can read a server-sent event response.
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.TrimSpace(mediaType) == "text/event-stream" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// eventStream writes JSON-RPC messages as server-sent events.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	return &eventStream{w: w, flusher: flusher}, true
+}
+
+func (s *eventStream) send(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(s.w, "event: message\ndata: %s\n\n", data); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// streamChatTool answers a chat tool call over server-sent events. Each piece
+// of the reply is sent as a notifications/progress message carrying the text
+// in "message", followed by the regular tool result.
+func (h *MCPHandler) streamChatTool(ctx context.Context, w http.ResponseWriter, id, token interface{}, arguments map[string]interface{}) {
+	userID, message, err := chatArguments(arguments)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toolCallResponse(id, nil, err))
+		return
+	}
+
+	ctx, cancel, err := chatContext(ctx, arguments)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toolCallResponse(id, nil, err))
+		return
+	}
+	defer cancel()
+
+	if ctx, err = h.chatModel(ctx, arguments); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toolCallResponse(id, nil, err))
+		return
+	}
+
+	stream, ok := newEventStream(w)
+	if !ok {
+		// The connection cannot be flushed, answer in one piece
+		result, err := h.handleChatTool(ctx, arguments)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toolCallResponse(id, result, err))
+		return
+	}
+
+	progress := 0
+	response, err := h.conversation.ReplyStream(ctx, userID, message, func(delta string) {
+		progress++
+		notification := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "notifications/progress",
+			"params": map[string]interface{}{
+				"progressToken": token,
+				"progress":      progress,
+				"message":       delta,
+			},
+		}
+		if err := stream.send(notification); err != nil {
+			log.Printf("Error streaming chat progress: %v", err)
+		}
+	})
+
+	var result interface{}
+	if err == nil {
+		result = map[string]interface{}{
+			"response": response,
+			"user_id":  userID,
+		}
+	}
+
+	if err := stream.send(toolCallResponse(id, result, err)); err != nil {
+		log.Printf("Error streaming chat result: %v", err)
+	}
+}
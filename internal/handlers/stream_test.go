@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+)
+
+func TestStreamChatTool(t *testing.T) {
+	// Fake Grok API streaming two pieces
+	grokServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, piece := range []string{"Hello", " there"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", piece)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer grokServer.Close()
+
+	// Create test database
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{
+		GrokAPIKey:  "test-key",
+		GrokModel:   "grok-beta",
+		GrokBaseURL: grokServer.URL,
+	}
+	handler := NewMCPHandler(db, config, &mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+
+	body := `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"chat",
+		"arguments":{"user_id":"test-user","message":"Hi"},"_meta":{"progressToken":"tok-1"}}}`
+
+	t.Run("StreamsProgress", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/mcp", strings.NewReader(body))
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		rec := httptest.NewRecorder()
+
+		handler.HandleHTTPRequest(rec, req, nil)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Expected text/event-stream, got %s", ct)
+		}
+
+		var events []map[string]interface{}
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event map[string]interface{}
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("Invalid event %q: %v", data, err)
+			}
+			events = append(events, event)
+		}
+
+		if len(events) != 3 {
+			t.Fatalf("Expected 2 progress notifications and a result, got %d events", len(events))
+		}
+		for i, piece := range []string{"Hello", " there"} {
+			params, _ := events[i]["params"].(map[string]interface{})
+			if events[i]["method"] != "notifications/progress" || params["progressToken"] != "tok-1" || params["message"] != piece {
+				t.Errorf("Unexpected progress notification %d: %v", i, events[i])
+			}
+		}
+		if events[2]["id"] != float64(7) || events[2]["result"] == nil {
+			t.Errorf("Expected final tool result, got %v", events[2])
+		}
+
+		history, err := db.GetChatHistory("test-user", 10)
+		if err != nil {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+		if len(history) != 2 || history[1].Content != "Hello there" {
+			t.Errorf("Expected streamed reply to be stored, got %+v", history)
+		}
+	})
+
+	t.Run("PlainJSONWithoutEventStream", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/mcp", strings.NewReader(body))
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+
+		handler.HandleHTTPRequest(rec, req, nil)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected application/json, got %s", ct)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+)
+
+// maxUsageDays caps the days argument of the usage_report tool.
+const maxUsageDays = 366
+
+func (h *MCPHandler) handleUsageReportTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	// Extract parameters
+	groupBy := database.UsageByDay
+	if g, ok := arguments["group_by"].(string); ok && g != "" {
+		groupBy = g
+	}
+
+	days := 30 // default
+	if d, ok := arguments["days"].(float64); ok {
+		days = int(d)
+	}
+	if days < 1 || days > maxUsageDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", maxUsageDays)
+	}
+
+	limit := 50 // default
+	if l, ok := arguments["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	userID, _ := arguments["user_id"].(string)
+
+	rows, err := h.db.GetUsage(groupBy, days, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	// Totals come from a report by model, which every row falls into once
+	totals, err := h.db.GetUsage(database.UsageByModel, days, userID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	total := map[string]interface{}{}
+	var messages, totalTokens, unpriced, estimated int
+	var cost float64
+	for _, t := range totals {
+		messages += t.Messages
+		totalTokens += t.TotalTokens
+		cost += t.CostUSD
+		unpriced += t.UnpricedMessages
+		estimated += t.EstimatedMessages
+	}
+	total["messages"] = messages
+	total["total_tokens"] = totalTokens
+	total["cost_usd"] = cost
+	total["unpriced_messages"] = unpriced
+	total["estimated_messages"] = estimated
+
+	result := map[string]interface{}{
+		"group_by": groupBy,
+		"days":     days,
+		"rows":     rows,
+		"total":    total,
+	}
+	if userID != "" {
+		result["user_id"] = userID
+	}
+
+	return result, nil
+}
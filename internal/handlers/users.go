@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+)
+
+func (h *MCPHandler) handleUpdateUserTool(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+	// Extract parameters
+	userID, ok := arguments["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, fmt.Errorf("user_id is required and must be a string")
+	}
+
+	rawTier, hasTier := arguments["tier"]
+	rawTags, hasTags := arguments["tags"]
+	if !hasTier && !hasTags {
+		return nil, fmt.Errorf("at least one of tier and tags is required")
+	}
+
+	if hasTier {
+		tier, ok := rawTier.(string)
+		if !ok {
+			return nil, fmt.Errorf("tier must be a string")
+		}
+		if err := h.db.SetUserTier(userID, tier); err != nil {
+			return nil, err
+		}
+	}
+
+	if hasTags {
+		list, ok := rawTags.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("tags must be an array of strings")
+		}
+		tags := make([]string, 0, len(list))
+		for _, raw := range list {
+			tag, ok := raw.(string)
+			if !ok {
+				return nil, fmt.Errorf("tags must be an array of strings")
+			}
+			tags = append(tags, tag)
+		}
+		if err := h.db.SetUserTags(userID, tags); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := h.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := user.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return map[string]interface{}{
+		"user_id": userID,
+		"tier":    user.Tier,
+		"tags":    tags,
+	}, nil
+}
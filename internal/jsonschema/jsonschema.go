@@ -0,0 +1,346 @@
+// Package jsonschema validates JSON values against the subset of JSON Schema
+// that describes extraction results: type, properties, required,
+// additionalProperties, items, enum, const, anyOf and the numeric, string
+// length, pattern and array length bounds. Other keywords are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a parsed JSON schema.
+type Schema struct {
+	types                []string
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	noAdditional         bool
+	items                *Schema
+	enum                 []interface{}
+	constant             *interface{}
+	anyOf                []*Schema
+	minimum, maximum     *float64
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minItems, maxItems   *int
+}
+
+// rawSchema is the JSON form of a Schema.
+type rawSchema struct {
+	Type                 json.RawMessage            `json:"type"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	Enum                 []interface{}              `json:"enum"`
+	Const                json.RawMessage            `json:"const"`
+	AnyOf                []json.RawMessage          `json:"anyOf"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              string                     `json:"pattern"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+}
+
+var knownTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// Parse parses a schema, which must be a JSON object.
+func Parse(data []byte) (*Schema, error) {
+	return parse(data, "$")
+}
+
+func parse(data []byte, path string) (*Schema, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, fmt.Errorf("%s: schema must be an object", path)
+	}
+	var raw rawSchema
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	s := &Schema{
+		required:  raw.Required,
+		enum:      raw.Enum,
+		minimum:   raw.Minimum,
+		maximum:   raw.Maximum,
+		minLength: raw.MinLength,
+		maxLength: raw.MaxLength,
+		minItems:  raw.MinItems,
+		maxItems:  raw.MaxItems,
+	}
+
+	if len(raw.Type) > 0 {
+		var one string
+		if err := json.Unmarshal(raw.Type, &one); err == nil {
+			s.types = []string{one}
+		} else if err := json.Unmarshal(raw.Type, &s.types); err != nil {
+			return nil, fmt.Errorf("%s: type must be a string or an array of strings", path)
+		}
+		for _, t := range s.types {
+			if !knownTypes[t] {
+				return nil, fmt.Errorf("%s: unknown type %q", path, t)
+			}
+		}
+	}
+
+	if len(raw.Properties) > 0 {
+		s.properties = make(map[string]*Schema, len(raw.Properties))
+		for name, property := range raw.Properties {
+			var err error
+			if s.properties[name], err = parse(property, path+"."+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch additional := bytes.TrimSpace(raw.AdditionalProperties); {
+	case len(additional) == 0, string(additional) == "true":
+	case string(additional) == "false":
+		s.noAdditional = true
+	default:
+		var err error
+		if s.additionalProperties, err = parse(additional, path+".additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(raw.Items) > 0 {
+		var err error
+		if s.items, err = parse(raw.Items, path+"[]"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(raw.Const) > 0 {
+		var constant interface{}
+		if err := json.Unmarshal(raw.Const, &constant); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		s.constant = &constant
+	}
+
+	for i, option := range raw.AnyOf {
+		schema, err := parse(option, fmt.Sprintf("%s.anyOf[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		s.anyOf = append(s.anyOf, schema)
+	}
+
+	if raw.Pattern != "" {
+		var err error
+		if s.pattern, err = regexp.Compile(raw.Pattern); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Error is a value that does not follow the schema at Path, such as
+// "$.items[2].sku".
+type Error struct {
+	Path    string
+	Message string
+}
+
+func (e Error) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Errors are all the ways a value does not follow a schema.
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks the JSON document data against the schema and returns
+// Errors listing every violation, or a syntax error if data is not JSON.
+func (s *Schema) Validate(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if errs := s.validate(value, "$"); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *Schema) validate(value interface{}, path string) Errors {
+	if len(s.types) > 0 && !s.hasType(value) {
+		return Errors{{path, fmt.Sprintf("expected %s, got %s", strings.Join(s.types, " or "), typeOf(value))}}
+	}
+
+	var errs Errors
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, Error{path, fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.enum) > 0 && !contains(s.enum, value) {
+		fail("must be one of %s", jsonList(s.enum))
+	}
+	if s.constant != nil && !reflect.DeepEqual(*s.constant, value) {
+		fail("must be %s", jsonList([]interface{}{*s.constant}))
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, option := range s.anyOf {
+			if len(option.validate(value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("does not match any of the allowed schemas")
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, Error{path + "." + name, "is required"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.properties[name]; ok {
+				errs = append(errs, property.validate(v[name], path+"."+name)...)
+			} else if s.noAdditional {
+				errs = append(errs, Error{path + "." + name, "is not allowed"})
+			} else if s.additionalProperties != nil {
+				errs = append(errs, s.additionalProperties.validate(v[name], path+"."+name)...)
+			}
+		}
+
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				errs = append(errs, s.items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %s", s.pattern)
+		}
+
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("must be at least %g", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("must be at most %g", *s.maximum)
+		}
+	}
+
+	return errs
+}
+
+func (s *Schema) hasType(value interface{}) bool {
+	for _, t := range s.types {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case nil:
+			if t == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeOf names the JSON type of a decoded value.
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+func contains(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonList formats values as JSON separated by commas.
+func jsonList(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,83 @@
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"properties": {
+		"order_number": {"type": "string", "pattern": "^[A-Z]{2}-\\d{4,}$"},
+		"intent": {"enum": ["track", "cancel", "return", "other"]},
+		"quantity": {"type": "integer", "minimum": 1, "maximum": 99},
+		"note": {"type": ["string", "null"], "maxLength": 10},
+		"items": {"type": "array", "maxItems": 2, "items": {"type": "object", "required": ["sku"]}},
+		"channel": {"anyOf": [{"const": "whatsapp"}, {"type": "null"}]}
+	},
+	"required": ["order_number", "intent"],
+	"additionalProperties": false
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(orderSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{"Valid", `{"order_number": "AB-12345", "intent": "track", "quantity": 2, "note": null, "items": [{"sku": "x"}], "channel": "whatsapp"}`, ""},
+		{"Missing", `{"intent": "track"}`, "$.order_number: is required"},
+		{"Enum", `{"order_number": "AB-1234", "intent": "complain"}`, `$.intent: must be one of "track", "cancel", "return", "other"`},
+		{"Pattern", `{"order_number": "12345", "intent": "track"}`, `$.order_number: must match ^[A-Z]{2}-\d{4,}$`},
+		{"Integer", `{"order_number": "AB-1234", "intent": "track", "quantity": 1.5}`, "$.quantity: expected integer, got number"},
+		{"Maximum", `{"order_number": "AB-1234", "intent": "track", "quantity": 100}`, "$.quantity: must be at most 99"},
+		{"MaxLength", `{"order_number": "AB-1234", "intent": "track", "note": "far too long a note"}`, "$.note: must be at most 10 characters"},
+		{"Items", `{"order_number": "AB-1234", "intent": "track", "items": [{}, {"sku": "y"}, {"sku": "z"}]}`, "$.items: must have at most 2 items; $.items[0].sku: is required"},
+		{"AnyOf", `{"order_number": "AB-1234", "intent": "track", "channel": "email"}`, "$.channel: does not match any of the allowed schemas"},
+		{"Additional", `{"order_number": "AB-1234", "intent": "track", "extra": 1}`, "$.extra: is not allowed"},
+		{"Type", `["AB-1234"]`, "$: expected object, got array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.document))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			var errs Errors
+			if !errors.As(err, &errs) || err.Error() != tt.want {
+				t.Errorf("Expected %q, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	if err := schema.Validate([]byte(`{"order_number": `)); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		`"object"`,
+		`{"type": "thing"}`,
+		`{"type": 3}`,
+		`{"properties": {"a": true}}`,
+		`{"pattern": "("}`,
+	}
+
+	for _, schema := range tests {
+		t.Run(schema, func(t *testing.T) {
+			if _, err := Parse([]byte(schema)); err == nil {
+				t.Errorf("Expected an error for %s", schema)
+			}
+		})
+	}
+}
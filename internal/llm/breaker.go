@@ -0,0 +1,318 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/grok"
+)
+
+// ErrCircuitOpen is returned without calling the backend while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed passes every call to the backend.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects every call until the cooldown has passed.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets one trial call through, which closes the breaker
+	// if it succeeds and opens it again if it fails.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// Breaker settings used when BreakerSettings leaves them unset.
+const (
+	defaultBreakerFailureRate = 0.5
+	defaultBreakerWindow      = 20
+	defaultBreakerMinCalls    = 5
+	defaultBreakerCooldown    = 30 * time.Second
+)
+
+// BreakerSettings configure a Breaker.
+type BreakerSettings struct {
+	// FailureRate is the share of recent calls, from 0 to 1, that opens the breaker when they fail.
+	FailureRate float64
+	// Window is how many of the most recent calls the failure rate covers.
+	Window int
+	// MinCalls is the fewest calls in the window that can open the breaker.
+	MinCalls int
+	// Cooldown is how long the breaker stays open before a trial call.
+	Cooldown time.Duration
+}
+
+// Breaker is a Provider that stops calling a failing backend. Once too many
+// recent calls failed it opens and fails calls with ErrCircuitOpen at once,
+// so callers fall back without waiting for timeouts; after the cooldown a
+// single trial call decides whether it closes again. Timeouts, rate limits,
+// server and transport errors count as failures, a deadline only if it was
+// set with WithTimeout. Calls abandoned because the caller cancelled them or
+// its own deadline passed are not counted, and requests the backend
+// rejected, such as for an unknown model or an oversized prompt, count as
+// answered: they say nothing about its health.
+type Breaker struct {
+	provider Provider
+	settings BreakerSettings
+	now      func() time.Time
+
+	mu    sync.Mutex
+	state BreakerState
+	// outcomes is a ring of the results of the last Window calls, true for failures
+	outcomes  []bool
+	next      int
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	opens     int
+	rejected  int
+	lastError string
+}
+
+// timeoutKey marks the deadline set by WithTimeout in a context.
+type timeoutKey struct{}
+
+// WithTimeout returns a copy of ctx that ends after timeout, the time the
+// backend is given to answer. A Breaker counts calls cut off by it as
+// failures, unlike those cut off by a deadline the caller chose.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	deadline, _ := ctx.Deadline()
+	return context.WithValue(ctx, timeoutKey{}, deadline), cancel
+}
+
+// timedOut reports whether ctx ended at a deadline set by WithTimeout.
+func timedOut(ctx context.Context) bool {
+	set, ok := ctx.Value(timeoutKey{}).(time.Time)
+	deadline, _ := ctx.Deadline()
+	return ok && deadline.Equal(set) && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// NewBreaker wraps provider in a circuit breaker, starting closed.
+func NewBreaker(provider Provider, settings BreakerSettings) *Breaker {
+	if settings.FailureRate <= 0 || settings.FailureRate > 1 {
+		settings.FailureRate = defaultBreakerFailureRate
+	}
+	if settings.Window <= 0 {
+		settings.Window = defaultBreakerWindow
+	}
+	if settings.MinCalls <= 0 {
+		settings.MinCalls = defaultBreakerMinCalls
+	}
+	if settings.MinCalls > settings.Window {
+		settings.MinCalls = settings.Window
+	}
+	if settings.Cooldown <= 0 {
+		settings.Cooldown = defaultBreakerCooldown
+	}
+
+	return &Breaker{
+		provider: provider,
+		settings: settings,
+		now:      time.Now,
+		state:    BreakerClosed,
+	}
+}
+
+func (b *Breaker) Name() string  { return b.provider.Name() }
+func (b *Breaker) Model() string { return b.provider.Model() }
+
+func (b *Breaker) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+
+	response, err := b.provider.Complete(ctx, req)
+	b.record(ctx, err)
+	return response, err
+}
+
+func (b *Breaker) Stream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+
+	chunks, err := b.provider.Stream(ctx, req)
+	if err != nil {
+		b.record(ctx, err)
+		return nil, err
+	}
+
+	// The stream counts once it ends, as a failure if it broke off
+	out := make(chan Chunk)
+	go func() {
+		defer close(out)
+
+		var streamErr error
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				b.record(ctx, ctx.Err())
+				return
+			}
+		}
+		b.record(ctx, streamErr)
+	}()
+
+	return out, nil
+}
+
+// State returns the breaker's current state.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Status describes the breaker for health checks and stats.
+func (b *Breaker) Status() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := map[string]interface{}{
+		"state":        string(b.state),
+		"calls":        len(b.outcomes),
+		"failures":     b.failures,
+		"failure_rate": b.failureRate(),
+		"opens":        b.opens,
+		"rejected":     b.rejected,
+	}
+	if b.lastError != "" {
+		status["last_error"] = b.lastError
+	}
+	if b.state == BreakerOpen {
+		status["retry_at"] = b.openedAt.Add(b.settings.Cooldown).Format(time.RFC3339)
+	}
+	return status
+}
+
+// Stats returns the backend's counters, if it keeps any, with the breaker's status.
+func (b *Breaker) Stats() map[string]interface{} {
+	stats := map[string]interface{}{}
+	if reporter, ok := b.provider.(StatsReporter); ok {
+		stats = reporter.Stats()
+	}
+	stats["breaker"] = b.Status()
+	return stats
+}
+
+// allow reports whether a call may go to the backend, moving an open breaker
+// whose cooldown has passed to half-open.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.settings.Cooldown {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record counts the outcome of a call allowed by allow.
+func (b *Breaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A call the caller gave up on or ran out of time for says nothing about the backend
+	if err != nil && ctx.Err() != nil && !timedOut(ctx) {
+		if b.state == BreakerHalfOpen {
+			b.probing = false
+		}
+		return
+	}
+
+	failed := err != nil && !rejected(err)
+	if failed {
+		b.lastError = err.Error()
+	}
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.trip()
+		} else {
+			log.Printf("LLM circuit breaker closed, %s is answering again", b.provider.Name())
+			b.reset(BreakerClosed)
+		}
+		return
+	case BreakerOpen:
+		// A call that started before the breaker opened
+		return
+	}
+
+	if len(b.outcomes) < b.settings.Window {
+		b.outcomes = append(b.outcomes, failed)
+	} else {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % b.settings.Window
+	}
+	if failed {
+		b.failures++
+	}
+
+	if failed && len(b.outcomes) >= b.settings.MinCalls && b.failureRate() >= b.settings.FailureRate {
+		log.Printf("LLM circuit breaker open: %d of the last %d calls to %s failed, last with: %v",
+			b.failures, len(b.outcomes), b.provider.Name(), err)
+		b.trip()
+	}
+}
+
+// trip opens the breaker for a cooldown.
+func (b *Breaker) trip() {
+	b.reset(BreakerOpen)
+	b.openedAt = b.now()
+	b.opens++
+}
+
+// reset moves the breaker to state with an empty window.
+func (b *Breaker) reset(state BreakerState) {
+	b.state = state
+	b.outcomes = b.outcomes[:0]
+	b.next = 0
+	b.failures = 0
+}
+
+func (b *Breaker) failureRate() float64 {
+	if len(b.outcomes) == 0 {
+		return 0
+	}
+	return float64(b.failures) / float64(len(b.outcomes))
+}
+
+// rejected reports whether err is the backend refusing the request itself
+// with a 4xx status other than 408 (timeout) and 429 (rate limited).
+func rejected(err error) bool {
+	if errors.Is(err, grok.ErrBadRequest) || errors.Is(err, grok.ErrAuth) {
+		return true
+	}
+	var statusErr *ollamaStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 &&
+		statusErr.StatusCode != http.StatusRequestTimeout && statusErr.StatusCode != http.StatusTooManyRequests
+}
@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/grok"
+)
+
+func TestBreaker(t *testing.T) {
+	boom := errors.New("boom")
+	provider := NewScripted("one").Fail(boom).Fail(boom).Reply("two").Reply("three")
+	breaker := NewBreaker(provider, BreakerSettings{FailureRate: 0.5, Window: 4, MinCalls: 2, Cooldown: time.Minute})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	req := Request{Messages: []Message{{Role: "user", Content: "Hi"}}}
+	call := func() error {
+		_, err := breaker.Complete(ctx, req)
+		return err
+	}
+
+	// One failure in two calls reaches the failure rate
+	if err := call(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := call(); !errors.Is(err, boom) {
+		t.Fatalf("Expected the backend error, got: %v", err)
+	}
+	if state := breaker.State(); state != BreakerOpen {
+		t.Fatalf("Expected the breaker open, got %s", state)
+	}
+
+	// Open, calls fail at once without reaching the backend
+	if err := call(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got: %v", err)
+	}
+	if _, err := breaker.Stream(ctx, req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen from Stream, got: %v", err)
+	}
+	if got := len(provider.Requests()); got != 2 {
+		t.Errorf("Expected 2 calls to reach the backend, got %d", got)
+	}
+
+	// A failed trial call opens it again for another cooldown
+	now = now.Add(time.Minute)
+	if err := call(); !errors.Is(err, boom) {
+		t.Fatalf("Expected the trial call to fail, got: %v", err)
+	}
+	if err := call(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen after the failed trial, got: %v", err)
+	}
+
+	// A successful trial call closes it
+	now = now.Add(time.Minute)
+	chunks, err := breaker.Stream(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected the trial stream to start, got: %v", err)
+	}
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+	}
+	if state := breaker.State(); state != BreakerClosed {
+		t.Fatalf("Expected the breaker closed, got %s", state)
+	}
+
+	status := breaker.Status()
+	if status["opens"] != 2 || status["rejected"] != 3 || status["last_error"] != "boom" {
+		t.Errorf("Unexpected status: %v", status)
+	}
+}
+
+func TestBreakerIgnoresCancellation(t *testing.T) {
+	breaker := NewBreaker(NewScripted(), BreakerSettings{Window: 2, MinCalls: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 3; i++ {
+		if _, err := breaker.Complete(ctx, Request{}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got: %v", err)
+		}
+	}
+	if state := breaker.State(); state != BreakerClosed {
+		t.Errorf("Expected cancelled calls not to open the breaker, got %s", state)
+	}
+
+	// A deadline the caller chose does not count, the backend's own timeout does
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		breaker.Complete(expired, Request{})
+	}
+	if state := breaker.State(); state != BreakerClosed {
+		t.Errorf("Expected calls past the caller's deadline not to open the breaker, got %s", state)
+	}
+
+	timedOut, cancel := WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-timedOut.Done()
+	if _, err := breaker.Complete(timedOut, Request{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if state := breaker.State(); state != BreakerOpen {
+		t.Errorf("Expected a backend timeout to open the breaker, got %s", state)
+	}
+}
+
+func TestBreakerIgnoresRejectedRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantOpen bool
+	}{
+		{"BadRequest", &grok.APIError{StatusCode: 400, Message: "model not found"}, false},
+		{"Auth", &grok.APIError{StatusCode: 401}, false},
+		{"OllamaNotFound", &ollamaStatusError{StatusCode: 404, Message: "model not found"}, false},
+		{"Server", &grok.APIError{StatusCode: 503}, true},
+		{"RateLimited", fmt.Errorf("after retries: %w", &grok.APIError{StatusCode: 429}), true},
+		{"OllamaTimeout", &ollamaStatusError{StatusCode: 408}, true},
+		{"Transport", errors.New("failed to reach ollama: connection refused"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewScripted()
+			for i := 0; i < 5; i++ {
+				provider.Fail(tt.err)
+			}
+			breaker := NewBreaker(provider, BreakerSettings{FailureRate: 0.5, Window: 4, MinCalls: 2, Cooldown: time.Minute})
+
+			for i := 0; i < 5; i++ {
+				breaker.Complete(context.Background(), Request{})
+			}
+			if open := breaker.State() == BreakerOpen; open != tt.wantOpen {
+				t.Errorf("Expected open %v after repeated %v, got state %s", tt.wantOpen, tt.err, breaker.State())
+			}
+		})
+	}
+}
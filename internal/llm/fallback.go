@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"log"
+)
+
+// Fallback is a Provider that sends a request to a secondary backend and
+// model when the primary one fails, including when its circuit breaker is
+// open. Requests abandoned because the caller's context ended do not fall back.
+type Fallback struct {
+	primary   Provider
+	secondary Provider
+	// model replaces the requested model on the secondary backend; "" uses its default.
+	model string
+}
+
+// NewFallback returns a provider that answers with primary and, should that
+// fail, with model on secondary.
+func NewFallback(primary, secondary Provider, model string) *Fallback {
+	return &Fallback{primary: primary, secondary: secondary, model: model}
+}
+
+func (f *Fallback) Name() string {
+	return f.primary.Name()
+}
+
+func (f *Fallback) Model() string {
+	return f.primary.Model()
+}
+
+// Complete returns the primary backend's answer, or the secondary's if it fails.
+func (f *Fallback) Complete(ctx context.Context, req Request) (*Response, error) {
+	response, err := f.primary.Complete(ctx, req)
+	if err == nil || ctx.Err() != nil {
+		return response, err
+	}
+
+	req = f.fallbackRequest(req, err)
+	response, err = f.secondary.Complete(ctx, req)
+	if err == nil && response.Model == "" {
+		response.Model = f.fallbackModel()
+	}
+	return response, err
+}
+
+// Stream streams the primary backend's answer, or the secondary's if the
+// primary fails before sending any of it. A stream that breaks off midway
+// is not restarted, as its beginning was already delivered.
+func (f *Fallback) Stream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	chunks, err := f.primary.Stream(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return f.fallbackStream(ctx, f.fallbackRequest(req, err))
+	}
+
+	// Look at the first chunk to see whether the stream got going
+	first, ok := <-chunks
+	if ok && first.Err != nil && ctx.Err() == nil {
+		return f.fallbackStream(ctx, f.fallbackRequest(req, first.Err))
+	}
+
+	out := make(chan Chunk)
+	go func() {
+		defer close(out)
+		if !ok {
+			return
+		}
+		chunk := first
+		for {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+			if chunk, ok = <-chunks; !ok {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// fallbackStream streams req from the secondary backend, marking the chunks
+// with the model answering.
+func (f *Fallback) fallbackStream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	chunks, err := f.secondary.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	model := f.fallbackModel()
+	out := make(chan Chunk)
+	go func() {
+		defer close(out)
+		for chunk := range chunks {
+			chunk.Model = model
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// fallbackRequest logs the primary backend's failure and returns req for the secondary.
+func (f *Fallback) fallbackRequest(req Request, err error) Request {
+	model := req.Model
+	if model == "" {
+		model = f.primary.Model()
+	}
+	log.Printf("LLM %s model %s failed, falling back to %s model %s: %v",
+		f.primary.Name(), model, f.secondary.Name(), f.fallbackModel(), err)
+
+	req.Model = f.model
+	return req
+}
+
+func (f *Fallback) fallbackModel() string {
+	if f.model != "" {
+		return f.model
+	}
+	return f.secondary.Model()
+}
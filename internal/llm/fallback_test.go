@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	boom := errors.New("boom")
+	req := Request{Model: "big", Messages: []Message{{Role: "user", Content: "Hi"}}}
+
+	t.Run("Complete", func(t *testing.T) {
+		primary, secondary := NewScripted("one").Fail(boom), NewScripted("two")
+		fallback := NewFallback(primary, secondary, "small")
+
+		for _, want := range []string{"one", "two"} {
+			response, err := fallback.Complete(context.Background(), req)
+			if err != nil || response.Content != want {
+				t.Fatalf("Expected %q, got %+v, error %v", want, response, err)
+			}
+		}
+		if requests := secondary.Requests(); len(requests) != 1 || requests[0].Model != "small" {
+			t.Errorf("Expected one request for the fallback model, got %+v", requests)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		primary, secondary := NewScripted().Fail(boom), NewScripted("two words")
+		chunks, err := NewFallback(primary, secondary, "small").Stream(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		text := ""
+		for chunk := range chunks {
+			if chunk.Model != "small" {
+				t.Errorf("Expected chunks marked with the fallback model, got %+v", chunk)
+			}
+			text += chunk.Delta
+		}
+		if text != "two words" {
+			t.Errorf("Expected the fallback answer, got %q", text)
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		secondary := NewScripted("two")
+		if _, err := NewFallback(NewScripted("one"), secondary, "").Complete(ctx, req); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got: %v", err)
+		}
+		if n := len(secondary.Requests()); n != 0 {
+			t.Errorf("Expected no fallback for a cancelled request, got %d", n)
+		}
+	})
+}
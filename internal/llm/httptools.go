@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxHTTPToolResult caps the bytes of an HTTP tool's response sent back to
+// the model.
+const maxHTTPToolResult = 16 << 10
+
+// HTTPTool is a tool run by posting the model's arguments to a URL, so that
+// lookups such as order status can live in an existing service.
+type HTTPTool struct {
+	Tool
+	URL string
+	// Headers are sent with every call, e.g. an Authorization header.
+	Headers map[string]string
+
+	client *http.Client
+}
+
+// httpToolSpec is an entry of the tools file.
+type httpToolSpec struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Parameters  json.RawMessage   `json:"parameters"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers"`
+}
+
+// LoadHTTPTools reads the tools defined in the JSON file at path, an array of
+// objects with a name, description, JSON schema of the parameters, url and
+// optional headers. Header values may refer to environment variables as
+// $NAME or ${NAME}, keeping secrets out of the file. Each call may take up
+// to timeout.
+func LoadHTTPTools(path string, timeout time.Duration) ([]*HTTPTool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tools file: %w", err)
+	}
+
+	var specs []httpToolSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("failed to parse tools file: %w", err)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	tools := make([]*HTTPTool, 0, len(specs))
+	for i, spec := range specs {
+		if spec.Name == "" || spec.URL == "" {
+			return nil, fmt.Errorf("tool %d: name and url are required", i)
+		}
+		if !strings.HasPrefix(spec.URL, "http://") && !strings.HasPrefix(spec.URL, "https://") {
+			return nil, fmt.Errorf("tool %s: url must be http or https", spec.Name)
+		}
+		if len(spec.Parameters) == 0 {
+			spec.Parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+
+		headers := make(map[string]string, len(spec.Headers))
+		for name, value := range spec.Headers {
+			headers[name] = os.ExpandEnv(value)
+		}
+
+		tools = append(tools, &HTTPTool{
+			Tool:    Tool{Name: spec.Name, Description: spec.Description, Parameters: spec.Parameters},
+			URL:     spec.URL,
+			Headers: headers,
+			client:  client,
+		})
+	}
+
+	return tools, nil
+}
+
+// Run posts arguments to the tool's URL and returns the response body. It is
+// the tool's ToolFunc.
+func (t *HTTPTool) Run(ctx context.Context, arguments json.RawMessage) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(arguments))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call %s: %w", t.Name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPToolResult))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s response: %w", t.Name, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s returned status %d: %s", t.Name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHTTPTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/order_status":
+			fmt.Fprintf(w, `{"request":%s,"status":"shipped"}`, body)
+		default:
+			http.Error(w, "no such order", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("TOOLS_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), "tools.json")
+	file := fmt.Sprintf(`[
+		{"name": "order_status", "description": "Look up an order", "url": "%[1]s/order_status",
+		 "parameters": {"type": "object", "properties": {"order": {"type": "string"}}},
+		 "headers": {"Authorization": "Bearer ${TOOLS_TOKEN}"}},
+		{"name": "refund", "url": "%[1]s/refund", "headers": {"Authorization": "Bearer $TOOLS_TOKEN"}}
+	]`, server.URL)
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("Failed to write tools file: %v", err)
+	}
+
+	tools, err := LoadHTTPTools(path, time.Second)
+	if err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Description != "Look up an order" || !json.Valid(tools[1].Parameters) {
+		t.Fatalf("Unexpected tools: %+v", tools)
+	}
+
+	result, err := tools[0].Run(context.Background(), json.RawMessage(`{"order":"AB-1"}`))
+	if err != nil || result != `{"request":{"order":"AB-1"},"status":"shipped"}` {
+		t.Errorf("Expected the service's answer, got %q, error %v", result, err)
+	}
+	if _, err := tools[1].Run(context.Background(), json.RawMessage(`{}`)); err == nil {
+		t.Error("Expected an error for a failed call")
+	}
+
+	for _, invalid := range []string{`{"name": "x"}`, `[{"name": "x"}]`, `[{"name": "x", "url": "file:///etc/passwd"}]`} {
+		if err := os.WriteFile(path, []byte(invalid), 0o600); err != nil {
+			t.Fatalf("Failed to write tools file: %v", err)
+		}
+		if _, err := LoadHTTPTools(path, time.Second); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/jsonschema"
+)
+
+// ErrInvalidJSON is returned by CompleteJSON when the model's answer still
+// does not follow the schema once the repair attempts are used up.
+var ErrInvalidJSON = errors.New("model answer does not follow the schema")
+
+// repairPrompt asks the model to fix an answer, given what is wrong with it.
+const repairPrompt = "Your answer does not follow the required JSON schema: %s. Reply again with only the corrected JSON, without any explanation."
+
+// CompleteJSON asks provider for a JSON answer to req, which should carry a
+// ResponseFormat, and validates it against schema. An answer that is not
+// valid is sent back with the problems found and a request to fix them, up
+// to repairs times. It returns the valid JSON along with every response
+// received, for usage accounting, which are also returned on failure.
+func CompleteJSON(ctx context.Context, provider Provider, req Request, schema *jsonschema.Schema, repairs int) (json.RawMessage, []*Response, error) {
+	var responses []*Response
+	for attempt := 0; ; attempt++ {
+		response, err := provider.Complete(ctx, req)
+		if err != nil {
+			return nil, responses, err
+		}
+		responses = append(responses, response)
+
+		content := jsonContent(response.Content)
+		err = schema.Validate([]byte(content))
+		if err == nil {
+			return json.RawMessage(content), responses, nil
+		}
+		if attempt == repairs {
+			return nil, responses, fmt.Errorf("%w after %d attempts: %v", ErrInvalidJSON, attempt+1, err)
+		}
+
+		log.Printf("Invalid JSON answer from %s, asking for a repair: %v", provider.Name(), err)
+		messages := req.Messages[:len(req.Messages):len(req.Messages)]
+		req.Messages = append(messages,
+			Message{Role: "assistant", Content: response.Content},
+			Message{Role: "user", Content: fmt.Sprintf(repairPrompt, err)},
+		)
+	}
+}
+
+// jsonContent strips the Markdown code fence models sometimes put around
+// JSON despite being asked not to.
+func jsonContent(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	if newline := strings.IndexByte(content, '\n'); newline >= 0 {
+		// Drop the language tag, e.g. "json"
+		content = content[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(content), "```"))
+}
@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/jsonschema"
+)
+
+func TestCompleteJSON(t *testing.T) {
+	schema, err := jsonschema.Parse([]byte(`{"type":"object","properties":{"intent":{"enum":["track","cancel"]}},"required":["intent"]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	req := Request{
+		Messages:       []Message{{Role: "user", Content: "Where is my parcel?"}},
+		ResponseFormat: &ResponseFormat{Type: FormatJSONObject},
+	}
+
+	tests := []struct {
+		name          string
+		provider      *Scripted
+		repairs       int
+		want          string
+		wantErr       error
+		wantResponses int
+	}{
+		{"Valid", NewScripted(`{"intent":"track"}`), 2, `{"intent":"track"}`, nil, 1},
+		{"Fenced", NewScripted("```json\n{\"intent\":\"track\"}\n```"), 2, `{"intent":"track"}`, nil, 1},
+		{"Repaired", NewScripted(`{"intent":"where"}`, `{"intent":"track"}`), 2, `{"intent":"track"}`, nil, 2},
+		{"GivesUp", NewScripted(`not json`, `{}`), 1, "", ErrInvalidJSON, 2},
+		{"Failed", NewScripted().Fail(errors.New("boom")), 2, "", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, responses, err := CompleteJSON(context.Background(), tt.provider, req, schema, tt.repairs)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got: %v", tt.wantErr, err)
+			}
+			if tt.want != "" && (err != nil || string(got) != tt.want) {
+				t.Fatalf("Expected %s, got %s, error %v", tt.want, got, err)
+			}
+			if tt.want == "" && err == nil {
+				t.Fatalf("Expected an error, got %s", got)
+			}
+			if len(responses) != tt.wantResponses {
+				t.Errorf("Expected %d responses, got %d", tt.wantResponses, len(responses))
+			}
+		})
+	}
+
+	// The repair request shows the model its answer and what is wrong with it
+	provider := NewScripted(`{"intent":"where"}`, `{"intent":"track"}`)
+	if _, _, err := CompleteJSON(context.Background(), provider, req, schema, 1); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	repair := provider.Requests()[1].Messages
+	if len(repair) != 3 || repair[1].Content != `{"intent":"where"}` || !strings.Contains(repair[2].Content, `$.intent: must be one of "track", "cancel"`) {
+		t.Errorf("Expected a repair prompt naming the problem, got %+v", repair)
+	}
+}
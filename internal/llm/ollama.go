@@ -0,0 +1,290 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultOllamaURL is where a local Ollama server listens by default.
+const DefaultOllamaURL = "http://localhost:11434"
+
+// Ollama is a Provider for a local Ollama server's /api/chat endpoint, so
+// replies can be generated fully offline.
+type Ollama struct {
+	BaseURL string
+	model   string
+	client  *http.Client
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	// ToolName is the tool a "tool" message returns the result of
+	ToolName string `json:"tool_name,omitempty"`
+	// Images are base64 encoded, without a data URL prefix
+	Images []string `json:"images,omitempty"`
+}
+
+// ollamaToolCall is a tool call; unlike the OpenAI protocol, the arguments
+// are a JSON object rather than a string and calls have no IDs.
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+	// Format is "json" or a JSON schema the answer must follow
+	Format json.RawMessage `json:"format,omitempty"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+// ollamaResponse is the body of a non-streamed reply and each line of a streamed one.
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// NewOllama returns a provider for the Ollama server at baseURL.
+func NewOllama(baseURL, model string) *Ollama {
+	return &Ollama{BaseURL: baseURL, model: model, client: &http.Client{}}
+}
+
+func (o *Ollama) Name() string {
+	return ProviderOllama
+}
+
+func (o *Ollama) Model() string {
+	return o.model
+}
+
+func (o *Ollama) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := o.post(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode ollama response: %w", err)
+	}
+	if chatResp.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", chatResp.Error)
+	}
+
+	return &Response{
+		Content:      chatResp.Message.Content,
+		ToolCalls:    chatResp.Message.toolCalls(0),
+		Model:        chatResp.Model,
+		FinishReason: chatResp.DoneReason,
+		Usage:        chatResp.usage(),
+	}, nil
+}
+
+func (o *Ollama) Stream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	resp, err := o.post(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan Chunk)
+	go func() {
+		defer close(chunks)
+		defer resp.Body.Close()
+
+		send := func(chunk Chunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if err := readOllamaStream(resp.Body, send); err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			send(Chunk{Err: err})
+		}
+	}()
+
+	return chunks, nil
+}
+
+// readOllamaStream reads the newline-delimited JSON objects of a streamed
+// reply until the one marked done.
+func readOllamaStream(r io.Reader, send func(Chunk) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var toolCalls []ToolCall
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var event ollamaResponse
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("failed to decode ollama stream: %w", err)
+		}
+		if event.Error != "" {
+			return fmt.Errorf("ollama error: %s", event.Error)
+		}
+
+		toolCalls = append(toolCalls, event.Message.toolCalls(len(toolCalls))...)
+		if event.Message.Content != "" && !send(Chunk{Delta: event.Message.Content}) {
+			return nil
+		}
+		if event.Done {
+			usage := event.usage()
+			send(Chunk{FinishReason: event.DoneReason, ToolCalls: toolCalls, Usage: &usage})
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read ollama stream: %w", err)
+	}
+	return errors.New("ollama stream ended before completion")
+}
+
+func (o *Ollama) post(ctx context.Context, req Request, stream bool) (*http.Response, error) {
+	body := ollamaRequest{
+		Model:  req.Model,
+		Stream: stream,
+	}
+	if body.Model == "" {
+		body.Model = o.model
+	}
+	toolNames := make(map[string]string)
+	for _, msg := range req.Messages {
+		message := ollamaMessage{Role: msg.Role, Content: msg.Content, ToolName: toolNames[msg.ToolCallID]}
+		for _, image := range msg.Images {
+			message.Images = append(message.Images, base64.StdEncoding.EncodeToString(image.Data))
+		}
+		for _, call := range msg.ToolCalls {
+			toolNames[call.ID] = call.Name
+
+			var toolCall ollamaToolCall
+			toolCall.Function.Name = call.Name
+			toolCall.Function.Arguments = json.RawMessage(call.Arguments)
+			if !json.Valid(toolCall.Function.Arguments) {
+				toolCall.Function.Arguments = json.RawMessage("{}")
+			}
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+		}
+		body.Messages = append(body.Messages, message)
+	}
+	for _, tool := range req.Tools {
+		t := ollamaTool{Type: "function"}
+		t.Function.Name = tool.Name
+		t.Function.Description = tool.Description
+		t.Function.Parameters = tool.Parameters
+		body.Tools = append(body.Tools, t)
+	}
+	if req.Temperature != nil || req.MaxTokens != 0 {
+		body.Options = &ollamaOptions{Temperature: req.Temperature, NumPredict: req.MaxTokens}
+	}
+	if f := req.ResponseFormat; f != nil {
+		body.Format = json.RawMessage(`"json"`)
+		if f.Type == FormatJSONSchema {
+			body.Format = f.Schema
+		}
+	}
+
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.BaseURL+"/api/chat", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ollama: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+		statusErr := &ollamaStatusError{StatusCode: resp.StatusCode, Message: string(errBody)}
+		var errResp ollamaResponse
+		if json.Unmarshal(errBody, &errResp) == nil && errResp.Error != "" {
+			statusErr.Message = errResp.Error
+		}
+		return nil, statusErr
+	}
+
+	return resp, nil
+}
+
+// ollamaStatusError is an error response from Ollama.
+type ollamaStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ollamaStatusError) Error() string {
+	return fmt.Sprintf("ollama returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// toolCalls converts the message's tool calls, numbering their IDs from n
+// since Ollama does not assign any.
+func (m ollamaMessage) toolCalls(n int) []ToolCall {
+	var calls []ToolCall
+	for i, call := range m.ToolCalls {
+		calls = append(calls, ToolCall{
+			ID:        fmt.Sprintf("call_%d", n+i+1),
+			Name:      call.Function.Name,
+			Arguments: string(call.Function.Arguments),
+		})
+	}
+	return calls
+}
+
+func (r ollamaResponse) usage() Usage {
+	return Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
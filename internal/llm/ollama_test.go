@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if req.Model != "llama3.2" || len(req.Messages) != 2 {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		if !req.Stream {
+			fmt.Fprint(w, `{"model":"llama3.2","message":{"role":"assistant","content":"Hi!"},"done":true,
+				"done_reason":"stop","prompt_eval_count":12,"eval_count":3}`)
+			return
+		}
+		for _, piece := range []string{"Hi", "!"} {
+			fmt.Fprintf(w, "{\"message\":{\"role\":\"assistant\",\"content\":%q},\"done\":false}\n", piece)
+		}
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}`+"\n")
+	}))
+	defer server.Close()
+
+	provider := NewOllama(server.URL, "llama3.2")
+	req := Request{Messages: []Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hello"},
+	}}
+
+	t.Run("Complete", func(t *testing.T) {
+		resp, err := provider.Complete(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if resp.Content != "Hi!" || resp.Usage.TotalTokens != 15 {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		chunks, err := provider.Stream(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		var text strings.Builder
+		finishReason := ""
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				t.Fatalf("Unexpected stream error: %v", chunk.Err)
+			}
+			text.WriteString(chunk.Delta)
+			if chunk.FinishReason != "" {
+				finishReason = chunk.FinishReason
+			}
+		}
+		if text.String() != "Hi!" || finishReason != "stop" {
+			t.Errorf("Expected Hi! with reason stop, got %q, %q", text.String(), finishReason)
+		}
+	})
+}
+
+func TestOllamaError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"llama3.2\" not found, try pulling it first"}`)
+	}))
+	defer server.Close()
+
+	provider := NewOllama(server.URL, "llama3.2")
+	_, err := provider.Complete(context.Background(), Request{Messages: []Message{{Role: "user", Content: "Hi"}}})
+	if err == nil || !strings.Contains(err.Error(), "try pulling it first") {
+		t.Errorf("Expected ollama error message, got: %v", err)
+	}
+}
+
+func TestOllamaToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "get_weather" {
+			t.Errorf("Expected the weather tool to be offered, got %+v", req.Tools)
+		}
+
+		if last := req.Messages[len(req.Messages)-1]; last.Role == "tool" {
+			if last.ToolName != "get_weather" || string(req.Messages[1].ToolCalls[0].Function.Arguments) != `{"city":"Oslo"}` {
+				t.Errorf("Expected the tool round trip, got %+v", req.Messages)
+			}
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"Sunny."},"done":true,"done_reason":"stop"}`)
+			return
+		}
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"","tool_calls":[
+			{"function":{"name":"get_weather","arguments":{"city":"Oslo"}}}]},"done":true,"done_reason":"stop"}`)
+	}))
+	defer server.Close()
+
+	provider := NewOllama(server.URL, "llama3.2")
+	req := Request{
+		Messages: []Message{{Role: "user", Content: "Weather in Oslo?"}},
+		Tools:    []Tool{{Name: "get_weather", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	}
+
+	resp, err := provider.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Arguments != `{"city":"Oslo"}` {
+		t.Fatalf("Expected a get_weather call, got %+v", resp.ToolCalls)
+	}
+
+	req.Messages = append(req.Messages,
+		Message{Role: "assistant", ToolCalls: resp.ToolCalls},
+		Message{Role: "tool", Content: "Sunny, 21C", ToolCallID: "call_1"})
+	resp, err = provider.Complete(context.Background(), req)
+	if err != nil || resp.Content != "Sunny." {
+		t.Errorf("Expected the final answer, got %+v, error %v", resp, err)
+	}
+}
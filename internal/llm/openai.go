@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/grok"
+)
+
+// chatCompletions is a Provider for APIs that speak the OpenAI chat
+// completions protocol, which includes Grok.
+type chatCompletions struct {
+	name   string
+	client *grok.Client
+}
+
+// NewGrok returns a provider backed by the X.AI Grok API.
+func NewGrok(apiKey, baseURL, model string, maxRetries int) Provider {
+	return newChatCompletions(ProviderGrok, apiKey, baseURL, model, maxRetries)
+}
+
+// NewOpenAI returns a provider for any OpenAI-compatible chat completions
+// endpoint, such as OpenAI itself, vLLM, LM Studio or llama.cpp's server.
+// apiKey may be empty for local servers.
+func NewOpenAI(apiKey, baseURL, model string, maxRetries int) Provider {
+	return newChatCompletions(ProviderOpenAI, apiKey, baseURL, model, maxRetries)
+}
+
+func newChatCompletions(name, apiKey, baseURL, model string, maxRetries int) *chatCompletions {
+	client := grok.NewClient(apiKey, baseURL, model)
+	client.MaxRetries = max(maxRetries, 0)
+	return &chatCompletions{name: name, client: client}
+}
+
+func (p *chatCompletions) Name() string {
+	return p.name
+}
+
+func (p *chatCompletions) Model() string {
+	return p.client.Model
+}
+
+func (p *chatCompletions) Stats() map[string]interface{} {
+	return p.client.Stats()
+}
+
+func (p *chatCompletions) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := p.client.Complete(ctx, p.request(req))
+	if err != nil {
+		return nil, err
+	}
+
+	choice := resp.Choices[0]
+	return &Response{
+		Content:      choice.Message.Content,
+		ToolCalls:    fromGrokToolCalls(choice.Message.ToolCalls),
+		Model:        resp.Model,
+		FinishReason: choice.FinishReason,
+		Usage:        fromGrokUsage(resp.Usage),
+	}, nil
+}
+
+func (p *chatCompletions) Stream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	chunks, err := p.client.Stream(ctx, p.request(req))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Chunk)
+	go func() {
+		defer close(out)
+		for chunk := range chunks {
+			select {
+			case out <- Chunk{
+				Delta:        chunk.Delta,
+				FinishReason: chunk.FinishReason,
+				ToolCalls:    fromGrokToolCalls(chunk.ToolCalls),
+				Usage:        streamUsage(chunk.Usage),
+				Err:          chunk.Err,
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (p *chatCompletions) request(req Request) grok.ChatCompletionRequest {
+	messages := make([]grok.Message, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		message := grok.Message{Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+		if len(msg.Images) > 0 {
+			message.Parts = append(message.Parts, grok.TextPart(msg.Content))
+			for _, image := range msg.Images {
+				message.Parts = append(message.Parts, grok.ImagePart(grok.DataURL(image.MimeType, image.Data)))
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, grok.ToolCall{
+				ID:       call.ID,
+				Type:     "function",
+				Function: grok.FunctionCall{Name: call.Name, Arguments: call.Arguments},
+			})
+		}
+		messages = append(messages, message)
+	}
+
+	var tools []grok.Tool
+	for _, tool := range req.Tools {
+		tools = append(tools, grok.FunctionTool(tool.Name, tool.Description, tool.Parameters))
+	}
+
+	var format *grok.ResponseFormat
+	if f := req.ResponseFormat; f != nil {
+		format = grok.JSONObjectFormat()
+		if f.Type == FormatJSONSchema {
+			format = grok.JSONSchemaFormat(f.Name, f.Schema)
+		}
+	}
+
+	return grok.ChatCompletionRequest{
+		Model:          req.Model,
+		Messages:       messages,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+		Tools:          tools,
+		ResponseFormat: format,
+	}
+}
+
+func fromGrokUsage(usage grok.Usage) Usage {
+	return Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+func streamUsage(usage *grok.Usage) *Usage {
+	if usage == nil {
+		return nil
+	}
+	converted := fromGrokUsage(*usage)
+	return &converted
+}
+
+func fromGrokToolCalls(calls []grok.ToolCall) []ToolCall {
+	var out []ToolCall
+	for _, call := range calls {
+		out = append(out, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price is what a model costs, in US dollars per million tokens.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// PriceTable maps model names to their prices. A model without an exact
+// entry uses the longest entry it starts with, so "gpt-4o-mini" also prices
+// dated snapshots such as "gpt-4o-mini-2024-07-18".
+type PriceTable map[string]Price
+
+// ParsePrices parses a price table written as comma-separated
+// model=prompt:completion entries, e.g. "grok-beta=5:15,gpt-4o-mini=0.15:0.6".
+func ParsePrices(s string) (PriceTable, error) {
+	table := make(PriceTable)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, prices, ok := strings.Cut(entry, "=")
+		prompt, completion, ok2 := strings.Cut(prices, ":")
+		if !ok || !ok2 || strings.TrimSpace(model) == "" {
+			return nil, fmt.Errorf("invalid price %q, want model=prompt:completion", entry)
+		}
+
+		var price Price
+		var err error
+		if price.Prompt, err = strconv.ParseFloat(strings.TrimSpace(prompt), 64); err != nil || price.Prompt < 0 {
+			return nil, fmt.Errorf("invalid prompt price in %q", entry)
+		}
+		if price.Completion, err = strconv.ParseFloat(strings.TrimSpace(completion), 64); err != nil || price.Completion < 0 {
+			return nil, fmt.Errorf("invalid completion price in %q", entry)
+		}
+		table[strings.TrimSpace(model)] = price
+	}
+	return table, nil
+}
+
+// Lookup returns the price of model.
+func (t PriceTable) Lookup(model string) (Price, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	best, found := "", false
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best, found = name, true
+		}
+	}
+	return t[best], found
+}
+
+// Cost returns what usage of model cost in US dollars, and false if the
+// model has no price.
+func (t PriceTable) Cost(model string, usage Usage) (float64, bool) {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6, true
+}
@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPrices(t *testing.T) {
+	prices, err := ParsePrices("grok-beta=5:15, gpt-4o=2.5:10,gpt-4o-mini=0.15:0.6")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	usage := Usage{PromptTokens: 1000, CompletionTokens: 200}
+	tests := []struct {
+		model    string
+		wantCost float64
+		wantOK   bool
+	}{
+		{"grok-beta", 0.008, true},
+		{"gpt-4o-mini-2024-07-18", 0.00027, true},
+		{"gpt-4o-2024-08-06", 0.0045, true},
+		{"llama3.2", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			cost, ok := prices.Cost(tt.model, usage)
+			if ok != tt.wantOK || math.Abs(cost-tt.wantCost) > 1e-12 {
+				t.Errorf("Expected %g, %v, got %g, %v", tt.wantCost, tt.wantOK, cost, ok)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"grok-beta", "grok-beta=5", "=1:2", "grok-beta=a:1", "grok-beta=-1:1"} {
+		if _, err := ParsePrices(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package llm defines the interface the conversation pipeline uses to talk to
+// a language model, and the backends that implement it.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+)
+
+// Provider generates chat completions.
+type Provider interface {
+	// Name identifies the backend, e.g. "grok" or "ollama".
+	Name() string
+	// Model is the model used when a Request does not name one.
+	Model() string
+	// Complete returns the whole answer to req.
+	Complete(ctx context.Context, req Request) (*Response, error)
+	// Stream returns the answer to req piece by piece. The channel is closed
+	// when the answer is complete; a failure is sent as a Chunk with Err set.
+	Stream(ctx context.Context, req Request) (<-chan Chunk, error)
+}
+
+// Message is one turn of the conversation sent to the model.
+type Message struct {
+	Role    string // "system", "user", "assistant" or "tool"
+	Content string
+	// ToolCalls are the tools an assistant message asks to run.
+	ToolCalls []ToolCall
+	// ToolCallID is the call a "tool" message returns the result of.
+	ToolCallID string
+	// Images are shown to the model with the content of a "user" message.
+	Images []Image
+}
+
+// Image is a picture sent to a multimodal model.
+type Image struct {
+	MimeType string
+	Data     []byte
+}
+
+// Request is a chat completion request.
+type Request struct {
+	Messages []Message
+	// Model overrides the provider's default model when set.
+	Model string
+	// Temperature is sent when set, including 0; nil leaves the
+	// provider's default.
+	Temperature *float64
+	MaxTokens   int
+	// Tools the model may call instead of answering.
+	Tools []Tool
+	// ResponseFormat, if set, asks for a JSON answer.
+	ResponseFormat *ResponseFormat
+}
+
+// Temperature returns t for Request.Temperature.
+func Temperature(t float64) *float64 {
+	return &t
+}
+
+// Response format types.
+const (
+	// FormatJSONObject asks for any JSON object.
+	FormatJSONObject = "json_object"
+	// FormatJSONSchema asks for JSON following ResponseFormat.Schema.
+	FormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat constrains an answer to JSON.
+type ResponseFormat struct {
+	Type string
+	// Name identifies the schema to the model.
+	Name   string
+	Schema json.RawMessage
+}
+
+// Response is a complete answer, or the tools to run before one.
+type Response struct {
+	Content      string
+	ToolCalls    []ToolCall
+	Model        string
+	FinishReason string
+	Usage        Usage
+}
+
+// Usage reports the tokens a request consumed, as counted by the backend.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// Chunk is one piece of a streamed answer. Tool calls arrive whole, with
+// the FinishReason, as does the usage if the backend reports it.
+type Chunk struct {
+	Delta        string
+	FinishReason string
+	ToolCalls    []ToolCall
+	Usage        *Usage
+	// Model, if set, is the model answering in place of the one requested.
+	Model string
+	Err   error
+}
+
+// StatsReporter is implemented by providers that count their API calls.
+type StatsReporter interface {
+	Stats() map[string]interface{}
+}
+
+// Provider names accepted in LLM_PROVIDER.
+const (
+	ProviderGrok   = "grok"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+	ProviderNone   = "none"
+)
+
+// New creates the provider selected by config.LLMProvider. It returns nil
+// without an error when no provider is configured, in which case callers
+// answer with fallback replies.
+func New(config *configs.Config) (Provider, error) {
+	return NewNamed(config, config.LLMProvider, "")
+}
+
+// NewNamed creates the provider called name with the settings in config, as
+// New does for LLM_PROVIDER. A model other than "" replaces the configured
+// default model, which then need not be set.
+func NewNamed(config *configs.Config, name, model string) (Provider, error) {
+	switch strings.ToLower(name) {
+	case "", ProviderGrok:
+		if config.GrokAPIKey == "" {
+			log.Printf("Warning: GROK_API_KEY not set, using fallback responses")
+			return nil, nil
+		}
+		if model == "" {
+			model = config.GrokModel
+		}
+		return NewGrok(config.GrokAPIKey, config.GrokBaseURL, model, config.GrokMaxRetries), nil
+
+	case ProviderOpenAI:
+		if model == "" {
+			model = config.LLMModel
+		}
+		if config.LLMBaseURL == "" || model == "" {
+			return nil, fmt.Errorf("the openai provider needs LLM_BASE_URL and LLM_MODEL")
+		}
+		return NewOpenAI(config.LLMAPIKey, config.LLMBaseURL, model, config.GrokMaxRetries), nil
+
+	case ProviderOllama:
+		baseURL := config.LLMBaseURL
+		if baseURL == "" {
+			baseURL = DefaultOllamaURL
+		}
+		if model == "" {
+			model = config.LLMModel
+		}
+		if model == "" {
+			return nil, fmt.Errorf("the ollama provider needs LLM_MODEL")
+		}
+		return NewOllama(baseURL, model), nil
+
+	case ProviderNone:
+		log.Printf("LLM provider disabled, using fallback responses")
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q (want grok, openai, ollama or none)", name)
+	}
+}
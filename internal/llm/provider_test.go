@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   configs.Config
+		wantName string
+		wantErr  bool
+	}{
+		{"GrokWithoutKey", configs.Config{LLMProvider: "grok"}, "", false},
+		{"Grok", configs.Config{LLMProvider: "grok", GrokAPIKey: "key", GrokModel: "grok-beta"}, ProviderGrok, false},
+		{"OpenAI", configs.Config{LLMProvider: "openai", LLMBaseURL: "http://localhost:8000/v1", LLMModel: "qwen"}, ProviderOpenAI, false},
+		{"OpenAIWithoutModel", configs.Config{LLMProvider: "openai", LLMBaseURL: "http://localhost:8000/v1"}, "", true},
+		{"Ollama", configs.Config{LLMProvider: "Ollama", LLMModel: "llama3.2"}, ProviderOllama, false},
+		{"None", configs.Config{LLMProvider: "none", GrokAPIKey: "key"}, "", false},
+		{"Unknown", configs.Config{LLMProvider: "claude"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := New(&tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected configuration error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			name := ""
+			if provider != nil {
+				name = provider.Name()
+			}
+			if name != tt.wantName {
+				t.Errorf("Expected provider %q, got %q", tt.wantName, name)
+			}
+		})
+	}
+}
+
+func TestScripted(t *testing.T) {
+	provider := NewScripted("Hello there").Fail(errors.New("boom"))
+	ctx := context.Background()
+	req := Request{Messages: []Message{{Role: "user", Content: "Hi"}}}
+
+	chunks, err := provider.Stream(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var text strings.Builder
+	for chunk := range chunks {
+		text.WriteString(chunk.Delta)
+	}
+	if text.String() != "Hello there" {
+		t.Errorf("Expected streamed answer, got %q", text.String())
+	}
+
+	if _, err := provider.Complete(ctx, req); err == nil || err.Error() != "boom" {
+		t.Errorf("Expected scripted failure, got: %v", err)
+	}
+	if _, err := provider.Complete(ctx, req); !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("Expected ErrScriptExhausted, got: %v", err)
+	}
+	if got := len(provider.Requests()); got != 3 {
+		t.Errorf("Expected 3 recorded requests, got %d", got)
+	}
+}
+
+func TestTemperature(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if r.URL.Path == "/api/chat" {
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"Hi!"},"done":true}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Hi!"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	providers := []Provider{NewOpenAI("key", server.URL, "gpt-4o-mini", 0), NewOllama(server.URL, "llama3.2")}
+	tests := []struct {
+		name        string
+		temperature *float64
+		want        string
+	}{
+		{"Zero", Temperature(0), `"temperature":0`},
+		{"Set", Temperature(0.7), `"temperature":0.7`},
+		{"Unset", nil, ""},
+	}
+
+	for _, provider := range providers {
+		for _, tt := range tests {
+			t.Run(provider.Name()+"/"+tt.name, func(t *testing.T) {
+				req := Request{Messages: []Message{{Role: "user", Content: "Hello"}}, Temperature: tt.temperature}
+				if _, err := provider.Complete(context.Background(), req); err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if tt.want == "" && strings.Contains(body, `"temperature"`) {
+					t.Errorf("Expected no temperature, got %s", body)
+				}
+				if tt.want != "" && !strings.Contains(body, tt.want) {
+					t.Errorf("Expected %s, got %s", tt.want, body)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrScriptExhausted is returned by a Scripted provider asked for more
+// answers than it was given.
+var ErrScriptExhausted = errors.New("scripted provider has no answers left")
+
+// Scripted is a deterministic Provider for tests. It returns its answers in
+// order and records every request it receives.
+type Scripted struct {
+	mu       sync.Mutex
+	steps    []scriptStep
+	requests []Request
+}
+
+type scriptStep struct {
+	response Response
+	err      error
+}
+
+// NewScripted returns a provider that answers with replies, in order.
+func NewScripted(replies ...string) *Scripted {
+	s := &Scripted{}
+	for _, reply := range replies {
+		s.Reply(reply)
+	}
+	return s
+}
+
+// Reply queues a text answer.
+func (s *Scripted) Reply(content string) *Scripted {
+	return s.Respond(Response{Content: content, FinishReason: "stop"})
+}
+
+// Respond queues a complete response.
+func (s *Scripted) Respond(response Response) *Scripted {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, scriptStep{response: response})
+	return s
+}
+
+// Fail queues an error.
+func (s *Scripted) Fail(err error) *Scripted {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, scriptStep{err: err})
+	return s
+}
+
+// Requests returns the requests received so far.
+func (s *Scripted) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Scripted) Name() string {
+	return "scripted"
+}
+
+func (s *Scripted) Model() string {
+	return "scripted"
+}
+
+func (s *Scripted) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	step, err := s.next(req)
+	if err != nil {
+		return nil, err
+	}
+	response := step.response
+	if response.Model == "" {
+		response.Model = s.Model()
+	}
+	return &response, nil
+}
+
+// Stream delivers the next answer one word at a time, and its tool calls
+// and usage with the final chunk.
+func (s *Scripted) Stream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	response, err := s.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan Chunk)
+	go func() {
+		defer close(chunks)
+		for _, word := range strings.SplitAfter(response.Content, " ") {
+			select {
+			case chunks <- Chunk{Delta: word}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case chunks <- Chunk{FinishReason: response.FinishReason, ToolCalls: response.ToolCalls, Usage: &response.Usage}:
+		case <-ctx.Done():
+		}
+	}()
+
+	return chunks, nil
+}
+
+func (s *Scripted) next(req Request) (scriptStep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, req)
+	if len(s.steps) == 0 {
+		return scriptStep{}, ErrScriptExhausted
+	}
+
+	step := s.steps[0]
+	s.steps = s.steps[1:]
+	return step, step.err
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Tool describes a function the model may ask to call.
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments object.
+	Parameters json.RawMessage
+}
+
+// ToolCall is the model's request to run a tool.
+type ToolCall struct {
+	ID   string
+	Name string
+	// Arguments is a JSON object, as generated by the model.
+	Arguments string
+}
+
+// ToolFunc runs a tool with the arguments the model supplied and returns the
+// result to send back to it.
+type ToolFunc func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// Toolbox holds the tools offered to the model and runs the calls it makes.
+// It is safe for concurrent use.
+type Toolbox struct {
+	mu    sync.RWMutex
+	tools []Tool
+	funcs map[string]ToolFunc
+}
+
+func NewToolbox() *Toolbox {
+	return &Toolbox{funcs: make(map[string]ToolFunc)}
+}
+
+// Register offers tool to the model, run by fn. Registering a name again
+// replaces the earlier tool.
+func (t *Toolbox) Register(tool Tool, fn ToolFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.funcs[tool.Name]; exists {
+		for i := range t.tools {
+			if t.tools[i].Name == tool.Name {
+				t.tools[i] = tool
+			}
+		}
+	} else {
+		t.tools = append(t.tools, tool)
+	}
+	t.funcs[tool.Name] = fn
+}
+
+// Tools returns the registered tools in registration order.
+func (t *Toolbox) Tools() []Tool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]Tool(nil), t.tools...)
+}
+
+// Run executes call and returns the "tool" message answering it. Unknown
+// tools, malformed arguments and failures are reported to the model in the
+// message rather than returned, so it can correct itself or answer anyway.
+func (t *Toolbox) Run(ctx context.Context, call ToolCall) Message {
+	result := Message{Role: "tool", ToolCallID: call.ID}
+
+	t.mu.RLock()
+	fn, ok := t.funcs[call.Name]
+	t.mu.RUnlock()
+	if !ok {
+		result.Content = fmt.Sprintf("error: unknown tool %q", call.Name)
+		return result
+	}
+
+	arguments := json.RawMessage(call.Arguments)
+	if call.Arguments == "" {
+		arguments = json.RawMessage("{}")
+	}
+	if !json.Valid(arguments) {
+		result.Content = "error: arguments are not valid JSON"
+		return result
+	}
+
+	content, err := fn(ctx, arguments)
+	if err != nil {
+		result.Content = fmt.Sprintf("error: %v", err)
+		return result
+	}
+	result.Content = content
+	return result
+}
@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestToolbox(t *testing.T) {
+	toolbox := NewToolbox()
+	echo := func(ctx context.Context, arguments json.RawMessage) (string, error) {
+		var args struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return "", err
+		}
+		return args.Text, nil
+	}
+	toolbox.Register(Tool{Name: "echo", Description: "old"}, echo)
+	toolbox.Register(Tool{Name: "fail"}, func(ctx context.Context, arguments json.RawMessage) (string, error) {
+		return "", errors.New("out of order")
+	})
+	toolbox.Register(Tool{Name: "echo", Description: "Repeat text"}, echo)
+
+	tools := toolbox.Tools()
+	if len(tools) != 2 || tools[0].Description != "Repeat text" {
+		t.Fatalf("Expected echo replaced in place, got %+v", tools)
+	}
+
+	tests := []struct {
+		name string
+		call ToolCall
+		want string
+	}{
+		{"Runs", ToolCall{ID: "1", Name: "echo", Arguments: `{"text":"hi"}`}, "hi"},
+		{"NoArguments", ToolCall{ID: "2", Name: "echo"}, ""},
+		{"Unknown", ToolCall{ID: "3", Name: "delete_everything"}, `error: unknown tool "delete_everything"`},
+		{"InvalidArguments", ToolCall{ID: "4", Name: "echo", Arguments: `{"text":`}, "error: arguments are not valid JSON"},
+		{"Fails", ToolCall{ID: "5", Name: "fail", Arguments: `{}`}, "error: out of order"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := toolbox.Run(context.Background(), tt.call)
+			if result.Role != "tool" || result.ToolCallID != tt.call.ID {
+				t.Errorf("Expected a tool message answering %s, got %+v", tt.call.ID, result)
+			}
+			if result.Content != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, result.Content)
+			}
+		})
+	}
+}
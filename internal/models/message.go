@@ -7,7 +7,44 @@ type Message struct {
 	UserID    string    `json:"user_id" db:"user_id"`
 	Content   string    `json:"content" db:"content"`
 	Role      string    `json:"role" db:"role"`
+	WAMID     string    `json:"wamid,omitempty" db:"wamid"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// SelectionID is the ID of the reply button or list row the user tapped
+	SelectionID string `json:"selection_id,omitempty" db:"selection_id"`
+
+	// ToolCalls are the tools an assistant message asked to run, and
+	// ToolCallID the call a "tool" message holds the result of
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty" db:"tool_calls"`
+	ToolCallID string     `json:"tool_call_id,omitempty" db:"tool_call_id"`
+
+	// Description is the model's account of an image the message carried
+	Description string `json:"description,omitempty" db:"description"`
+	// FromAudio marks a message whose content was transcribed from a voice note
+	FromAudio bool `json:"from_audio,omitempty" db:"from_audio"`
+
+	// Delivery state of outbound messages, from webhook status events
+	DeliveryStatus    string `json:"delivery_status,omitempty" db:"delivery_status"`
+	DeliveryErrorCode int    `json:"delivery_error_code,omitempty" db:"delivery_error_code"`
+	DeliveryError     string `json:"delivery_error,omitempty" db:"delivery_error"`
+}
+
+// ToolCall is a model's request to run a tool, stored with the message that made it.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// MessageStatus is one delivery status event for an outbound message.
+type MessageStatus struct {
+	WAMID        string    `json:"wamid" db:"wamid"`
+	RecipientID  string    `json:"recipient_id" db:"recipient_id"`
+	Status       string    `json:"status" db:"status"`
+	ErrorCode    int       `json:"error_code,omitempty" db:"error_code"`
+	ErrorTitle   string    `json:"error_title,omitempty" db:"error_title"`
+	ErrorDetails string    `json:"error_details,omitempty" db:"error_details"`
+	Timestamp    time.Time `json:"timestamp" db:"status_at"`
 }
 
 type User struct {
@@ -16,6 +53,9 @@ type User struct {
 	Name        string    `json:"name" db:"name"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	LastSeen    time.Time `json:"last_seen" db:"last_seen"`
+	// Tier and Tags segment users, e.g. for picking the model that answers them.
+	Tier string   `json:"tier,omitempty" db:"tier"`
+	Tags []string `json:"tags,omitempty" db:"tags"`
 }
 
 type ChatRequest struct {
@@ -38,33 +78,95 @@ type HistoryResponse struct {
 	UserID   string    `json:"user_id"`
 }
 
-type WhatsAppMessage struct {
-	From      string    `json:"from"`
-	ID        string    `json:"id"`
-	Text      string    `json:"text"`
-	Timestamp time.Time `json:"timestamp"`
-	Type      string    `json:"type"`
+// InboundJob is a webhook payload waiting to be processed by the inbound workers.
+type InboundJob struct {
+	ID        int64     `json:"id" db:"id"`
+	Payload   []byte    `json:"payload" db:"payload"`
+	Status    string    `json:"status" db:"status"`
+	Attempts  int       `json:"attempts" db:"attempts"`
+	LastError string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
-type WhatsAppContact struct {
-	Profile struct {
-		Name string `json:"name"`
-	} `json:"profile"`
-	WaID string `json:"wa_id"`
+// Persona is a named assistant configuration: the system prompt and generation
+// settings used for the users or business numbers it is assigned to.
+type Persona struct {
+	ID           int64     `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	SystemPrompt string    `json:"system_prompt" db:"system_prompt"`
+	Temperature  *float64  `json:"temperature,omitempty" db:"temperature"` // nil uses the server default
+	MaxTokens    int       `json:"max_tokens,omitempty" db:"max_tokens"`   // 0 uses LLM_REPLY_TOKENS
+	Model        string    `json:"model,omitempty" db:"model"`             // empty uses the provider's model
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
-type WhatsAppWebhookEntry struct {
-	ID      string `json:"id"`
-	Changes []struct {
-		Value struct {
-			Messages []WhatsAppMessage `json:"messages"`
-			Contacts []WhatsAppContact `json:"contacts"`
-		} `json:"value"`
-		Field string `json:"field"`
-	} `json:"changes"`
+// Kinds of model usage.
+const (
+	UsageKindReply       = "reply"
+	UsageKindSummary     = "summary"
+	UsageKindDescription = "description"
+	UsageKindGuardrail   = "guardrail"
+	UsageKindExtract     = "extract"
+)
+
+// MessageUsage is what generating an assistant message consumed, summed over
+// all the model calls it took, or what another model call made for a user,
+// such as a summary, consumed.
+type MessageUsage struct {
+	// MessageID is 0 for calls that produced no message
+	MessageID int64 `json:"message_id,omitempty" db:"message_id"`
+	// Kind is one of the UsageKind constants
+	Kind             string `json:"kind" db:"kind"`
+	UserID           string `json:"user_id" db:"user_id"`
+	Model            string `json:"model" db:"model"`
+	PromptTokens     int    `json:"prompt_tokens" db:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens" db:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens" db:"total_tokens"`
+	LatencyMs        int64  `json:"latency_ms" db:"latency_ms"`
+	FinishReason     string `json:"finish_reason" db:"finish_reason"`
+	// CostUSD is nil when the model has no configured price
+	CostUSD *float64 `json:"cost_usd,omitempty" db:"cost_usd"`
+	// Estimated marks token counts estimated from the text because the
+	// provider reported none, as some do for streamed replies
+	Estimated bool      `json:"estimated,omitempty" db:"estimated"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// UsageSummary totals the usage of one user, day or model.
+type UsageSummary struct {
+	Key              string  `json:"key"`
+	Messages         int     `json:"messages"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	// UnpricedMessages counts messages from models without a configured price
+	UnpricedMessages int `json:"unpriced_messages"`
+	// EstimatedMessages counts messages whose token counts are estimated
+	EstimatedMessages int   `json:"estimated_messages"`
+	AvgLatencyMs      int64 `json:"avg_latency_ms"`
 }
 
-type WhatsAppWebhook struct {
-	Object string                 `json:"object"`
-	Entry  []WhatsAppWebhookEntry `json:"entry"`
+// Summary condenses the older part of a user's conversation so that it can
+// stand in for those messages in the prompt.
+type Summary struct {
+	UserID  string `json:"user_id" db:"user_id"`
+	Content string `json:"content" db:"content"`
+	// ThroughMessageID is the newest message the summary covers
+	ThroughMessageID int64     `json:"through_message_id" db:"through_message_id"`
+	MessageCount     int       `json:"message_count" db:"message_count"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// StoredMedia is a media file received with an inbound WhatsApp message.
+type StoredMedia struct {
+	WAMID     string    `json:"wamid" db:"wamid"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	MediaID   string    `json:"media_id" db:"media_id"`
+	MimeType  string    `json:"mime_type" db:"mime_type"`
+	SHA256    string    `json:"sha256,omitempty" db:"sha256"`
+	Data      []byte    `json:"-" db:"data"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,385 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WhatsAppMessage is an inbound message as delivered by the Cloud API webhook.
+// Exactly one of the typed fields is set, selected by Type; use Content to get
+// it as a MessageContent.
+type WhatsAppMessage struct {
+	From      string                  `json:"from"`
+	ID        string                  `json:"id"`
+	Timestamp string                  `json:"timestamp"`
+	Type      string                  `json:"type"`
+	Context   *WhatsAppMessageContext `json:"context,omitempty"`
+
+	Text        *TextContent        `json:"text,omitempty"`
+	Image       *ImageContent       `json:"image,omitempty"`
+	Audio       *AudioContent       `json:"audio,omitempty"`
+	Video       *VideoContent       `json:"video,omitempty"`
+	Document    *DocumentContent    `json:"document,omitempty"`
+	Sticker     *StickerContent     `json:"sticker,omitempty"`
+	Location    *LocationContent    `json:"location,omitempty"`
+	Contacts    ContactsContent     `json:"contacts,omitempty"`
+	Interactive *InteractiveContent `json:"interactive,omitempty"`
+	Button      *ButtonContent      `json:"button,omitempty"`
+	Reaction    *ReactionContent    `json:"reaction,omitempty"`
+	Errors      []WhatsAppError     `json:"errors,omitempty"`
+}
+
+// WhatsAppMessageContext links a message to the one it quotes or replies to.
+type WhatsAppMessageContext struct {
+	From string `json:"from"`
+	ID   string `json:"id"`
+}
+
+// WhatsAppError is an error object as reported in webhook messages and statuses.
+type WhatsAppError struct {
+	Code      int    `json:"code"`
+	Title     string `json:"title"`
+	Message   string `json:"message,omitempty"`
+	ErrorData struct {
+		Details string `json:"details"`
+	} `json:"error_data,omitempty"`
+}
+
+// Time returns the message timestamp, sent by the API as Unix seconds in a string.
+func (m WhatsAppMessage) Time() time.Time {
+	seconds, err := strconv.ParseInt(m.Timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// Content returns the typed body of the message. Messages of an unknown type,
+// or whose body is missing, are returned as UnsupportedContent.
+func (m WhatsAppMessage) Content() MessageContent {
+	switch {
+	case m.Type == "text" && m.Text != nil:
+		return *m.Text
+	case m.Type == "image" && m.Image != nil:
+		return *m.Image
+	case m.Type == "audio" && m.Audio != nil:
+		return *m.Audio
+	case m.Type == "video" && m.Video != nil:
+		return *m.Video
+	case m.Type == "document" && m.Document != nil:
+		return *m.Document
+	case m.Type == "sticker" && m.Sticker != nil:
+		return *m.Sticker
+	case m.Type == "location" && m.Location != nil:
+		return *m.Location
+	case m.Type == "contacts" && len(m.Contacts) > 0:
+		return m.Contacts
+	case m.Type == "interactive" && m.Interactive != nil:
+		return *m.Interactive
+	case m.Type == "button" && m.Button != nil:
+		return *m.Button
+	case m.Type == "reaction" && m.Reaction != nil:
+		return *m.Reaction
+	default:
+		return UnsupportedContent{Type: m.Type, Errors: m.Errors}
+	}
+}
+
+// MessageContent is the typed body of an inbound WhatsApp message. It is
+// implemented only by the content types in this package.
+type MessageContent interface {
+	// Kind returns the WhatsApp message type, such as "text" or "image".
+	Kind() string
+	// PlainText renders the content as text for the chat history.
+	PlainText() string
+
+	isMessageContent()
+}
+
+type TextContent struct {
+	Body string `json:"body"`
+}
+
+// Media identifies an uploaded media object; download it through the Graph media endpoint.
+type Media struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	SHA256   string `json:"sha256,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+}
+
+type ImageContent struct {
+	Media
+}
+
+type AudioContent struct {
+	Media
+	Voice bool `json:"voice,omitempty"`
+}
+
+type VideoContent struct {
+	Media
+}
+
+type DocumentContent struct {
+	Media
+	Filename string `json:"filename,omitempty"`
+}
+
+type StickerContent struct {
+	Media
+	Animated bool `json:"animated,omitempty"`
+}
+
+type LocationContent struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+	URL       string  `json:"url,omitempty"`
+}
+
+// ContactsContent is one or more shared contact cards.
+type ContactsContent []ContactCard
+
+type ContactCard struct {
+	Name struct {
+		FormattedName string `json:"formatted_name"`
+		FirstName     string `json:"first_name,omitempty"`
+		LastName      string `json:"last_name,omitempty"`
+	} `json:"name"`
+	Phones []struct {
+		Phone string `json:"phone"`
+		WaID  string `json:"wa_id,omitempty"`
+		Type  string `json:"type,omitempty"`
+	} `json:"phones,omitempty"`
+	Emails []struct {
+		Email string `json:"email"`
+		Type  string `json:"type,omitempty"`
+	} `json:"emails,omitempty"`
+	Org struct {
+		Company string `json:"company,omitempty"`
+	} `json:"org,omitempty"`
+}
+
+// InteractiveContent is the user's answer to an interactive message; Type is
+// "button_reply" or "list_reply".
+type InteractiveContent struct {
+	Type        string          `json:"type"`
+	ButtonReply *ReplySelection `json:"button_reply,omitempty"`
+	ListReply   *ReplySelection `json:"list_reply,omitempty"`
+}
+
+// ReplySelection is the button or list row a user picked.
+type ReplySelection struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// Selection returns the chosen button or list row, or nil if neither is set.
+func (c InteractiveContent) Selection() *ReplySelection {
+	if c.ButtonReply != nil {
+		return c.ButtonReply
+	}
+	return c.ListReply
+}
+
+// ButtonContent is a tap on a quick-reply button of a template message.
+type ButtonContent struct {
+	Payload string `json:"payload"`
+	Text    string `json:"text"`
+}
+
+// ReactionContent is an emoji reaction; an empty Emoji removes the reaction.
+type ReactionContent struct {
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+// UnsupportedContent stands in for message types this server does not understand.
+type UnsupportedContent struct {
+	Type   string
+	Errors []WhatsAppError
+}
+
+func (TextContent) Kind() string        { return "text" }
+func (ImageContent) Kind() string       { return "image" }
+func (AudioContent) Kind() string       { return "audio" }
+func (VideoContent) Kind() string       { return "video" }
+func (DocumentContent) Kind() string    { return "document" }
+func (StickerContent) Kind() string     { return "sticker" }
+func (LocationContent) Kind() string    { return "location" }
+func (ContactsContent) Kind() string    { return "contacts" }
+func (InteractiveContent) Kind() string { return "interactive" }
+func (ButtonContent) Kind() string      { return "button" }
+func (ReactionContent) Kind() string    { return "reaction" }
+func (UnsupportedContent) Kind() string { return "unsupported" }
+
+func (TextContent) isMessageContent()        {}
+func (ImageContent) isMessageContent()       {}
+func (AudioContent) isMessageContent()       {}
+func (VideoContent) isMessageContent()       {}
+func (DocumentContent) isMessageContent()    {}
+func (StickerContent) isMessageContent()     {}
+func (LocationContent) isMessageContent()    {}
+func (ContactsContent) isMessageContent()    {}
+func (InteractiveContent) isMessageContent() {}
+func (ButtonContent) isMessageContent()      {}
+func (ReactionContent) isMessageContent()    {}
+func (UnsupportedContent) isMessageContent() {}
+
+func (c TextContent) PlainText() string {
+	return c.Body
+}
+
+func (c ImageContent) PlainText() string {
+	return withCaption("[Image]", c.Caption)
+}
+
+func (c AudioContent) PlainText() string {
+	if c.Voice {
+		return "[Voice note]"
+	}
+	return "[Audio]"
+}
+
+func (c VideoContent) PlainText() string {
+	return withCaption("[Video]", c.Caption)
+}
+
+func (c DocumentContent) PlainText() string {
+	label := "[Document]"
+	if c.Filename != "" {
+		label = fmt.Sprintf("[Document: %s]", c.Filename)
+	}
+	return withCaption(label, c.Caption)
+}
+
+func (c StickerContent) PlainText() string {
+	return "[Sticker]"
+}
+
+func (c LocationContent) PlainText() string {
+	place := strings.Join(nonEmpty(c.Name, c.Address), ", ")
+	if place == "" {
+		return fmt.Sprintf("[Location: %.6f, %.6f]", c.Latitude, c.Longitude)
+	}
+	return fmt.Sprintf("[Location: %s (%.6f, %.6f)]", place, c.Latitude, c.Longitude)
+}
+
+func (c ContactsContent) PlainText() string {
+	cards := make([]string, 0, len(c))
+	for _, card := range c {
+		details := []string{card.Name.FormattedName}
+		for _, phone := range card.Phones {
+			details = append(details, phone.Phone)
+		}
+		cards = append(cards, strings.Join(nonEmpty(details...), " "))
+	}
+	return fmt.Sprintf("[Contact: %s]", strings.Join(cards, "; "))
+}
+
+func (c InteractiveContent) PlainText() string {
+	selection := c.Selection()
+	if selection == nil {
+		return "[Interactive reply]"
+	}
+	return selection.Title
+}
+
+func (c ButtonContent) PlainText() string {
+	return c.Text
+}
+
+func (c ReactionContent) PlainText() string {
+	if c.Emoji == "" {
+		return "[Removed reaction]"
+	}
+	return fmt.Sprintf("[Reacted %s]", c.Emoji)
+}
+
+func (c UnsupportedContent) PlainText() string {
+	if c.Type == "" || c.Type == "unsupported" {
+		return "[Unsupported message]"
+	}
+	return fmt.Sprintf("[Unsupported message: %s]", c.Type)
+}
+
+func withCaption(label, caption string) string {
+	if caption == "" {
+		return label
+	}
+	return label + " " + caption
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// WhatsAppStatus reports the delivery state of a message we sent: "sent",
+// "delivered", "read" or "failed".
+type WhatsAppStatus struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	Timestamp    string `json:"timestamp"`
+	RecipientID  string `json:"recipient_id"`
+	Conversation *struct {
+		ID     string `json:"id"`
+		Origin struct {
+			Type string `json:"type"`
+		} `json:"origin"`
+	} `json:"conversation,omitempty"`
+	Pricing *struct {
+		Billable     bool   `json:"billable"`
+		PricingModel string `json:"pricing_model"`
+		Category     string `json:"category"`
+	} `json:"pricing,omitempty"`
+	Errors []WhatsAppError `json:"errors,omitempty"`
+}
+
+// Time returns the status timestamp, sent by the API as Unix seconds in a string.
+func (s WhatsAppStatus) Time() time.Time {
+	seconds, err := strconv.ParseInt(s.Timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+type WhatsAppContact struct {
+	Profile struct {
+		Name string `json:"name"`
+	} `json:"profile"`
+	WaID string `json:"wa_id"`
+}
+
+type WhatsAppWebhookEntry struct {
+	ID      string `json:"id"`
+	Changes []struct {
+		Value struct {
+			MessagingProduct string `json:"messaging_product"`
+			Metadata         struct {
+				DisplayPhoneNumber string `json:"display_phone_number"`
+				PhoneNumberID      string `json:"phone_number_id"`
+			} `json:"metadata"`
+			Messages []WhatsAppMessage `json:"messages"`
+			Contacts []WhatsAppContact `json:"contacts"`
+			Statuses []WhatsAppStatus  `json:"statuses"`
+		} `json:"value"`
+		Field string `json:"field"`
+	} `json:"changes"`
+}
+
+type WhatsAppWebhook struct {
+	Object string                 `json:"object"`
+	Entry  []WhatsAppWebhookEntry `json:"entry"`
+}
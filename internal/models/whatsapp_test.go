@@ -0,0 +1,106 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWhatsAppMessageContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		wantKind string
+		wantText string
+	}{
+		{
+			name:     "Text",
+			payload:  `{"type":"text","text":{"body":"Hello"}}`,
+			wantKind: "text",
+			wantText: "Hello",
+		},
+		{
+			name:     "ImageWithCaption",
+			payload:  `{"type":"image","image":{"id":"123","mime_type":"image/jpeg","caption":"Broken lid"}}`,
+			wantKind: "image",
+			wantText: "[Image] Broken lid",
+		},
+		{
+			name:     "VoiceNote",
+			payload:  `{"type":"audio","audio":{"id":"456","mime_type":"audio/ogg; codecs=opus","voice":true}}`,
+			wantKind: "audio",
+			wantText: "[Voice note]",
+		},
+		{
+			name:     "Document",
+			payload:  `{"type":"document","document":{"id":"789","filename":"invoice.pdf"}}`,
+			wantKind: "document",
+			wantText: "[Document: invoice.pdf]",
+		},
+		{
+			name:     "Location",
+			payload:  `{"type":"location","location":{"latitude":52.52,"longitude":13.405,"name":"Office"}}`,
+			wantKind: "location",
+			wantText: "[Location: Office (52.520000, 13.405000)]",
+		},
+		{
+			name:     "Contacts",
+			payload:  `{"type":"contacts","contacts":[{"name":{"formatted_name":"Jo Doe"},"phones":[{"phone":"+1 555 0100"}]}]}`,
+			wantKind: "contacts",
+			wantText: "[Contact: Jo Doe +1 555 0100]",
+		},
+		{
+			name:     "ButtonReply",
+			payload:  `{"type":"interactive","interactive":{"type":"button_reply","button_reply":{"id":"yes","title":"Yes"}}}`,
+			wantKind: "interactive",
+			wantText: "Yes",
+		},
+		{
+			name:     "TemplateButton",
+			payload:  `{"type":"button","button":{"payload":"STOP","text":"Stop promotions"}}`,
+			wantKind: "button",
+			wantText: "Stop promotions",
+		},
+		{
+			name:     "Reaction",
+			payload:  `{"type":"reaction","reaction":{"message_id":"wamid.1","emoji":"👍"}}`,
+			wantKind: "reaction",
+			wantText: "[Reacted 👍]",
+		},
+		{
+			name:     "Unsupported",
+			payload:  `{"type":"unsupported","errors":[{"code":131051,"title":"Message type unknown"}]}`,
+			wantKind: "unsupported",
+			wantText: "[Unsupported message]",
+		},
+		{
+			name:     "MissingBody",
+			payload:  `{"type":"image"}`,
+			wantKind: "unsupported",
+			wantText: "[Unsupported message: image]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var message WhatsAppMessage
+			if err := json.Unmarshal([]byte(tt.payload), &message); err != nil {
+				t.Fatalf("Failed to decode payload: %v", err)
+			}
+
+			content := message.Content()
+			if content.Kind() != tt.wantKind {
+				t.Errorf("Expected kind %s, got %s", tt.wantKind, content.Kind())
+			}
+			if content.PlainText() != tt.wantText {
+				t.Errorf("Expected text %q, got %q", tt.wantText, content.PlainText())
+			}
+		})
+	}
+}
+
+func TestWhatsAppMessageTime(t *testing.T) {
+	message := WhatsAppMessage{Timestamp: "1700000000"}
+	if got := message.Time().Unix(); got != 1700000000 {
+		t.Errorf("Expected 1700000000, got %d", got)
+	}
+}
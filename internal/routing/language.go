@@ -0,0 +1,126 @@
+package routing
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scripts maps writing systems used by essentially one language to its ISO
+// 639-1 code. Han is Chinese unless kana show it to be Japanese.
+var scripts = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+	{unicode.Cyrillic, "ru"},
+}
+
+// stopwords are frequent short words of languages written in Latin script,
+// which tell them apart in even a short message.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "my", "to", "of", "it", "what", "how", "can", "this", "i", "with", "for", "have", "please", "where", "when"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "es", "mi", "por", "para", "con", "una", "un", "no", "hola", "gracias", "cómo", "dónde", "está"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "je", "vous", "mon", "ma", "pour", "avec", "une", "un", "pas", "bonjour", "merci", "où", "qui"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "sie", "mein", "meine", "nicht", "mit", "für", "ein", "eine", "wie", "wo", "hallo", "danke", "bitte", "zu"},
+	"it": {"il", "lo", "la", "gli", "di", "che", "e", "è", "sono", "mio", "mia", "per", "con", "una", "un", "non", "ciao", "grazie", "dove", "come"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "é", "meu", "minha", "por", "para", "com", "uma", "um", "não", "olá", "obrigado", "onde", "como"},
+	"nl": {"de", "het", "een", "en", "is", "ik", "je", "mijn", "niet", "met", "voor", "van", "dat", "hoe", "waar", "hallo", "bedankt", "alsjeblieft", "wat", "zijn"},
+}
+
+// stopwordLanguages indexes stopwords by word.
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for language, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], language)
+		}
+	}
+	return index
+}()
+
+// DetectLanguage guesses the ISO 639-1 code of the language text is written
+// in, or returns "" when it cannot tell. Scripts identify Japanese, Korean,
+// Chinese, Arabic, Hebrew, Greek, Hindi, Thai and Russian (for any Cyrillic
+// except Ukrainian); English, Spanish, French, German, Italian, Portuguese
+// and Dutch are told apart by their most frequent words.
+func DetectLanguage(text string) string {
+	if language := detectScript(text); language != "" {
+		return language
+	}
+
+	scores := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		for _, language := range stopwordLanguages[word] {
+			scores[language]++
+		}
+	}
+
+	best, bestScore, tied := "", 0, false
+	for language, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = language, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if tied {
+		return ""
+	}
+	return best
+}
+
+// detectScript returns the language of the non-Latin script most letters of
+// text are written in, or "" if most are Latin or there are none.
+func detectScript(text string) string {
+	counts := make(map[string]int)
+	latin := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[script.language]++
+				break
+			}
+		}
+		// Letters only Ukrainian uses among Cyrillic languages
+		if strings.ContainsRune("іїєґІЇЄҐ", r) {
+			counts["uk"]++
+		}
+	}
+
+	// Kanji with any kana is Japanese
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	if counts["uk"] > 0 {
+		counts["uk"] = counts["ru"]
+		delete(counts, "ru")
+	}
+
+	best, bestCount := "", latin
+	for language, count := range counts {
+		if count > bestCount {
+			best, bestCount = language, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,242 @@
+// Package routing picks the language model that answers a message from
+// rules on the message and its sender, configured in LLM_ROUTES.
+//
+// Rules are separated by semicolons and tried in order; the first whose
+// conditions all hold names the model:
+//
+//	tier=premium => grok:grok-2; length>400 & lang=en => grok:grok-2; lang=de|fr => ollama:mistral
+//
+// Conditions are joined with "&" and compare a fact with "=" (one of values
+// separated by "|") or, for length, with "<", "<=", ">" or ">=". The facts
+// are length (of the message, in characters), lang (its detected language,
+// see DetectLanguage), tier, tag (any of the user's tags) and persona (the
+// persona's name). "*" matches every message.
+package routing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Providers a target may name, as accepted in LLM_PROVIDER.
+var providers = []string{"grok", "openai", "ollama"}
+
+// Target is a model on a provider.
+type Target struct {
+	// Provider is "" for the default provider.
+	Provider string
+	Model    string
+}
+
+// ParseTarget parses "[provider:]model". The prefix is only taken for a
+// provider if it names one, so Ollama models such as "llama3:8b" need none.
+func ParseTarget(s string) (Target, error) {
+	s = strings.TrimSpace(s)
+	if prefix, model, ok := strings.Cut(s, ":"); ok {
+		for _, provider := range providers {
+			if strings.EqualFold(prefix, provider) {
+				if model = strings.TrimSpace(model); model == "" {
+					return Target{}, fmt.Errorf("missing model after %q", prefix+":")
+				}
+				return Target{Provider: provider, Model: model}, nil
+			}
+		}
+	}
+	if s == "" {
+		return Target{}, fmt.Errorf("missing model")
+	}
+	return Target{Model: s}, nil
+}
+
+func (t Target) String() string {
+	if t.Provider == "" {
+		return t.Model
+	}
+	return t.Provider + ":" + t.Model
+}
+
+// Facts describe a message to route.
+type Facts struct {
+	// Message is the user's message; its length and language are derived from it.
+	Message string
+	Tier    string
+	Tags    []string
+	Persona string
+}
+
+// Rule routes the messages matching all its conditions to Target.
+type Rule struct {
+	Text       string
+	conditions []condition
+	Target     Target
+}
+
+type condition struct {
+	fact   string
+	op     string
+	values []string
+	number int
+}
+
+// Router holds the rules in order.
+type Router struct {
+	rules []Rule
+}
+
+// Parse parses rules in the LLM_ROUTES syntax; an empty string has none.
+func Parse(rules string) (*Router, error) {
+	router := &Router{}
+	for _, text := range strings.Split(rules, ";") {
+		if text = strings.TrimSpace(text); text == "" {
+			continue
+		}
+		rule, err := parseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %q: %w", text, err)
+		}
+		router.rules = append(router.rules, rule)
+	}
+	return router, nil
+}
+
+func parseRule(text string) (Rule, error) {
+	when, target, ok := strings.Cut(text, "=>")
+	if !ok {
+		return Rule{}, fmt.Errorf("want conditions => [provider:]model")
+	}
+
+	rule := Rule{Text: text}
+	var err error
+	if rule.Target, err = ParseTarget(target); err != nil {
+		return Rule{}, err
+	}
+
+	for _, part := range strings.Split(when, "&") {
+		part = strings.TrimSpace(part)
+		if part == "*" {
+			continue
+		}
+		c, err := parseCondition(part)
+		if err != nil {
+			return Rule{}, err
+		}
+		rule.conditions = append(rule.conditions, c)
+	}
+	return rule, nil
+}
+
+func parseCondition(text string) (condition, error) {
+	// Two-character operators first so "<=" is not read as "<"
+	for _, op := range []string{"<=", ">=", "<", ">", "="} {
+		fact, value, ok := strings.Cut(text, op)
+		if !ok {
+			continue
+		}
+		c := condition{fact: strings.ToLower(strings.TrimSpace(fact)), op: op}
+		value = strings.TrimSpace(value)
+
+		switch c.fact {
+		case "length":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return condition{}, fmt.Errorf("length must be compared with a number, got %q", value)
+			}
+			c.number = n
+		case "lang", "tier", "tag", "persona":
+			if op != "=" {
+				return condition{}, fmt.Errorf("%s can only be compared with =", c.fact)
+			}
+			for _, v := range strings.Split(value, "|") {
+				if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+					c.values = append(c.values, v)
+				}
+			}
+			if len(c.values) == 0 {
+				return condition{}, fmt.Errorf("missing value for %s", c.fact)
+			}
+		default:
+			return condition{}, fmt.Errorf("unknown fact %q (want length, lang, tier, tag or persona)", c.fact)
+		}
+		return c, nil
+	}
+	return condition{}, fmt.Errorf("condition %q has no comparison", text)
+}
+
+// Rules returns the rules in order.
+func (r *Router) Rules() []Rule {
+	return r.rules
+}
+
+// Route returns the first rule matching facts, or false if none does.
+func (r *Router) Route(facts Facts) (Rule, bool) {
+	m := matcher{facts: facts}
+	for _, rule := range r.rules {
+		if m.matches(rule) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// matcher evaluates conditions on facts, detecting the language at most once.
+type matcher struct {
+	facts    Facts
+	language *string
+}
+
+func (m *matcher) matches(rule Rule) bool {
+	for _, c := range rule.conditions {
+		if !m.holds(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *matcher) holds(c condition) bool {
+	switch c.fact {
+	case "length":
+		n := utf8.RuneCountInString(m.facts.Message)
+		switch c.op {
+		case "<":
+			return n < c.number
+		case "<=":
+			return n <= c.number
+		case ">":
+			return n > c.number
+		case ">=":
+			return n >= c.number
+		default:
+			return n == c.number
+		}
+	case "lang":
+		if m.language == nil {
+			language := DetectLanguage(m.facts.Message)
+			m.language = &language
+		}
+		return c.oneOf(*m.language)
+	case "tier":
+		return c.oneOf(m.facts.Tier)
+	case "persona":
+		return c.oneOf(m.facts.Persona)
+	case "tag":
+		for _, tag := range m.facts.Tags {
+			if c.oneOf(tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c condition) oneOf(value string) bool {
+	value = strings.ToLower(value)
+	for _, v := range c.values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package routing
+
+import "testing"
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Target
+		wantErr bool
+	}{
+		{"grok:grok-2", Target{Provider: "grok", Model: "grok-2"}, false},
+		{"Ollama:llama3:8b", Target{Provider: "ollama", Model: "llama3:8b"}, false},
+		{"llama3:8b", Target{Model: "llama3:8b"}, false},
+		{" grok-mini ", Target{Model: "grok-mini"}, false},
+		{"openai:", Target{}, true},
+		{"", Target{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTarget(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRoute(t *testing.T) {
+	router, err := Parse("tier=premium => grok:grok-2; tag=vip|beta & length>=20 => openai:gpt-4o; lang=de => ollama:mistral; persona=Support => grok-mini; length>100 => grok-2; * => grok-mini-fast")
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		facts Facts
+		want  string
+	}{
+		{"Tier", Facts{Message: "Hi", Tier: "Premium"}, "grok:grok-2"},
+		{"TagAndLength", Facts{Message: "Can you compare these two plans?", Tags: []string{"new", "beta"}}, "openai:gpt-4o"},
+		{"TagTooShort", Facts{Message: "Hi there", Tags: []string{"vip"}}, "grok-mini-fast"},
+		{"Language", Facts{Message: "Wo ist meine Bestellung?"}, "ollama:mistral"},
+		{"Persona", Facts{Message: "Hello", Persona: "support"}, "grok-mini"},
+		{"Length", Facts{Message: "This is a much longer question that goes into a lot of detail about what exactly went wrong with the order"}, "grok-2"},
+		{"CatchAll", Facts{Message: "Thanks!"}, "grok-mini-fast"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := router.Route(tt.facts)
+			if !ok || rule.Target.String() != tt.want {
+				t.Errorf("Expected %s, got %+v (matched %v)", tt.want, rule, ok)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"tier=premium",
+		"size>10 => grok-2",
+		"length>many => grok-2",
+		"lang>de => grok-2",
+		"tier= => grok-2",
+		"tier=premium => ",
+	}
+
+	for _, rules := range tests {
+		t.Run(rules, func(t *testing.T) {
+			if _, err := Parse(rules); err == nil {
+				t.Errorf("Expected an error for %q", rules)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Where is my order? It was supposed to arrive today.", "en"},
+		{"Hola, ¿dónde está mi pedido?", "es"},
+		{"Bonjour, où est ma commande ?", "fr"},
+		{"Hallo, wo ist meine Bestellung?", "de"},
+		{"Ciao, dove è il mio ordine?", "it"},
+		{"Olá, onde está o meu pedido? Obrigado", "pt"},
+		{"Hallo, waar is mijn bestelling?", "nl"},
+		{"Где мой заказ?", "ru"},
+		{"Де моє замовлення? Дякую, і все", "uk"},
+		{"我的订单在哪里？", "zh"},
+		{"私の注文はどこですか？", "ja"},
+		{"제 주문은 어디에 있나요?", "ko"},
+		{"أين طلبي؟", "ar"},
+		{"12345", ""},
+		{"ok", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// filePlaceholder marks where the recording's path goes in the command line.
+const filePlaceholder = "{file}"
+
+// Command transcribes by running a local program, such as whisper.cpp's
+// whisper-cli, on a temporary copy of the recording and reading the text it
+// prints. The path replaces {file} in the arguments, or is appended if there
+// is none; the command line is split on spaces and not run through a shell.
+type Command struct {
+	path string
+	args []string
+}
+
+// NewCommand parses commandLine, e.g. "whisper-cli -m ggml-base.bin -nt -np -f {file}".
+func NewCommand(commandLine string) (*Command, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty transcription command")
+	}
+
+	path, err := exec.LookPath(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("transcription command not found: %w", err)
+	}
+	return &Command{path: path, args: fields[1:]}, nil
+}
+
+func (c *Command) Name() string {
+	return ProviderCommand
+}
+
+func (c *Command) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	file, err := os.CreateTemp("", "voice-*"+extension(audio.MimeType))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(audio.Data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	args := make([]string, 0, len(c.args)+1)
+	replaced := false
+	for _, arg := range c.args {
+		if strings.Contains(arg, filePlaceholder) {
+			arg = strings.ReplaceAll(arg, filePlaceholder, file.Name())
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, file.Name())
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("transcription command failed: %w: %s", err, strings.TrimSpace(lastLine(stderr.String())))
+	}
+
+	return strings.Join(strings.Fields(stdout.String()), " "), nil
+}
+
+// lastLine returns the last non-empty line of output, where tools usually
+// put the error.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,105 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// OpenAI transcribes through an OpenAI-compatible /audio/transcriptions
+// endpoint, such as OpenAI's own or a local faster-whisper server.
+type OpenAI struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+
+	client *http.Client
+}
+
+type transcriptionResponse struct {
+	Text  string `json:"text"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewOpenAI returns a transcriber for the endpoint under baseURL, e.g.
+// "https://api.openai.com/v1". apiKey may be empty for local servers.
+func NewOpenAI(apiKey, baseURL, model string) *OpenAI {
+	return &OpenAI{
+		APIKey:  apiKey,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Model:   model,
+		client:  &http.Client{},
+	}
+}
+
+func (o *OpenAI) Name() string {
+	return ProviderOpenAI
+}
+
+func (o *OpenAI) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("model", o.Model); err != nil {
+		return "", fmt.Errorf("failed to build request: %w", err)
+	}
+	if err := writer.WriteField("response_format", "json"); err != nil {
+		return "", fmt.Errorf("failed to build request: %w", err)
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="voice%s"`, extension(audio.MimeType)))
+	header.Set("Content-Type", audio.MimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to build request: %w", err)
+	}
+	if _, err := part.Write(audio.Data); err != nil {
+		return "", fmt.Errorf("failed to build request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.BaseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach transcription endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var transcription transcriptionResponse
+	decodeErr := json.Unmarshal(respBody, &transcription)
+	if resp.StatusCode != http.StatusOK {
+		if decodeErr == nil && transcription.Error != nil {
+			return "", fmt.Errorf("transcription endpoint returned status %d: %s", resp.StatusCode, transcription.Error.Message)
+		}
+		return "", fmt.Errorf("transcription endpoint returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+
+	return strings.TrimSpace(transcription.Text), nil
+}
@@ -0,0 +1,85 @@
+// Package speech defines the interface used to transcribe inbound voice
+// notes, and the backends that implement it.
+package speech
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+)
+
+// Transcriber turns recorded speech into text.
+type Transcriber interface {
+	// Name identifies the backend, e.g. "command" or "openai".
+	Name() string
+	// Transcribe returns the text spoken in audio.
+	Transcribe(ctx context.Context, audio Audio) (string, error)
+}
+
+// Audio is a recording to transcribe, such as a WhatsApp voice note
+// (audio/ogg with the Opus codec).
+type Audio struct {
+	MimeType string
+	Data     []byte
+}
+
+// Transcriber names accepted in SPEECH_PROVIDER.
+const (
+	ProviderCommand = "command"
+	ProviderOpenAI  = "openai"
+	ProviderNone    = "none"
+)
+
+// DefaultModel is the transcription model requested from OpenAI-compatible endpoints.
+const DefaultModel = "whisper-1"
+
+// New creates the transcriber selected by config.SpeechProvider. It returns
+// nil without an error when none is configured, in which case voice notes
+// are stored as placeholders.
+func New(config *configs.Config) (Transcriber, error) {
+	switch name := strings.ToLower(config.SpeechProvider); name {
+	case "", ProviderNone:
+		return nil, nil
+
+	case ProviderCommand:
+		if config.SpeechCommand == "" {
+			return nil, fmt.Errorf("the command transcriber needs SPEECH_COMMAND")
+		}
+		return NewCommand(config.SpeechCommand)
+
+	case ProviderOpenAI:
+		if config.SpeechBaseURL == "" {
+			return nil, fmt.Errorf("the openai transcriber needs SPEECH_BASE_URL")
+		}
+		model := config.SpeechModel
+		if model == "" {
+			model = DefaultModel
+		}
+		return NewOpenAI(config.SpeechAPIKey, config.SpeechBaseURL, model), nil
+
+	default:
+		return nil, fmt.Errorf("unknown SPEECH_PROVIDER %q (want command, openai or none)", config.SpeechProvider)
+	}
+}
+
+// extension returns the file extension for an audio MIME type, which tools
+// use to pick a decoder.
+func extension(mimeType string) string {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "audio/ogg", "audio/opus":
+		return ".ogg"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/mp4", "audio/aac", "audio/m4a":
+		return ".m4a"
+	case "audio/amr":
+		return ".amr"
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return ".wav"
+	default:
+		return ".bin"
+	}
+}
@@ -0,0 +1,129 @@
+package speech
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+)
+
+var voiceNote = Audio{MimeType: "audio/ogg; codecs=opus", Data: []byte("hello   there\nworld\n")}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   configs.Config
+		wantName string
+		wantErr  bool
+	}{
+		{"Unset", configs.Config{}, "", false},
+		{"None", configs.Config{SpeechProvider: "none", SpeechCommand: "cat"}, "", false},
+		{"Command", configs.Config{SpeechProvider: "command", SpeechCommand: "cat {file}"}, ProviderCommand, false},
+		{"CommandWithoutCommand", configs.Config{SpeechProvider: "command"}, "", true},
+		{"CommandNotFound", configs.Config{SpeechProvider: "command", SpeechCommand: "no-such-whisper"}, "", true},
+		{"OpenAI", configs.Config{SpeechProvider: "OpenAI", SpeechBaseURL: "http://localhost:8000/v1"}, ProviderOpenAI, false},
+		{"OpenAIWithoutURL", configs.Config{SpeechProvider: "openai"}, "", true},
+		{"Unknown", configs.Config{SpeechProvider: "vosk"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transcriber, err := New(&tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected configuration error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			name := ""
+			if transcriber != nil {
+				name = transcriber.Name()
+			}
+			if name != tt.wantName {
+				t.Errorf("Expected transcriber %q, got %q", tt.wantName, name)
+			}
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	// cat prints the recording back, standing in for a speech model
+	tests := []struct {
+		name        string
+		commandLine string
+		want        string
+		wantErr     bool
+	}{
+		{"Placeholder", "cat {file}", "hello there world", false},
+		{"AppendedPath", "cat", "hello there world", false},
+		{"Failure", "false", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := NewCommand(tt.commandLine)
+			if err != nil {
+				t.Fatalf("Failed to create command: %v", err)
+			}
+
+			text, err := command.Transcribe(context.Background(), voiceNote)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected the command to fail")
+				}
+				return
+			}
+			if err != nil || text != tt.want {
+				t.Errorf("Expected %q, got %q, error %v", tt.want, text, err)
+			}
+		})
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("Unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("Failed to parse upload: %v", err)
+		}
+		if got := r.FormValue("model"); got != "whisper-1" {
+			t.Errorf("Expected model whisper-1, got %s", got)
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("Expected a file, got: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "voice.ogg" || string(data) != string(voiceNote.Data) {
+			t.Errorf("Unexpected file %s: %q", header.Filename, data)
+		}
+
+		w.Write([]byte(`{"text":" Hello there, world. "}`))
+	}))
+	defer server.Close()
+
+	text, err := NewOpenAI("key", server.URL+"/v1/", "whisper-1").Transcribe(context.Background(), voiceNote)
+	if err != nil || text != "Hello there, world." {
+		t.Errorf("Expected the transcript, got %q, error %v", text, err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"Invalid file format."}}`))
+	}))
+	defer failing.Close()
+
+	if _, err := NewOpenAI("", failing.URL, "whisper-1").Transcribe(context.Background(), voiceNote); err == nil {
+		t.Error("Expected the endpoint's error")
+	}
+}
@@ -0,0 +1,81 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// reengagementErrorCode is returned for free-form messages sent more than 24
+// hours after the customer last wrote to us.
+const reengagementErrorCode = 131047
+
+// ErrReengagementRequired matches API errors that require a template message
+// because the customer service window is closed.
+var ErrReengagementRequired = errors.New("customer service window closed: send an approved template message instead")
+
+// APIError is an error response from the Graph API.
+type APIError struct {
+	StatusCode int
+	Code       int
+	Subcode    int
+	Type       string
+	Message    string
+	Details    string
+	FBTraceID  string
+}
+
+type graphErrorResponse struct {
+	Error struct {
+		Message      string `json:"message"`
+		Type         string `json:"type"`
+		Code         int    `json:"code"`
+		ErrorSubcode int    `json:"error_subcode"`
+		ErrorData    struct {
+			Details string `json:"details"`
+		} `json:"error_data"`
+		FBTraceID string `json:"fbtrace_id"`
+	} `json:"error"`
+}
+
+// parseAPIError builds an APIError from a non-200 Graph API response body.
+func parseAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+
+	var resp graphErrorResponse
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error.Code == 0 {
+		apiErr.Message = string(body)
+		return apiErr
+	}
+
+	apiErr.Code = resp.Error.Code
+	apiErr.Subcode = resp.Error.ErrorSubcode
+	apiErr.Type = resp.Error.Type
+	apiErr.Message = resp.Error.Message
+	apiErr.Details = resp.Error.ErrorData.Details
+	apiErr.FBTraceID = resp.Error.FBTraceID
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	if e.Code == reengagementErrorCode {
+		return fmt.Sprintf("WhatsApp API error %d: more than 24 hours have passed since the customer last wrote; "+
+			"free-form messages are refused, send an approved template (send_template) instead", e.Code)
+	}
+
+	msg := fmt.Sprintf("WhatsApp API returned status %d", e.StatusCode)
+	if e.Code != 0 {
+		msg = fmt.Sprintf("WhatsApp API error %d: %s", e.Code, e.Message)
+	} else if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Details != "" {
+		msg += " (" + e.Details + ")"
+	}
+	return msg
+}
+
+// Is reports whether the error matches ErrReengagementRequired.
+func (e *APIError) Is(target error) bool {
+	return target == ErrReengagementRequired && e.Code == reengagementErrorCode
+}
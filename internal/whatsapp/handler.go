@@ -2,27 +2,66 @@ package whatsapp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/conversation"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
 	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
 )
 
+const (
+	// maxWebhookBodySize caps the webhook payload read into memory for signature checks.
+	maxWebhookBodySize = 1 << 20
+	// maxErrorBodySize caps how much of a Graph API error response is read.
+	maxErrorBodySize = 64 << 10
+
+	defaultAPIURL = "https://graph.facebook.com/v18.0"
+)
+
 type Handler struct {
-	config     *configs.Config
-	httpClient *http.Client
+	config       *configs.Config
+	db           *database.DB
+	conversation *conversation.Service
+	httpClient   *http.Client
+	stats        webhookStats
+	wake         chan struct{}
+}
+
+// webhookStats counts webhook deliveries for the /stats endpoint.
+type webhookStats struct {
+	received          atomic.Int64
+	rejectedSignature atomic.Int64
+	malformed         atomic.Int64
+	queued            atomic.Int64
+	inboundMessages   atomic.Int64
+	duplicates        atomic.Int64
 }
 
 type SendMessageRequest struct {
-	MessagingProduct string `json:"messaging_product"`
-	To               string `json:"to"`
-	Type             string `json:"type"`
-	Text             struct {
-		Body string `json:"body"`
-	} `json:"text"`
+	MessagingProduct string       `json:"messaging_product"`
+	To               string       `json:"to"`
+	Type             string       `json:"type"`
+	Text             *TextBody    `json:"text,omitempty"`
+	Image            *MediaObject `json:"image,omitempty"`
+	Audio            *MediaObject `json:"audio,omitempty"`
+	Video            *MediaObject `json:"video,omitempty"`
+	Document         *MediaObject `json:"document,omitempty"`
+	Template         *Template    `json:"template,omitempty"`
+	Interactive      *Interactive `json:"interactive,omitempty"`
+}
+
+type TextBody struct {
+	Body string `json:"body"`
 }
 
 type SendMessageResponse struct {
@@ -31,10 +70,17 @@ type SendMessageResponse struct {
 	} `json:"messages"`
 }
 
-func NewHandler(config *configs.Config) *Handler {
+func NewHandler(config *configs.Config, db *database.DB, conv *conversation.Service) *Handler {
+	if config.WhatsAppAppSecret == "" {
+		log.Printf("Warning: WHATSAPP_APP_SECRET not set, webhook signatures will not be verified")
+	}
+
 	return &Handler{
-		config:     config,
-		httpClient: &http.Client{},
+		config:       config,
+		db:           db,
+		conversation: conv,
+		httpClient:   &http.Client{},
+		wake:         make(chan struct{}, 1),
 	}
 }
 
@@ -56,27 +102,93 @@ func (h *Handler) VerifyWebhook(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
-	var webhook models.WhatsAppWebhook
-	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
-		log.Printf("Error decoding webhook: %v", err)
+	h.stats.received.Add(1)
+
+	// The signature covers the exact bytes Meta sent, so keep the raw body
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		log.Printf("Error reading webhook body: %v", err)
+		h.stats.malformed.Add(1)
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
 
-	// Process webhook entries
-	for _, entry := range webhook.Entry {
-		for _, change := range entry.Changes {
-			if change.Field == "messages" {
-				h.processMessages(change.Value.Messages, change.Value.Contacts)
-			}
-		}
+	if h.config.WhatsAppAppSecret != "" && !validSignature(body, r.Header.Get(signatureHeader), h.config.WhatsAppAppSecret) {
+		log.Printf("Rejected webhook from %s: invalid or missing %s", r.RemoteAddr, signatureHeader)
+		h.stats.rejectedSignature.Add(1)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
+	if !json.Valid(body) {
+		log.Printf("Error decoding webhook: invalid JSON")
+		h.stats.malformed.Add(1)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	// Acknowledge immediately; the workers do the slow part
+	if _, err := h.db.EnqueueInboundJob(body, sender(body)); err != nil {
+		log.Printf("Error queueing webhook: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	h.stats.queued.Add(1)
+	h.notifyWorkers()
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
-func (h *Handler) processMessages(messages []models.WhatsAppMessage, contacts []models.WhatsAppContact) {
+// Stats returns webhook delivery counters.
+func (h *Handler) Stats() map[string]interface{} {
+	inbound := h.stats.inboundMessages.Load()
+	duplicates := h.stats.duplicates.Load()
+
+	dedupHitRate := 0.0
+	if inbound > 0 {
+		dedupHitRate = float64(duplicates) / float64(inbound)
+	}
+
+	return map[string]interface{}{
+		"received":           h.stats.received.Load(),
+		"rejected_signature": h.stats.rejectedSignature.Load(),
+		"malformed":          h.stats.malformed.Load(),
+		"queued":             h.stats.queued.Load(),
+		"inbound_messages":   inbound,
+		"duplicates":         duplicates,
+		"dedup_hit_rate":     dedupHitRate,
+	}
+}
+
+// processStatuses records delivery status events for messages we sent.
+func (h *Handler) processStatuses(statuses []models.WhatsAppStatus) error {
+	var errs []error
+	for _, status := range statuses {
+		record := models.MessageStatus{
+			WAMID:       status.ID,
+			RecipientID: status.RecipientID,
+			Status:      status.Status,
+			Timestamp:   status.Time(),
+		}
+		if len(status.Errors) > 0 {
+			record.ErrorCode = status.Errors[0].Code
+			record.ErrorTitle = status.Errors[0].Title
+			record.ErrorDetails = status.Errors[0].ErrorData.Details
+			log.Printf("Message %s to %s failed: %d %s", status.ID, status.RecipientID, record.ErrorCode, record.ErrorTitle)
+		}
+
+		if err := h.db.RecordMessageStatus(record); err != nil {
+			errs = append(errs, fmt.Errorf("status %s for %s: %w", status.Status, status.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// processMessages stores and answers messages sent to the business number phoneNumberID.
+func (h *Handler) processMessages(ctx context.Context, phoneNumberID string, messages []models.WhatsAppMessage, contacts []models.WhatsAppContact) error {
+	var errs []error
 	for _, message := range messages {
 		// Get contact info
 		var contactName string
@@ -87,36 +199,169 @@ func (h *Handler) processMessages(messages []models.WhatsAppMessage, contacts []
 			}
 		}
 
-		log.Printf("Received message from %s (%s): %s", message.From, contactName, message.Text)
+		content := message.Content()
+		text := content.PlainText()
+		log.Printf("Received %s message from %s (%s): %s", content.Kind(), message.From, contactName, text)
+
+		if err := h.conversation.RecordContact(message.From, message.From, contactName); err != nil {
+			log.Printf("Error recording contact %s: %v", message.From, err)
+		}
+
+		// Every inbound message, whatever its type, reopens the customer service window
+		receivedAt := message.Time()
+		if receivedAt.IsZero() {
+			receivedAt = time.Now()
+		}
+		if err := h.db.RecordInboundAt(message.From, receivedAt); err != nil {
+			errs = append(errs, fmt.Errorf("message %s from %s: %w", message.ID, message.From, err))
+			continue
+		}
+
+		if u, ok := content.(models.UnsupportedContent); ok && len(u.Errors) > 0 {
+			log.Printf("Unsupported message %s: %d %s", message.ID, u.Errors[0].Code, u.Errors[0].Title)
+		}
+
+		inbound := conversation.Inbound{
+			UserID:        message.From,
+			WAMID:         message.ID,
+			Text:          text,
+			SelectionID:   selectionID(content),
+			PhoneNumberID: phoneNumberID,
+		}
+
+		h.stats.inboundMessages.Add(1)
+		if !expectsReply(content) {
+			err := h.conversation.RecordInbound(inbound)
+			if errors.Is(err, conversation.ErrAlreadyRecorded) {
+				log.Printf("Skipping duplicate delivery of message %s", message.ID)
+				h.stats.duplicates.Add(1)
+			} else if err != nil {
+				errs = append(errs, fmt.Errorf("message %s from %s: %w", message.ID, message.From, err))
+			}
+			continue
+		}
+
+		// Skip redeliveries before downloading their media
+		answered, err := h.db.IsAnswered(message.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %s from %s: %w", message.ID, message.From, err))
+			continue
+		}
+		if answered {
+			log.Printf("Skipping duplicate delivery of message %s", message.ID)
+			h.stats.duplicates.Add(1)
+			continue
+		}
+		inbound.Image = h.inboundImage(ctx, message, content)
+		inbound.Audio = h.inboundAudio(ctx, message, content)
+
+		reply, err := h.conversation.AnswerInbound(ctx, inbound)
+		if errors.Is(err, conversation.ErrAlreadyAnswered) {
+			log.Printf("Skipping duplicate delivery of message %s", message.ID)
+			h.stats.duplicates.Add(1)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %s from %s: %w", message.ID, message.From, err))
+			continue
+		}
 
-		// Here you would integrate with your MCP chat handler
-		// For now, just log the message
-		// TODO: Integrate with MCP chat tool
+		replyID, err := h.SendMessage(message.From, reply)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reply to message %s: %w", message.ID, err))
+			continue
+		}
+
+		if err := h.conversation.RecordReply(message.From, message.ID, reply, replyID); err != nil {
+			log.Printf("Error saving reply to message %s: %v", message.ID, err)
+		}
 	}
+
+	return errors.Join(errs...)
 }
 
-func (h *Handler) SendMessage(to, message string) error {
-	if h.config.WhatsAppAccessToken == "" {
-		return fmt.Errorf("WhatsApp access token not configured")
+// sender returns who sent the messages of a webhook payload, so that the
+// workers answer each user in order, or "" if it carries none. A payload
+// mixing users is ordered with its first sender.
+func sender(payload []byte) string {
+	var webhook models.WhatsAppWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return ""
 	}
 
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/%s/messages", h.config.WhatsAppPhoneNumberID)
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			for _, message := range change.Value.Messages {
+				return message.From
+			}
+		}
+	}
+	return ""
+}
 
-	reqBody := SendMessageRequest{
+// expectsReply reports whether inbound content is answered rather than only stored.
+func expectsReply(content models.MessageContent) bool {
+	switch content.(type) {
+	case models.ReactionContent, models.UnsupportedContent:
+		return false
+	default:
+		return true
+	}
+}
+
+// selectionID returns the ID behind a tapped reply button, list row or
+// template quick-reply button, or "" for other content.
+func selectionID(content models.MessageContent) string {
+	switch c := content.(type) {
+	case models.InteractiveContent:
+		if selection := c.Selection(); selection != nil {
+			return selection.ID
+		}
+	case models.ButtonContent:
+		return c.Payload
+	}
+	return ""
+}
+
+// SendMessage sends a text message and returns the wamid WhatsApp assigned to it.
+func (h *Handler) SendMessage(to, message string) (string, error) {
+	return h.send(SendMessageRequest{
 		MessagingProduct: "whatsapp",
 		To:               to,
 		Type:             "text",
+		Text:             &TextBody{Body: message},
+	})
+}
+
+// send posts a message to the Cloud API and returns its wamid. Anything but a
+// template is refused locally when the recipient's customer service window is closed.
+func (h *Handler) send(reqBody SendMessageRequest) (string, error) {
+	if h.config.WhatsAppAccessToken == "" {
+		return "", fmt.Errorf("WhatsApp access token not configured")
+	}
+
+	to, err := NormalizeWAID(reqBody.To)
+	if err != nil {
+		return "", err
+	}
+	reqBody.To = to
+
+	if reqBody.Type != "template" {
+		if err := h.checkWindow(reqBody.To); err != nil {
+			return "", err
+		}
 	}
-	reqBody.Text.Body = message
+
+	url := fmt.Sprintf("%s/%s/messages", h.apiURL(), h.config.WhatsAppPhoneNumberID)
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -124,19 +369,31 @@ func (h *Handler) SendMessage(to, message string) error {
 
 	resp, err := h.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("WhatsApp API returned status %d", resp.StatusCode)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return "", parseAPIError(resp.StatusCode, body)
 	}
 
 	var response SendMessageResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	log.Printf("Message sent successfully to %s, ID: %s", to, response.Messages[0].ID)
-	return nil
+	if len(response.Messages) == 0 {
+		return "", fmt.Errorf("WhatsApp API returned no message ID")
+	}
+
+	log.Printf("%s message sent successfully to %s, ID: %s", reqBody.Type, reqBody.To, response.Messages[0].ID)
+	return response.Messages[0].ID, nil
+}
+
+func (h *Handler) apiURL() string {
+	if h.config.WhatsAppAPIURL != "" {
+		return strings.TrimRight(h.config.WhatsAppAPIURL, "/")
+	}
+	return defaultAPIURL
 }
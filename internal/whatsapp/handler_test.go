@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+)
+
+func sign(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHandleWebhookSignature(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{WhatsAppAppSecret: "test-secret"}
+	handler := NewHandler(config, db, nil)
+
+	body := `{"object":"whatsapp_business_account","entry":[]}`
+
+	tests := []struct {
+		name      string
+		signature string
+		want      int
+	}{
+		{"ValidSignature", sign(body, "test-secret"), http.StatusOK},
+		{"WrongSecret", sign(body, "other-secret"), http.StatusUnauthorized},
+		{"MissingSignature", "", http.StatusUnauthorized},
+		{"MalformedSignature", "sha256=not-hex", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+			if tt.signature != "" {
+				req.Header.Set(signatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.HandleWebhook(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	if got := handler.Stats()["rejected_signature"]; got != int64(3) {
+		t.Errorf("Expected 3 rejected signatures, got %v", got)
+	}
+}
@@ -0,0 +1,141 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+const (
+	// pollInterval bounds how long a due retry waits when no new webhook wakes the workers.
+	pollInterval = time.Second
+
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 10 * time.Minute
+)
+
+// StartWorkers recovers jobs interrupted by a previous run and starts n
+// goroutines that process queued webhook payloads until ctx is cancelled.
+// Payloads of different users run in parallel; ClaimInboundJob keeps each
+// user's in order.
+func (h *Handler) StartWorkers(ctx context.Context, n int) error {
+	if n <= 0 {
+		n = 1
+	}
+
+	if _, err := h.db.RecoverInboundJobs(); err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		go h.runWorker(ctx)
+	}
+
+	log.Printf("Started %d inbound webhook workers", n)
+	return nil
+}
+
+func (h *Handler) runWorker(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that is due before waiting again
+		for h.processNextJob(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNextJob claims and runs one job, reporting whether one was found.
+func (h *Handler) processNextJob(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	job, err := h.db.ClaimInboundJob()
+	if err != nil {
+		log.Printf("Error claiming inbound job: %v", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	if err := h.processPayload(ctx, job.Payload); err != nil {
+		delay := retryDelay(job.Attempts)
+		log.Printf("Inbound job %d failed (attempt %d/%d), retrying in %s: %v",
+			job.ID, job.Attempts, h.config.WebhookMaxAttempts, delay, err)
+		if err := h.db.RetryInboundJob(job.ID, err, delay, h.config.WebhookMaxAttempts); err != nil {
+			log.Printf("Error rescheduling inbound job %d: %v", job.ID, err)
+		}
+		return true
+	}
+
+	if err := h.db.CompleteInboundJob(job.ID); err != nil {
+		log.Printf("Error completing inbound job %d: %v", job.ID, err)
+	}
+	return true
+}
+
+// processPayload handles one webhook delivery. Every change is attempted
+// and the errors are joined so a single failure schedules a retry.
+func (h *Handler) processPayload(ctx context.Context, payload []byte) error {
+	var webhook models.WhatsAppWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		// A payload that does not decode will never succeed, so drop it
+		log.Printf("Discarding undecodable webhook payload: %v", err)
+		return nil
+	}
+
+	var errs []error
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+			if err := h.processStatuses(change.Value.Statuses); err != nil {
+				errs = append(errs, err)
+			}
+			if err := h.processMessages(ctx, change.Value.Metadata.PhoneNumberID, change.Value.Messages, change.Value.Contacts); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// notifyWorkers wakes an idle worker without blocking the webhook response.
+func (h *Handler) notifyWorkers() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// retryDelay doubles from retryBaseDelay per attempt, capped at retryMaxDelay.
+func retryDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,233 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/conversation"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+)
+
+func TestProcessNextJob(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{WebhookMaxAttempts: 2}
+	handler := NewHandler(config, db, conversation.NewService(db, config))
+
+	t.Run("CompletesEmptyPayload", func(t *testing.T) {
+		if _, err := db.EnqueueInboundJob([]byte(`{"object":"whatsapp_business_account","entry":[]}`), ""); err != nil {
+			t.Fatalf("Failed to enqueue job: %v", err)
+		}
+
+		if !handler.processNextJob(context.Background()) {
+			t.Fatal("Expected a job to be processed")
+		}
+
+		assertJobCounts(t, db, map[string]int{"done": 1})
+	})
+
+	t.Run("RetriesFailedSend", func(t *testing.T) {
+		// No access token is configured, so sending the reply fails
+		payload := `{"entry":[{"changes":[{"field":"messages","value":{
+			"messages":[{"from":"15550001111","id":"wamid.1","type":"text","text":{"body":"hi"}}]}}]}]}`
+		if _, err := db.EnqueueInboundJob([]byte(payload), "15550001111"); err != nil {
+			t.Fatalf("Failed to enqueue job: %v", err)
+		}
+
+		if !handler.processNextJob(context.Background()) {
+			t.Fatal("Expected a job to be processed")
+		}
+
+		assertJobCounts(t, db, map[string]int{"done": 1, "pending": 1})
+	})
+
+	t.Run("RecoversInterruptedJobs", func(t *testing.T) {
+		if _, err := db.EnqueueInboundJob([]byte(`{"entry":[]}`), ""); err != nil {
+			t.Fatalf("Failed to enqueue job: %v", err)
+		}
+		if _, err := db.ClaimInboundJob(); err != nil {
+			t.Fatalf("Failed to claim job: %v", err)
+		}
+
+		recovered, err := db.RecoverInboundJobs()
+		if err != nil {
+			t.Fatalf("Failed to recover jobs: %v", err)
+		}
+		if recovered != 1 {
+			t.Errorf("Expected 1 recovered job, got %d", recovered)
+		}
+	})
+}
+
+func TestClaimInboundJobInUserOrder(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	payload := `{"entry":[{"changes":[{"field":"messages","value":{
+		"messages":[{"from":"15550001111","id":"wamid.1","type":"text","text":{"body":"hi"}}]}}]}]}`
+	if got := sender([]byte(payload)); got != "15550001111" {
+		t.Fatalf("Expected sender 15550001111, got %q", got)
+	}
+
+	var ids []int64
+	for _, userID := range []string{"15550001111", "15550001111", "15550002222", ""} {
+		id, err := db.EnqueueInboundJob([]byte(payload), userID)
+		if err != nil {
+			t.Fatalf("Failed to enqueue job: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// The second job of 15550001111 waits for the first
+	var claimed []int64
+	for {
+		job, err := db.ClaimInboundJob()
+		if err != nil {
+			t.Fatalf("Failed to claim job: %v", err)
+		}
+		if job == nil {
+			break
+		}
+		claimed = append(claimed, job.ID)
+	}
+	if len(claimed) != 3 || claimed[0] != ids[0] || claimed[1] != ids[2] || claimed[2] != ids[3] {
+		t.Fatalf("Expected jobs %v, got %v", []int64{ids[0], ids[2], ids[3]}, claimed)
+	}
+
+	if err := db.CompleteInboundJob(ids[0]); err != nil {
+		t.Fatalf("Failed to complete job: %v", err)
+	}
+	job, err := db.ClaimInboundJob()
+	if err != nil || job == nil || job.ID != ids[1] {
+		t.Errorf("Expected job %d once the first was done, got %+v (error %v)", ids[1], job, err)
+	}
+}
+
+func assertJobCounts(t *testing.T, db *database.DB, want map[string]int) {
+	t.Helper()
+
+	stats, err := db.GetStats()
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+
+	counts := stats["inbound_jobs"].(map[string]int)
+	for status, n := range want {
+		if counts[status] != n {
+			t.Errorf("Expected %d %s jobs, got %d", n, status, counts[status])
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{20, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestProcessStatuses(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{WebhookMaxAttempts: 2}
+	handler := NewHandler(config, db, conversation.NewService(db, config))
+
+	if err := db.SaveReply("15550001111", "Your order shipped", "wamid.in", "wamid.out", nil); err != nil {
+		t.Fatalf("Failed to save reply: %v", err)
+	}
+
+	payload := `{"entry":[{"changes":[{"field":"messages","value":{"statuses":[
+		{"id":"wamid.out","status":"sent","timestamp":"1700000000","recipient_id":"15550001111"},
+		{"id":"wamid.out","status":"failed","timestamp":"1700000005","recipient_id":"15550001111",
+		 "errors":[{"code":131026,"title":"Message undeliverable","error_data":{"details":"Receiver is incapable"}}]},
+		{"id":"wamid.out","status":"sent","timestamp":"1700000000","recipient_id":"15550001111"}
+	]}}]}]}`
+
+	if err := handler.processPayload(context.Background(), []byte(payload)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	statuses, err := db.GetMessageStatuses("wamid.out")
+	if err != nil {
+		t.Fatalf("Failed to get statuses: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 distinct status events, got %d", len(statuses))
+	}
+	if statuses[1].ErrorCode != 131026 {
+		t.Errorf("Expected error code 131026, got %d", statuses[1].ErrorCode)
+	}
+
+	history, err := db.GetChatHistory("15550001111", 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 1 || history[0].DeliveryStatus != "failed" {
+		t.Fatalf("Expected the reply to be marked failed, got %+v", history)
+	}
+	if history[0].DeliveryError != "Message undeliverable: Receiver is incapable" {
+		t.Errorf("Unexpected delivery error %q", history[0].DeliveryError)
+	}
+}
+
+func TestProcessStatusesBeforeReply(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{WebhookMaxAttempts: 2}
+	handler := NewHandler(config, db, conversation.NewService(db, config))
+
+	// The statuses are handled before the reply they describe is stored
+	payload := `{"entry":[{"changes":[{"field":"messages","value":{"statuses":[
+		{"id":"wamid.out","status":"delivered","timestamp":"1700000003","recipient_id":"15550001111"},
+		{"id":"wamid.out","status":"sent","timestamp":"1700000000","recipient_id":"15550001111"}
+	]}}]}]}`
+
+	if err := handler.processPayload(context.Background(), []byte(payload)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := db.SaveReply("15550001111", "Your order shipped", "wamid.in", "wamid.out", nil); err != nil {
+		t.Fatalf("Failed to save reply: %v", err)
+	}
+	if err := db.SaveOutboundMessage("15550001111", "Anything else?", "wamid.none"); err != nil {
+		t.Fatalf("Failed to save outbound message: %v", err)
+	}
+
+	history, err := db.GetChatHistory("15550001111", 10)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	for _, message := range history {
+		want := map[string]string{"wamid.out": "delivered", "wamid.none": ""}[message.WAMID]
+		if message.DeliveryStatus != want {
+			t.Errorf("Expected %s to be marked %q, got %q", message.WAMID, want, message.DeliveryStatus)
+		}
+	}
+}
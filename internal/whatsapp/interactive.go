@@ -0,0 +1,250 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Cloud API limits for interactive messages.
+const (
+	maxReplyButtons     = 3
+	maxButtonTitleLen   = 20
+	maxListRows         = 10
+	maxListSections     = 10
+	maxRowTitleLen      = 24
+	maxRowDescLen       = 72
+	maxSelectionIDLen   = 256
+	maxInteractiveBody  = 1024
+	maxInteractiveExtra = 60 // header and footer
+)
+
+// Interactive is the "interactive" object of a Cloud API message.
+type Interactive struct {
+	Type   string             `json:"type"`
+	Header *InteractiveHeader `json:"header,omitempty"`
+	Body   InteractiveText    `json:"body"`
+	Footer *InteractiveText   `json:"footer,omitempty"`
+	Action InteractiveAction  `json:"action"`
+}
+
+type InteractiveHeader struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type InteractiveText struct {
+	Text string `json:"text"`
+}
+
+type InteractiveAction struct {
+	Buttons  []InteractiveButton `json:"buttons,omitempty"`
+	Button   string              `json:"button,omitempty"`
+	Sections []ListSection       `json:"sections,omitempty"`
+}
+
+type InteractiveButton struct {
+	Type  string      `json:"type"`
+	Reply ReplyButton `json:"reply"`
+}
+
+// ReplyButton is a quick-reply button; ID comes back in the webhook when tapped.
+type ReplyButton struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type ListSection struct {
+	Title string    `json:"title,omitempty"`
+	Rows  []ListRow `json:"rows"`
+}
+
+// ListRow is one choice in a list message; ID comes back in the webhook when picked.
+type ListRow struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// ButtonMessage is a message with up to three reply buttons.
+type ButtonMessage struct {
+	Header  string
+	Body    string
+	Footer  string
+	Buttons []ReplyButton
+}
+
+// ListMessage is a message that opens a sectioned list from ButtonText.
+type ListMessage struct {
+	Header     string
+	Body       string
+	Footer     string
+	ButtonText string
+	Sections   []ListSection
+}
+
+// Build validates the message and converts it to the API representation.
+func (m ButtonMessage) Build() (*Interactive, error) {
+	if len(m.Buttons) == 0 || len(m.Buttons) > maxReplyButtons {
+		return nil, fmt.Errorf("a button message needs 1 to %d buttons, got %d", maxReplyButtons, len(m.Buttons))
+	}
+
+	interactive, err := newInteractive("button", m.Header, m.Body, m.Footer)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, button := range m.Buttons {
+		if err := checkSelectionID(button.ID, seen); err != nil {
+			return nil, err
+		}
+		if err := checkText("button title", button.Title, maxButtonTitleLen, true); err != nil {
+			return nil, err
+		}
+		interactive.Action.Buttons = append(interactive.Action.Buttons, InteractiveButton{Type: "reply", Reply: button})
+	}
+
+	return interactive, nil
+}
+
+// Build validates the message and converts it to the API representation.
+func (m ListMessage) Build() (*Interactive, error) {
+	if len(m.Sections) == 0 || len(m.Sections) > maxListSections {
+		return nil, fmt.Errorf("a list message needs 1 to %d sections, got %d", maxListSections, len(m.Sections))
+	}
+	if err := checkText("list button text", m.ButtonText, maxButtonTitleLen, true); err != nil {
+		return nil, err
+	}
+
+	interactive, err := newInteractive("list", m.Header, m.Body, m.Footer)
+	if err != nil {
+		return nil, err
+	}
+	interactive.Action.Button = m.ButtonText
+
+	rows := 0
+	seen := make(map[string]bool)
+	for _, section := range m.Sections {
+		if len(m.Sections) > 1 && section.Title == "" {
+			return nil, fmt.Errorf("every section needs a title when there is more than one")
+		}
+		if err := checkText("section title", section.Title, maxRowTitleLen, false); err != nil {
+			return nil, err
+		}
+		if len(section.Rows) == 0 {
+			return nil, fmt.Errorf("section %q has no rows", section.Title)
+		}
+
+		for _, row := range section.Rows {
+			if err := checkSelectionID(row.ID, seen); err != nil {
+				return nil, err
+			}
+			if err := checkText("row title", row.Title, maxRowTitleLen, true); err != nil {
+				return nil, err
+			}
+			if err := checkText("row description", row.Description, maxRowDescLen, false); err != nil {
+				return nil, err
+			}
+		}
+		rows += len(section.Rows)
+	}
+	if rows > maxListRows {
+		return nil, fmt.Errorf("a list message can have at most %d rows, got %d", maxListRows, rows)
+	}
+
+	interactive.Action.Sections = m.Sections
+	return interactive, nil
+}
+
+// PlainText renders the button message for the chat history.
+func (m ButtonMessage) PlainText() string {
+	titles := make([]string, 0, len(m.Buttons))
+	for _, button := range m.Buttons {
+		titles = append(titles, button.Title)
+	}
+	return fmt.Sprintf("%s [Buttons: %s]", m.Body, strings.Join(titles, " | "))
+}
+
+// PlainText renders the list message for the chat history.
+func (m ListMessage) PlainText() string {
+	var titles []string
+	for _, section := range m.Sections {
+		for _, row := range section.Rows {
+			titles = append(titles, row.Title)
+		}
+	}
+	return fmt.Sprintf("%s [List: %s]", m.Body, strings.Join(titles, " | "))
+}
+
+func newInteractive(kind, header, body, footer string) (*Interactive, error) {
+	if err := checkText("body", body, maxInteractiveBody, true); err != nil {
+		return nil, err
+	}
+	if err := checkText("header", header, maxInteractiveExtra, false); err != nil {
+		return nil, err
+	}
+	if err := checkText("footer", footer, maxInteractiveExtra, false); err != nil {
+		return nil, err
+	}
+
+	interactive := &Interactive{Type: kind, Body: InteractiveText{Text: body}}
+	if header != "" {
+		interactive.Header = &InteractiveHeader{Type: "text", Text: header}
+	}
+	if footer != "" {
+		interactive.Footer = &InteractiveText{Text: footer}
+	}
+	return interactive, nil
+}
+
+func checkText(field, value string, maxLen int, required bool) error {
+	if required && value == "" {
+		return fmt.Errorf("%s is required", field)
+	}
+	if n := utf8.RuneCountInString(value); n > maxLen {
+		return fmt.Errorf("%s %q is %d characters, the limit is %d", field, value, n, maxLen)
+	}
+	return nil
+}
+
+func checkSelectionID(id string, seen map[string]bool) error {
+	if id == "" {
+		return fmt.Errorf("every button and row needs an id")
+	}
+	if len(id) > maxSelectionIDLen {
+		return fmt.Errorf("id %q is longer than %d characters", id, maxSelectionIDLen)
+	}
+	if seen[id] {
+		return fmt.Errorf("id %q is used more than once", id)
+	}
+	seen[id] = true
+	return nil
+}
+
+// SendButtons sends a message with reply buttons and returns its wamid.
+func (h *Handler) SendButtons(to string, message ButtonMessage) (string, error) {
+	interactive, err := message.Build()
+	if err != nil {
+		return "", err
+	}
+	return h.sendInteractive(to, interactive)
+}
+
+// SendList sends a list message and returns its wamid.
+func (h *Handler) SendList(to string, message ListMessage) (string, error) {
+	interactive, err := message.Build()
+	if err != nil {
+		return "", err
+	}
+	return h.sendInteractive(to, interactive)
+}
+
+func (h *Handler) sendInteractive(to string, interactive *Interactive) (string, error) {
+	return h.send(SendMessageRequest{
+		MessagingProduct: "whatsapp",
+		To:               to,
+		Type:             "interactive",
+		Interactive:      interactive,
+	})
+}
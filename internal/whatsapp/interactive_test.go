@@ -0,0 +1,76 @@
+package whatsapp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestButtonMessageBuild(t *testing.T) {
+	message := ButtonMessage{
+		Body:    "Did this answer your question?",
+		Buttons: []ReplyButton{{ID: "answer:yes", Title: "Yes"}, {ID: "answer:no", Title: "No"}},
+	}
+
+	interactive, err := message.Build()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if interactive.Type != "button" || len(interactive.Action.Buttons) != 2 {
+		t.Errorf("Unexpected interactive payload: %+v", interactive)
+	}
+	if interactive.Action.Buttons[0].Type != "reply" {
+		t.Errorf("Expected reply buttons, got %s", interactive.Action.Buttons[0].Type)
+	}
+
+	tests := []struct {
+		name    string
+		buttons []ReplyButton
+	}{
+		{"TooManyButtons", []ReplyButton{{"a", "A"}, {"b", "B"}, {"c", "C"}, {"d", "D"}}},
+		{"TitleTooLong", []ReplyButton{{"a", strings.Repeat("x", 21)}}},
+		{"DuplicateID", []ReplyButton{{"a", "A"}, {"a", "B"}}},
+		{"MissingID", []ReplyButton{{"", "A"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := ButtonMessage{Body: "Pick one", Buttons: tt.buttons}
+			if _, err := invalid.Build(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestListMessageBuild(t *testing.T) {
+	rows := func(n int) []ListRow {
+		var out []ListRow
+		for i := 0; i < n; i++ {
+			out = append(out, ListRow{ID: strings.Repeat("r", i+1), Title: "Row"})
+		}
+		return out
+	}
+
+	message := ListMessage{
+		Body:       "Choose a delivery slot",
+		ButtonText: "Slots",
+		Sections:   []ListSection{{Title: "Tomorrow", Rows: rows(4)}},
+	}
+	if _, err := message.Build(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	t.Run("TooManyRows", func(t *testing.T) {
+		message.Sections = []ListSection{{Title: "Tomorrow", Rows: rows(11)}}
+		if _, err := message.Build(); err == nil {
+			t.Error("Expected error for more than 10 rows")
+		}
+	})
+
+	t.Run("UntitledSections", func(t *testing.T) {
+		message.Sections = []ListSection{{Rows: rows(1)}, {Title: "Later", Rows: []ListRow{{ID: "z", Title: "Z"}}}}
+		if _, err := message.Build(); err == nil {
+			t.Error("Expected error for an untitled section among several")
+		}
+	})
+}
@@ -0,0 +1,405 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/speech"
+)
+
+// MediaObject references outbound media either by uploaded media ID or by public link.
+type MediaObject struct {
+	ID       string `json:"id,omitempty"`
+	Link     string `json:"link,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// OutboundMedia describes a media message to send. Set exactly one of Path,
+// MediaID and Link; Path is uploaded first and must lie inside the configured media directory.
+type OutboundMedia struct {
+	Type     string // image, document, audio or video
+	Path     string
+	MediaID  string
+	Link     string
+	Caption  string
+	Filename string
+}
+
+// Validate checks the combination of fields against what the Cloud API accepts.
+func (m OutboundMedia) Validate() error {
+	switch m.Type {
+	case "image", "document", "audio", "video":
+	default:
+		return fmt.Errorf("unsupported media type %q: must be image, document, audio or video", m.Type)
+	}
+
+	sources := 0
+	for _, source := range []string{m.Path, m.MediaID, m.Link} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of path, media_id or link is required")
+	}
+
+	if m.Caption != "" && m.Type == "audio" {
+		return fmt.Errorf("audio messages cannot have a caption")
+	}
+	if m.Filename != "" && m.Type != "document" {
+		return fmt.Errorf("filename is only supported for documents")
+	}
+
+	return nil
+}
+
+// PlainText renders the media message for the chat history.
+func (m OutboundMedia) PlainText() string {
+	media := models.Media{Caption: m.Caption}
+	switch m.Type {
+	case "image":
+		return models.ImageContent{Media: media}.PlainText()
+	case "audio":
+		return models.AudioContent{Media: media}.PlainText()
+	case "video":
+		return models.VideoContent{Media: media}.PlainText()
+	default:
+		filename := m.Filename
+		if filename == "" && m.Path != "" {
+			filename = filepath.Base(m.Path)
+		}
+		return models.DocumentContent{Media: media, Filename: filename}.PlainText()
+	}
+}
+
+type uploadMediaResponse struct {
+	ID string `json:"id"`
+}
+
+// UploadMedia uploads a file from the media directory and returns its media ID.
+func (h *Handler) UploadMedia(path string) (string, error) {
+	if h.config.WhatsAppAccessToken == "" {
+		return "", fmt.Errorf("WhatsApp access token not configured")
+	}
+	if h.config.WhatsAppMediaDir == "" {
+		return "", fmt.Errorf("media uploads are disabled: WHATSAPP_MEDIA_DIR not set")
+	}
+
+	// OpenInRoot rejects paths that escape the media directory, including via symlinks
+	file, err := os.OpenInRoot(h.config.WhatsAppMediaDir, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open media file: %w", err)
+	}
+	defer file.Close()
+
+	mimeType, err := detectMimeType(file, path)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("messaging_product", "whatsapp"); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	if err := writer.WriteField("type", mimeType); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filepath.Base(path)))
+	header.Set("Content-Type", mimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", fmt.Errorf("failed to read media file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/media", h.apiURL(), h.config.WhatsAppPhoneNumberID)
+	req, err := http.NewRequest("POST", url, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+h.config.WhatsAppAccessToken)
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return "", parseAPIError(resp.StatusCode, body)
+	}
+
+	var response uploadMediaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.ID == "" {
+		return "", fmt.Errorf("WhatsApp media upload returned no media ID")
+	}
+
+	log.Printf("Uploaded %s (%s) as media %s", filepath.Base(path), mimeType, response.ID)
+	return response.ID, nil
+}
+
+// SendMedia sends an image, document, audio or video message and returns its wamid.
+func (h *Handler) SendMedia(to string, media OutboundMedia) (string, error) {
+	if err := media.Validate(); err != nil {
+		return "", err
+	}
+
+	to, err := NormalizeWAID(to)
+	if err != nil {
+		return "", err
+	}
+
+	// Don't upload anything we won't be allowed to send
+	if err := h.checkWindow(to); err != nil {
+		return "", err
+	}
+
+	object := &MediaObject{
+		ID:       media.MediaID,
+		Link:     media.Link,
+		Caption:  media.Caption,
+		Filename: media.Filename,
+	}
+
+	if media.Path != "" {
+		id, err := h.UploadMedia(media.Path)
+		if err != nil {
+			return "", err
+		}
+		object.ID = id
+		if media.Type == "document" && object.Filename == "" {
+			object.Filename = filepath.Base(media.Path)
+		}
+	}
+
+	reqBody := SendMessageRequest{
+		MessagingProduct: "whatsapp",
+		To:               to,
+		Type:             media.Type,
+	}
+	switch media.Type {
+	case "image":
+		reqBody.Image = object
+	case "audio":
+		reqBody.Audio = object
+	case "video":
+		reqBody.Video = object
+	case "document":
+		reqBody.Document = object
+	}
+
+	return h.send(reqBody)
+}
+
+// maxInboundMediaSize caps the media downloaded from inbound messages;
+// WhatsApp images are at most 5 MB.
+const maxInboundMediaSize = 16 << 20
+
+type mediaURLResponse struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	SHA256   string `json:"sha256"`
+	FileSize int64  `json:"file_size"`
+}
+
+// DownloadMedia fetches the file of an inbound media object and returns it
+// with its MIME type. The Graph media endpoint answers with a short-lived
+// URL, which also requires the access token.
+func (h *Handler) DownloadMedia(ctx context.Context, mediaID string) ([]byte, string, error) {
+	if h.config.WhatsAppAccessToken == "" {
+		return nil, "", fmt.Errorf("WhatsApp access token not configured")
+	}
+
+	var media mediaURLResponse
+	resp, err := h.getMedia(ctx, fmt.Sprintf("%s/%s", h.apiURL(), mediaID))
+	if err != nil {
+		return nil, "", err
+	}
+	err = json.NewDecoder(resp.Body).Decode(&media)
+	resp.Body.Close()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if media.URL == "" {
+		return nil, "", fmt.Errorf("WhatsApp returned no URL for media %s", mediaID)
+	}
+	if media.FileSize > maxInboundMediaSize {
+		return nil, "", fmt.Errorf("media %s is too large (%d bytes)", mediaID, media.FileSize)
+	}
+
+	resp, err = h.getMedia(ctx, media.URL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxInboundMediaSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download media: %w", err)
+	}
+	if len(data) > maxInboundMediaSize {
+		return nil, "", fmt.Errorf("media %s is too large", mediaID)
+	}
+
+	mimeType := media.MimeType
+	if mimeType == "" {
+		mimeType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+
+	log.Printf("Downloaded media %s (%s, %d bytes)", mediaID, mimeType, len(data))
+	return data, mimeType, nil
+}
+
+// getMedia sends an authenticated GET to the Graph API and returns the
+// response if it succeeded; the caller closes its body.
+func (h *Handler) getMedia(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+h.config.WhatsAppAccessToken)
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, parseAPIError(resp.StatusCode, body)
+	}
+
+	return resp, nil
+}
+
+// inboundImage downloads the image of an inbound message so that the model
+// can look at it. It returns nil for other content, if the model cannot see
+// images, or if downloadInbound does not get the file.
+func (h *Handler) inboundImage(ctx context.Context, message models.WhatsAppMessage, content models.MessageContent) *llm.Image {
+	image, ok := content.(models.ImageContent)
+	if !ok || !h.conversation.UnderstandsImages() {
+		return nil
+	}
+
+	data, mimeType := h.downloadInbound(ctx, message, image.Media)
+	if data == nil {
+		return nil
+	}
+	return &llm.Image{MimeType: mimeType, Data: data}
+}
+
+// inboundAudio downloads the recording of an inbound voice note or audio
+// message for transcription. It returns nil for other content, without a
+// transcriber, or if downloadInbound does not get the file.
+func (h *Handler) inboundAudio(ctx context.Context, message models.WhatsAppMessage, content models.MessageContent) *speech.Audio {
+	audio, ok := content.(models.AudioContent)
+	if !ok || !h.conversation.TranscribesAudio() {
+		return nil
+	}
+
+	data, mimeType := h.downloadInbound(ctx, message, audio.Media)
+	if data == nil {
+		return nil
+	}
+	return &speech.Audio{MimeType: mimeType, Data: data}
+}
+
+// downloadInbound downloads and stores the media file of an inbound message.
+// It returns nil if the download fails, takes longer than
+// WhatsAppMediaTimeout or does not match the webhook's checksum.
+func (h *Handler) downloadInbound(ctx context.Context, message models.WhatsAppMessage, media models.Media) ([]byte, string) {
+	if media.ID == "" {
+		return nil, ""
+	}
+
+	if h.config.WhatsAppMediaTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.config.WhatsAppMediaTimeout)
+		defer cancel()
+	}
+
+	data, mimeType, err := h.DownloadMedia(ctx, media.ID)
+	if err != nil {
+		log.Printf("Error downloading media of message %s: %v", message.ID, err)
+		return nil, ""
+	}
+	if media.SHA256 != "" && !checksumMatches(data, media.SHA256) {
+		log.Printf("Dropping media of message %s: it does not match its sha256 checksum", message.ID)
+		return nil, ""
+	}
+
+	err = h.db.SaveMedia(models.StoredMedia{
+		WAMID:    message.ID,
+		UserID:   message.From,
+		MediaID:  media.ID,
+		MimeType: mimeType,
+		SHA256:   media.SHA256,
+		Data:     data,
+	})
+	if err != nil {
+		log.Printf("Error saving media of message %s: %v", message.ID, err)
+	}
+
+	return data, mimeType
+}
+
+// checksumMatches reports whether sum is the SHA-256 of data, hex or base64
+// encoded: webhooks send it in base64, the media endpoint in hex.
+func checksumMatches(data []byte, sum string) bool {
+	digest := sha256.Sum256(data)
+	return strings.EqualFold(sum, hex.EncodeToString(digest[:])) || sum == base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// detectMimeType uses the file extension and falls back to sniffing the content.
+func detectMimeType(file *os.File, path string) (string, error) {
+	if mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); mimeType != "" {
+		mediaType, _, err := mime.ParseMediaType(mimeType)
+		if err == nil {
+			return mediaType, nil
+		}
+	}
+
+	head := make([]byte, 512)
+	n, err := file.Read(head)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read media file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read media file: %w", err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	return mediaType, nil
+}
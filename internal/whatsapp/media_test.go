@@ -0,0 +1,244 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/conversation"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/llm"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/models"
+)
+
+func TestSendMedia(t *testing.T) {
+	var sent SendMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/PHONE_ID/media":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("Failed to parse upload: %v", err)
+			}
+			if got := r.FormValue("type"); got != "application/pdf" {
+				t.Errorf("Expected type application/pdf, got %s", got)
+			}
+			w.Write([]byte(`{"id":"media-1"}`))
+		case "/PHONE_ID/messages":
+			if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+				t.Errorf("Failed to decode message: %v", err)
+			}
+			w.Write([]byte(`{"messages":[{"id":"wamid.out"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	mediaDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mediaDir, "invoice.pdf"), []byte("%PDF-1.4"), 0o600); err != nil {
+		t.Fatalf("Failed to write media file: %v", err)
+	}
+
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{
+		WhatsAppAccessToken:   "token",
+		WhatsAppPhoneNumberID: "PHONE_ID",
+		WhatsAppAPIURL:        server.URL,
+		WhatsAppMediaDir:      mediaDir,
+	}
+	handler := NewHandler(config, db, nil)
+
+	// Open the customer service window
+	if err := db.RecordInboundAt("15550001111", time.Now()); err != nil {
+		t.Fatalf("Failed to record inbound message: %v", err)
+	}
+
+	t.Run("UploadsLocalFile", func(t *testing.T) {
+		wamid, err := handler.SendMedia("15550001111", OutboundMedia{
+			Type:    "document",
+			Path:    "invoice.pdf",
+			Caption: "Your invoice",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if wamid != "wamid.out" {
+			t.Errorf("Expected wamid.out, got %s", wamid)
+		}
+		if sent.Document == nil || sent.Document.ID != "media-1" || sent.Document.Filename != "invoice.pdf" {
+			t.Errorf("Unexpected document payload: %+v", sent.Document)
+		}
+	})
+
+	t.Run("RejectsPathOutsideMediaDir", func(t *testing.T) {
+		_, err := handler.SendMedia("15550001111", OutboundMedia{Type: "document", Path: "../secret.pdf"})
+		if err == nil {
+			t.Error("Expected error for path outside the media directory")
+		}
+	})
+
+	t.Run("RejectsAmbiguousSource", func(t *testing.T) {
+		_, err := handler.SendMedia("15550001111", OutboundMedia{Type: "image", MediaID: "1", Link: "https://example.com/a.jpg"})
+		if err == nil {
+			t.Error("Expected error when both media_id and link are set")
+		}
+	})
+}
+
+func TestDownloadMedia(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Expected the access token, got %q", got)
+		}
+		switch r.URL.Path {
+		case "/media-9":
+			fmt.Fprintf(w, `{"url":"%s/files/media-9","mime_type":"image/jpeg","file_size":4,"id":"media-9"}`, server.URL)
+		case "/files/media-9":
+			w.Write([]byte("jpeg"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"Unsupported get request","code":100}}`))
+		}
+	}))
+	defer server.Close()
+
+	config := &configs.Config{WhatsAppAccessToken: "token", WhatsAppAPIURL: server.URL}
+	handler := NewHandler(config, nil, nil)
+
+	data, mimeType, err := handler.DownloadMedia(context.Background(), "media-9")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if string(data) != "jpeg" || mimeType != "image/jpeg" {
+		t.Errorf("Expected the jpeg, got %q (%s)", data, mimeType)
+	}
+
+	if _, _, err := handler.DownloadMedia(context.Background(), "missing"); err == nil {
+		t.Error("Expected an error for unknown media")
+	}
+}
+
+func TestDownloadInbound(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/media-9", "/media-slow":
+			fmt.Fprintf(w, `{"url":"%s/files%s","mime_type":"image/jpeg"}`, server.URL, r.URL.Path)
+		case "/files/media-9":
+			w.Write([]byte("jpeg"))
+		case "/files/media-slow":
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{WhatsAppAccessToken: "token", WhatsAppAPIURL: server.URL, WhatsAppMediaTimeout: 100 * time.Millisecond}
+	handler := NewHandler(config, db, nil)
+
+	digest := sha256.Sum256([]byte("jpeg"))
+	tests := []struct {
+		name     string
+		media    models.Media
+		wantData bool
+	}{
+		{"Base64Checksum", models.Media{ID: "media-9", SHA256: base64.StdEncoding.EncodeToString(digest[:])}, true},
+		{"HexChecksum", models.Media{ID: "media-9", SHA256: hex.EncodeToString(digest[:])}, true},
+		{"NoChecksum", models.Media{ID: "media-9"}, true},
+		{"ChecksumMismatch", models.Media{ID: "media-9", SHA256: hex.EncodeToString(make([]byte, 32))}, false},
+		{"Stalled", models.Media{ID: "media-slow"}, false},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := models.WhatsAppMessage{From: "15550001111", ID: fmt.Sprintf("wamid.%d", i)}
+			data, _ := handler.downloadInbound(context.Background(), message, tt.media)
+			if (data != nil) != tt.wantData {
+				t.Errorf("Expected data %v, got %q", tt.wantData, data)
+			}
+		})
+	}
+}
+
+func TestInboundMediaRedelivery(t *testing.T) {
+	var server *httptest.Server
+	var downloads int
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/media-9":
+			downloads++
+			fmt.Fprintf(w, `{"url":"%s/files/media-9","mime_type":"image/jpeg","file_size":4,"id":"media-9"}`, server.URL)
+		case "/files/media-9":
+			w.Write([]byte("jpeg"))
+		case "/PHONE_ID/messages":
+			w.Write([]byte(`{"messages":[{"id":"wamid.out"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{
+		WhatsAppAccessToken:   "token",
+		WhatsAppPhoneNumberID: "PHONE_ID",
+		WhatsAppAPIURL:        server.URL,
+		LLMProvider:           "none",
+		LLMVisionModel:        "vision",
+	}
+	service := conversation.NewService(db, config)
+	service.SetProvider(llm.NewScripted("A parcel on a doorstep.", "Thanks, that is your parcel."))
+	handler := NewHandler(config, db, service)
+
+	var messages []models.WhatsAppMessage
+	if err := json.Unmarshal([]byte(`[
+		{"from":"15550001111","id":"wamid.photo","type":"image","image":{"id":"media-9","mime_type":"image/jpeg"}},
+		{"from":"15550001111","id":"wamid.reaction","type":"reaction","reaction":{"message_id":"wamid.out","emoji":"👍"}}
+	]`), &messages); err != nil {
+		t.Fatalf("Failed to decode messages: %v", err)
+	}
+
+	// The second delivery is skipped without fetching the photo again
+	for i := 0; i < 2; i++ {
+		if err := handler.processMessages(context.Background(), "PHONE_ID", messages, nil); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if downloads != 1 {
+		t.Errorf("Expected the photo to be downloaded once, got %d downloads", downloads)
+	}
+	stats := handler.Stats()
+	if stats["inbound_messages"] != int64(4) || stats["duplicates"] != int64(2) || stats["dedup_hit_rate"] != 0.5 {
+		t.Errorf("Expected 4 inbound messages with 2 duplicates, got %v", stats)
+	}
+}
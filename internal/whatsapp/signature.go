@@ -0,0 +1,29 @@
+package whatsapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// signatureHeader carries Meta's HMAC-SHA256 of the raw webhook body, keyed with the app secret.
+const signatureHeader = "X-Hub-Signature-256"
+
+// validSignature reports whether header is the "sha256=<hex>" HMAC of body under secret.
+func validSignature(body []byte, header, secret string) bool {
+	hexDigest, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+
+	got, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,180 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Template is the "template" object of a Cloud API message.
+type Template struct {
+	Name       string              `json:"name"`
+	Language   TemplateLanguage    `json:"language"`
+	Components []TemplateComponent `json:"components,omitempty"`
+}
+
+type TemplateLanguage struct {
+	Code string `json:"code"`
+}
+
+// TemplateComponent fills the variables of one part of a template: the
+// header, the body or a single button.
+type TemplateComponent struct {
+	Type       string              `json:"type"`
+	SubType    string              `json:"sub_type,omitempty"`
+	Index      string              `json:"index,omitempty"`
+	Parameters []TemplateParameter `json:"parameters"`
+}
+
+type TemplateParameter struct {
+	Type     string       `json:"type"`
+	Text     string       `json:"text,omitempty"`
+	Payload  string       `json:"payload,omitempty"`
+	Image    *MediaObject `json:"image,omitempty"`
+	Document *MediaObject `json:"document,omitempty"`
+	Video    *MediaObject `json:"video,omitempty"`
+}
+
+// TemplateMessage describes an approved template and the values for its variables.
+type TemplateMessage struct {
+	Name         string
+	LanguageCode string
+
+	// HeaderParams fill a text header; HeaderMedia fills an image, document or video header.
+	HeaderParams []string
+	HeaderMedia  *TemplateMedia
+
+	BodyParams []string
+	Buttons    []TemplateButton
+}
+
+// TemplateMedia is the media for a template header, referenced by ID or link.
+type TemplateMedia struct {
+	Type     string // image, document or video
+	MediaID  string
+	Link     string
+	Filename string
+}
+
+// TemplateButton fills a button variable: the payload of a quick_reply button
+// or the URL suffix of a url button.
+type TemplateButton struct {
+	SubType string // quick_reply or url
+	Index   int
+	Value   string
+}
+
+// Build validates the message and converts it to the API representation.
+func (m TemplateMessage) Build() (*Template, error) {
+	if m.Name == "" {
+		return nil, fmt.Errorf("template name is required")
+	}
+	if m.LanguageCode == "" {
+		return nil, fmt.Errorf("template language code is required")
+	}
+	if len(m.HeaderParams) > 0 && m.HeaderMedia != nil {
+		return nil, fmt.Errorf("a template header takes either text parameters or media, not both")
+	}
+
+	template := &Template{
+		Name:     m.Name,
+		Language: TemplateLanguage{Code: m.LanguageCode},
+	}
+
+	if len(m.HeaderParams) > 0 {
+		template.Components = append(template.Components, TemplateComponent{
+			Type:       "header",
+			Parameters: textParameters(m.HeaderParams),
+		})
+	}
+
+	if m.HeaderMedia != nil {
+		param, err := m.HeaderMedia.parameter()
+		if err != nil {
+			return nil, err
+		}
+		template.Components = append(template.Components, TemplateComponent{
+			Type:       "header",
+			Parameters: []TemplateParameter{param},
+		})
+	}
+
+	if len(m.BodyParams) > 0 {
+		template.Components = append(template.Components, TemplateComponent{
+			Type:       "body",
+			Parameters: textParameters(m.BodyParams),
+		})
+	}
+
+	for _, button := range m.Buttons {
+		component := TemplateComponent{
+			Type:    "button",
+			SubType: button.SubType,
+			Index:   strconv.Itoa(button.Index),
+		}
+		switch button.SubType {
+		case "quick_reply":
+			component.Parameters = []TemplateParameter{{Type: "payload", Payload: button.Value}}
+		case "url":
+			component.Parameters = []TemplateParameter{{Type: "text", Text: button.Value}}
+		default:
+			return nil, fmt.Errorf("unsupported button sub_type %q: must be quick_reply or url", button.SubType)
+		}
+		template.Components = append(template.Components, component)
+	}
+
+	return template, nil
+}
+
+// PlainText renders the template message for the chat history.
+func (m TemplateMessage) PlainText() string {
+	if len(m.BodyParams) == 0 {
+		return fmt.Sprintf("[Template: %s]", m.Name)
+	}
+	return fmt.Sprintf("[Template: %s] %s", m.Name, strings.Join(m.BodyParams, " | "))
+}
+
+func (m TemplateMedia) parameter() (TemplateParameter, error) {
+	if (m.MediaID == "") == (m.Link == "") {
+		return TemplateParameter{}, fmt.Errorf("template header media needs exactly one of media_id or link")
+	}
+
+	object := &MediaObject{ID: m.MediaID, Link: m.Link}
+	param := TemplateParameter{Type: m.Type}
+	switch m.Type {
+	case "image":
+		param.Image = object
+	case "video":
+		param.Video = object
+	case "document":
+		object.Filename = m.Filename
+		param.Document = object
+	default:
+		return TemplateParameter{}, fmt.Errorf("unsupported header media type %q: must be image, document or video", m.Type)
+	}
+	return param, nil
+}
+
+func textParameters(values []string) []TemplateParameter {
+	params := make([]TemplateParameter, 0, len(values))
+	for _, v := range values {
+		params = append(params, TemplateParameter{Type: "text", Text: v})
+	}
+	return params
+}
+
+// SendTemplate sends an approved template message and returns its wamid.
+// Templates may be sent outside the 24-hour customer service window.
+func (h *Handler) SendTemplate(to string, message TemplateMessage) (string, error) {
+	template, err := message.Build()
+	if err != nil {
+		return "", err
+	}
+
+	return h.send(SendMessageRequest{
+		MessagingProduct: "whatsapp",
+		To:               to,
+		Type:             "template",
+		Template:         template,
+	})
+}
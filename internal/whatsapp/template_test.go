@@ -0,0 +1,101 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+)
+
+func TestTemplateMessageBuild(t *testing.T) {
+	message := TemplateMessage{
+		Name:         "order_update",
+		LanguageCode: "en_US",
+		HeaderMedia:  &TemplateMedia{Type: "document", Link: "https://example.com/invoice.pdf", Filename: "invoice.pdf"},
+		BodyParams:   []string{"Jo", "#1042"},
+		Buttons:      []TemplateButton{{SubType: "url", Index: 0, Value: "1042"}},
+	}
+
+	template, err := message.Build()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(template.Components) != 3 {
+		t.Fatalf("Expected header, body and button components, got %d", len(template.Components))
+	}
+	if header := template.Components[0]; header.Parameters[0].Document == nil {
+		t.Error("Expected a document header parameter")
+	}
+	if body := template.Components[1]; len(body.Parameters) != 2 || body.Parameters[1].Text != "#1042" {
+		t.Errorf("Unexpected body parameters: %+v", body.Parameters)
+	}
+	if button := template.Components[2]; button.SubType != "url" || button.Index != "0" {
+		t.Errorf("Unexpected button component: %+v", button)
+	}
+
+	t.Run("RejectsTextAndMediaHeader", func(t *testing.T) {
+		message.HeaderParams = []string{"Hi"}
+		if _, err := message.Build(); err == nil {
+			t.Error("Expected error for a header with both text and media")
+		}
+	})
+}
+
+func TestReengagementError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SendMessageRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if req.Type == "template" {
+			w.Write([]byte(`{"messages":[{"id":"wamid.template"}]}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"Re-engagement message","type":"OAuthException","code":131047,
+			"error_data":{"details":"Message failed to send because more than 24 hours have passed"}}}`))
+	}))
+	defer server.Close()
+
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{
+		WhatsAppAccessToken:   "token",
+		WhatsAppPhoneNumberID: "PHONE_ID",
+		WhatsAppAPIURL:        server.URL,
+	}
+	handler := NewHandler(config, db, nil)
+
+	// Our window is open, but the API still refuses the free-form message
+	if err := db.RecordInboundAt("15550001111", time.Now()); err != nil {
+		t.Fatalf("Failed to record inbound message: %v", err)
+	}
+
+	_, err = handler.SendMessage("15550001111", "Are you still there?")
+	if !errors.Is(err, ErrReengagementRequired) {
+		t.Fatalf("Expected ErrReengagementRequired, got: %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 131047 {
+		t.Errorf("Expected APIError with code 131047, got: %v", err)
+	}
+
+	wamid, err := handler.SendTemplate("15550001111", TemplateMessage{Name: "hello_world", LanguageCode: "en_US"})
+	if err != nil {
+		t.Fatalf("Expected template to be sent, got: %v", err)
+	}
+	if wamid != "wamid.template" {
+		t.Errorf("Expected wamid.template, got %s", wamid)
+	}
+}
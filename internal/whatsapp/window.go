@@ -0,0 +1,104 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CustomerServiceWindow is how long after a customer's last message we may
+// send them free-form (non-template) messages.
+const CustomerServiceWindow = 24 * time.Hour
+
+// WindowState describes a contact's customer service window.
+type WindowState struct {
+	UserID        string
+	Open          bool
+	LastInboundAt time.Time // zero if the contact never wrote to us
+	ExpiresAt     time.Time
+}
+
+// Remaining returns how long the window stays open, or zero if it is closed.
+func (s WindowState) Remaining(now time.Time) time.Duration {
+	if !s.Open {
+		return 0
+	}
+	return s.ExpiresAt.Sub(now)
+}
+
+// WindowClosedError is returned when a free-form message is addressed to a
+// contact whose customer service window is closed. It matches
+// ErrReengagementRequired, like the equivalent API error.
+type WindowClosedError struct {
+	UserID        string
+	LastInboundAt time.Time
+}
+
+func (e *WindowClosedError) Error() string {
+	if e.LastInboundAt.IsZero() {
+		return fmt.Sprintf("customer service window for %s is closed: they have never messaged us; "+
+			"send an approved template (send_template) instead", e.UserID)
+	}
+	return fmt.Sprintf("customer service window for %s closed at %s (last message %s); "+
+		"send an approved template (send_template) instead",
+		e.UserID, e.LastInboundAt.Add(CustomerServiceWindow).Format(time.RFC3339), e.LastInboundAt.Format(time.RFC3339))
+}
+
+// Is reports whether the error matches ErrReengagementRequired.
+func (e *WindowClosedError) Is(target error) bool {
+	return target == ErrReengagementRequired
+}
+
+// NormalizeWAID turns a phone number in international format, such as
+// "+1 (555) 123-4567", into the digits-only wa_id WhatsApp identifies the
+// contact by and inbound messages are stored under.
+func NormalizeWAID(phone string) (string, error) {
+	var b strings.Builder
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' || r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("invalid phone number %q: unexpected %q", phone, r)
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("invalid phone number %q: no digits", phone)
+	}
+	return b.String(), nil
+}
+
+// WindowState returns the customer service window of userID as of now.
+func (h *Handler) WindowState(userID string) (WindowState, error) {
+	userID, err := NormalizeWAID(userID)
+	if err != nil {
+		return WindowState{}, err
+	}
+
+	lastInbound, err := h.db.GetLastInboundAt(userID)
+	if err != nil {
+		return WindowState{}, err
+	}
+
+	state := WindowState{UserID: userID, LastInboundAt: lastInbound}
+	if !lastInbound.IsZero() {
+		state.ExpiresAt = lastInbound.Add(CustomerServiceWindow)
+		state.Open = time.Now().Before(state.ExpiresAt)
+	}
+
+	return state, nil
+}
+
+// checkWindow fails with a WindowClosedError if userID's window is closed.
+func (h *Handler) checkWindow(userID string) error {
+	state, err := h.WindowState(userID)
+	if err != nil {
+		return fmt.Errorf("failed to check customer service window: %w", err)
+	}
+
+	if !state.Open {
+		return &WindowClosedError{UserID: userID, LastInboundAt: state.LastInboundAt}
+	}
+	return nil
+}
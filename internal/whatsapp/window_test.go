@@ -0,0 +1,122 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sinhaparth5/whatstyle-mcp/configs"
+	"github.com/sinhaparth5/whatstyle-mcp/internal/database"
+)
+
+func TestCustomerServiceWindow(t *testing.T) {
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	// No API URL is reachable; sends must be refused before any request is made
+	config := &configs.Config{WhatsAppAccessToken: "token", WhatsAppAPIURL: "http://127.0.0.1:0"}
+	handler := NewHandler(config, db, nil)
+
+	t.Run("NeverMessaged", func(t *testing.T) {
+		_, err := handler.SendMessage("15550002222", "Hello")
+
+		var windowErr *WindowClosedError
+		if !errors.As(err, &windowErr) {
+			t.Fatalf("Expected WindowClosedError, got: %v", err)
+		}
+		if !errors.Is(err, ErrReengagementRequired) {
+			t.Error("Expected WindowClosedError to match ErrReengagementRequired")
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		lastInbound := time.Now().Add(-25 * time.Hour)
+		if err := db.RecordInboundAt("15550003333", lastInbound); err != nil {
+			t.Fatalf("Failed to record inbound message: %v", err)
+		}
+
+		state, err := handler.WindowState("15550003333")
+		if err != nil {
+			t.Fatalf("Failed to get window state: %v", err)
+		}
+		if state.Open {
+			t.Error("Expected window to be closed")
+		}
+
+		if _, err := handler.SendMessage("15550003333", "Hello"); !errors.Is(err, ErrReengagementRequired) {
+			t.Errorf("Expected ErrReengagementRequired, got: %v", err)
+		}
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		lastInbound := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := db.RecordInboundAt("15550004444", lastInbound); err != nil {
+			t.Fatalf("Failed to record inbound message: %v", err)
+		}
+
+		// An older, late delivery must not shorten the window
+		if err := db.RecordInboundAt("15550004444", lastInbound.Add(-3*time.Hour)); err != nil {
+			t.Fatalf("Failed to record inbound message: %v", err)
+		}
+
+		state, err := handler.WindowState("15550004444")
+		if err != nil {
+			t.Fatalf("Failed to get window state: %v", err)
+		}
+		if !state.Open {
+			t.Fatal("Expected window to be open")
+		}
+		if !state.LastInboundAt.Equal(lastInbound) {
+			t.Errorf("Expected last inbound %s, got %s", lastInbound, state.LastInboundAt)
+		}
+		if remaining := state.Remaining(time.Now()); remaining < 22*time.Hour || remaining > 23*time.Hour {
+			t.Errorf("Expected about 23h remaining, got %s", remaining)
+		}
+	})
+}
+
+func TestInternationalRecipient(t *testing.T) {
+	var sent SendMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+			t.Errorf("Failed to decode message: %v", err)
+		}
+		w.Write([]byte(`{"messages":[{"id":"wamid.out"}]}`))
+	}))
+	defer server.Close()
+
+	db, err := database.InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	config := &configs.Config{WhatsAppAccessToken: "token", WhatsAppPhoneNumberID: "PHONE_ID", WhatsAppAPIURL: server.URL}
+	handler := NewHandler(config, db, nil)
+
+	// Inbound messages are keyed by the webhook's digits-only wa_id
+	if err := db.RecordInboundAt("15551234567", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to record inbound message: %v", err)
+	}
+
+	for _, to := range []string{"+15551234567", "+1 555 123 4567", "+1 (555) 123-4567"} {
+		t.Run(to, func(t *testing.T) {
+			if _, err := handler.SendMessage(to, "Hello"); err != nil {
+				t.Fatalf("Expected the open window to allow the send, got: %v", err)
+			}
+			if sent.To != "15551234567" {
+				t.Errorf("Expected the message to go to 15551234567, got %q", sent.To)
+			}
+		})
+	}
+
+	if _, err := handler.SendMessage("call me", "Hello"); err == nil {
+		t.Error("Expected an error for a recipient that is not a phone number")
+	}
+}